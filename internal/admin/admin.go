@@ -0,0 +1,160 @@
+// Package admin serves the API that controls a running nonodo, such as pausing the delivery
+// of the inputs or mining blocks, for the test harnesses that need a precise timing.
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+
+	"github.com/calindra/nonodo/internal/supervisor"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// Environment variable with the token of the admin API; the API is disabled if empty.
+const TokenEnv = "NONODO_ADMIN_TOKEN"
+
+// Prefix of the admin API in the rollups HTTP server.
+const Prefix = "/nonodo/admin"
+
+// Delivery of the inputs to the application.
+type Model interface {
+	Pause()
+	Resume()
+	Paused() bool
+	CurrentAdvanceIndex() (int, bool)
+	RejectCurrentInput() (int, bool, error)
+	SkipCurrentInput() (int, bool, error)
+}
+
+type Claimer interface {
+	ClaimNow(ctx context.Context) error
+}
+
+// Mine the blocks on the devnet.
+type Miner func(ctx context.Context, blocks uint64) error
+
+// State of the node as reported by the admin API.
+type Status struct {
+	Paused bool `json:"paused"`
+	// Index of the advance being processed by the application, if any.
+	CurrentInput *int   `json:"currentInput"`
+	LogLevel     string `json:"logLevel"`
+}
+
+type LogLevelRequest struct {
+	Level string `json:"level"`
+}
+
+type MineRequest struct {
+	Blocks uint64 `json:"blocks"`
+}
+
+type InputResponse struct {
+	Index int `json:"index"`
+}
+
+// Admin API of nonodo.
+type API struct {
+	// Bearer token expected in the Authorization header.
+	Token    string
+	Model    Model
+	Workers  *supervisor.StatusRegistry
+	LogLevel *slog.LevelVar
+	// Claims the outputs; nil if the claims are disabled.
+	Claimer Claimer
+	// Mines the blocks; nil if nonodo doesn't run its own Anvil.
+	Mine Miner
+}
+
+// Register the admin API to echo, under Prefix.
+func (a *API) Register(e *echo.Echo) {
+	g := e.Group(Prefix, middleware.KeyAuthWithConfig(middleware.KeyAuthConfig{
+		Validator: func(key string, c echo.Context) (bool, error) {
+			return subtle.ConstantTimeCompare([]byte(key), []byte(a.Token)) == 1, nil
+		},
+	}))
+	g.GET("/status", a.status)
+	g.POST("/pause", func(c echo.Context) error {
+		a.Model.Pause()
+		return a.status(c)
+	})
+	g.POST("/resume", func(c echo.Context) error {
+		a.Model.Resume()
+		return a.status(c)
+	})
+	g.POST("/inputs/current/reject", func(c echo.Context) error {
+		return finishCurrentInput(c, a.Model.RejectCurrentInput)
+	})
+	g.POST("/inputs/current/skip", func(c echo.Context) error {
+		return finishCurrentInput(c, a.Model.SkipCurrentInput)
+	})
+	g.GET("/log-level", a.logLevel)
+	g.PUT("/log-level", func(c echo.Context) error {
+		var request LogLevelRequest
+		if err := c.Bind(&request); err != nil {
+			return c.String(http.StatusBadRequest, "invalid request body")
+		}
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(request.Level)); err != nil {
+			return c.String(http.StatusBadRequest, "invalid log level; use debug, info, warn or error")
+		}
+		a.LogLevel.Set(level)
+		slog.Info("admin: changed the log level", "level", level)
+		return a.logLevel(c)
+	})
+	g.POST("/claim", func(c echo.Context) error {
+		if a.Claimer == nil {
+			return c.String(http.StatusNotFound, "the claims are disabled; set --epoch-blocks")
+		}
+		if err := a.Claimer.ClaimNow(c.Request().Context()); err != nil {
+			slog.Error("admin: failed to claim", "error", err)
+			return c.String(http.StatusInternalServerError, err.Error())
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+	g.POST("/mine", func(c echo.Context) error {
+		if a.Mine == nil {
+			return c.String(http.StatusNotFound, "nonodo doesn't run its own Anvil")
+		}
+		request := MineRequest{Blocks: 1}
+		if err := c.Bind(&request); err != nil || request.Blocks == 0 {
+			return c.String(http.StatusBadRequest, "blocks must be a positive number")
+		}
+		if err := a.Mine(c.Request().Context(), request.Blocks); err != nil {
+			slog.Error("admin: failed to mine", "error", err)
+			return c.String(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, request)
+	})
+	g.GET("/workers", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, a.Workers.List())
+	})
+}
+
+func (a *API) status(c echo.Context) error {
+	status := Status{Paused: a.Model.Paused(), LogLevel: a.LogLevel.Level().String()}
+	if index, ok := a.Model.CurrentAdvanceIndex(); ok {
+		status.CurrentInput = &index
+	}
+	return c.JSON(http.StatusOK, status)
+}
+
+func (a *API) logLevel(c echo.Context) error {
+	return c.JSON(http.StatusOK, LogLevelRequest{Level: a.LogLevel.Level().String()})
+}
+
+func finishCurrentInput(c echo.Context, finish func() (int, bool, error)) error {
+	index, ok, err := finish()
+	if err != nil {
+		slog.Error("admin: failed to finish the current input", "error", err)
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	if !ok {
+		return c.String(http.StatusConflict, "no input is being processed")
+	}
+	slog.Info("admin: finished the current input", "index", index, "path", c.Path())
+	return c.JSON(http.StatusOK, InputResponse{Index: index})
+}
@@ -0,0 +1,188 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/calindra/nonodo/internal/supervisor"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+)
+
+type AdminSuite struct {
+	suite.Suite
+	model   *fakeModel
+	api     *API
+	e       *echo.Echo
+	claims  int
+	blocks  uint64
+	mineErr error
+}
+
+func TestAdminSuite(t *testing.T) {
+	suite.Run(t, new(AdminSuite))
+}
+
+type fakeModel struct {
+	paused  bool
+	current *int
+	skipped bool
+}
+
+func (m *fakeModel) Pause()       { m.paused = true }
+func (m *fakeModel) Resume()      { m.paused = false }
+func (m *fakeModel) Paused() bool { return m.paused }
+
+func (m *fakeModel) CurrentAdvanceIndex() (int, bool) {
+	if m.current == nil {
+		return 0, false
+	}
+	return *m.current, true
+}
+
+func (m *fakeModel) RejectCurrentInput() (int, bool, error) {
+	index, ok := m.CurrentAdvanceIndex()
+	m.current = nil
+	return index, ok, nil
+}
+
+func (m *fakeModel) SkipCurrentInput() (int, bool, error) {
+	m.skipped = true
+	return m.RejectCurrentInput()
+}
+
+type claimerFunc func(ctx context.Context) error
+
+func (f claimerFunc) ClaimNow(ctx context.Context) error {
+	return f(ctx)
+}
+
+func (s *AdminSuite) SetupTest() {
+	s.model = &fakeModel{}
+	s.claims = 0
+	s.blocks = 0
+	s.mineErr = nil
+	s.api = &API{
+		Token:    "secret",
+		Model:    s.model,
+		Workers:  supervisor.NewStatusRegistry(),
+		LogLevel: new(slog.LevelVar),
+		Claimer: claimerFunc(func(ctx context.Context) error {
+			s.claims++
+			return nil
+		}),
+		Mine: func(ctx context.Context, blocks uint64) error {
+			s.blocks += blocks
+			return s.mineErr
+		},
+	}
+	s.e = echo.New()
+	s.api.Register(s.e)
+}
+
+func (s *AdminSuite) send(method string, path string, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, Prefix+path, strings.NewReader(body))
+	request.Header.Set(echo.HeaderAuthorization, "Bearer secret")
+	if body != "" {
+		request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	recorder := httptest.NewRecorder()
+	s.e.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func (s *AdminSuite) TestItRequiresTheToken() {
+	recorder := httptest.NewRecorder()
+	s.e.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, Prefix+"/pause", nil))
+	s.Equal(http.StatusBadRequest, recorder.Code)
+
+	request := httptest.NewRequest(http.MethodPost, Prefix+"/pause", nil)
+	request.Header.Set(echo.HeaderAuthorization, "Bearer wrong")
+	recorder = httptest.NewRecorder()
+	s.e.ServeHTTP(recorder, request)
+	s.Equal(http.StatusUnauthorized, recorder.Code)
+	s.False(s.model.paused)
+}
+
+func (s *AdminSuite) TestItPausesAndResumes() {
+	recorder := s.send(http.MethodPost, "/pause", "")
+	s.Equal(http.StatusOK, recorder.Code)
+	s.JSONEq(`{"paused":true,"currentInput":null,"logLevel":"INFO"}`, recorder.Body.String())
+
+	index := 3
+	s.model.current = &index
+	recorder = s.send(http.MethodPost, "/resume", "")
+	s.Equal(http.StatusOK, recorder.Code)
+	s.JSONEq(`{"paused":false,"currentInput":3,"logLevel":"INFO"}`, recorder.Body.String())
+}
+
+func (s *AdminSuite) TestItFinishesTheCurrentInput() {
+	recorder := s.send(http.MethodPost, "/inputs/current/reject", "")
+	s.Equal(http.StatusConflict, recorder.Code)
+
+	index := 2
+	s.model.current = &index
+	recorder = s.send(http.MethodPost, "/inputs/current/reject", "")
+	s.Equal(http.StatusOK, recorder.Code)
+	s.JSONEq(`{"index":2}`, recorder.Body.String())
+	s.False(s.model.skipped)
+
+	s.model.current = &index
+	recorder = s.send(http.MethodPost, "/inputs/current/skip", "")
+	s.Equal(http.StatusOK, recorder.Code)
+	s.True(s.model.skipped)
+}
+
+func (s *AdminSuite) TestItChangesTheLogLevel() {
+	recorder := s.send(http.MethodPut, "/log-level", `{"level":"debug"}`)
+	s.Equal(http.StatusOK, recorder.Code)
+	s.JSONEq(`{"level":"DEBUG"}`, recorder.Body.String())
+	s.Equal(slog.LevelDebug, s.api.LogLevel.Level())
+
+	recorder = s.send(http.MethodPut, "/log-level", `{"level":"verbose"}`)
+	s.Equal(http.StatusBadRequest, recorder.Code)
+	s.Equal(slog.LevelDebug, s.api.LogLevel.Level())
+}
+
+func (s *AdminSuite) TestItClaimsNow() {
+	recorder := s.send(http.MethodPost, "/claim", "")
+	s.Equal(http.StatusNoContent, recorder.Code)
+	s.Equal(1, s.claims)
+
+	s.api.Claimer = nil
+	recorder = s.send(http.MethodPost, "/claim", "")
+	s.Equal(http.StatusNotFound, recorder.Code)
+}
+
+func (s *AdminSuite) TestItMinesTheBlocks() {
+	recorder := s.send(http.MethodPost, "/mine", "")
+	s.Equal(http.StatusOK, recorder.Code)
+	s.Equal(uint64(1), s.blocks)
+
+	recorder = s.send(http.MethodPost, "/mine", `{"blocks":5}`)
+	s.Equal(http.StatusOK, recorder.Code)
+	s.JSONEq(`{"blocks":5}`, recorder.Body.String())
+	s.Equal(uint64(6), s.blocks) // nolint
+
+	recorder = s.send(http.MethodPost, "/mine", `{"blocks":0}`)
+	s.Equal(http.StatusBadRequest, recorder.Code)
+
+	s.mineErr = errors.New("connection refused")
+	recorder = s.send(http.MethodPost, "/mine", `{"blocks":1}`)
+	s.Equal(http.StatusInternalServerError, recorder.Code)
+
+	s.api.Mine = nil
+	recorder = s.send(http.MethodPost, "/mine", "")
+	s.Equal(http.StatusNotFound, recorder.Code)
+}
+
+func (s *AdminSuite) TestItListsTheWorkers() {
+	recorder := s.send(http.MethodGet, "/workers", "")
+	s.Equal(http.StatusOK, recorder.Code)
+	s.JSONEq(`[]`, recorder.Body.String())
+}
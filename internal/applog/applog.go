@@ -0,0 +1,105 @@
+// (c) Cartesi and individual authors (see AUTHORS)
+// SPDX-License-Identifier: Apache-2.0 (see LICENSE)
+
+// This package keeps the latest lines printed by the application and serves them over HTTP.
+package applog
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Number of lines kept by default.
+const DefaultCapacity = 10_000
+
+// Line printed by the application.
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Stream string    `json:"stream"`
+	Line   string    `json:"line"`
+	// Index of the advance input being processed when the line was printed.
+	// The lines printed while handling an inspect are not tagged.
+	InputIndex *int `json:"inputIndex,omitempty"`
+}
+
+// Ring buffer with the latest lines printed by the application.
+type Store struct {
+	mutex   sync.Mutex
+	entries []Entry
+	next    int
+	full    bool
+	current func() (int, bool)
+}
+
+// Create a store that keeps up to capacity lines.
+// The current function returns the index of the input being processed, if any.
+func NewStore(capacity int, current func() (int, bool)) *Store {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &Store{
+		entries: make([]Entry, capacity),
+		current: current,
+	}
+}
+
+// Add a line to the store, tagging it with the input being processed.
+// Once the store is full, the oldest line is discarded.
+func (s *Store) Add(stream string, line string) {
+	entry := Entry{
+		Time:   time.Now(),
+		Stream: stream,
+		Line:   line,
+	}
+	if s.current != nil {
+		if index, ok := s.current(); ok {
+			entry.InputIndex = &index
+		}
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.entries[s.next] = entry
+	s.next = (s.next + 1) % len(s.entries)
+	if s.next == 0 {
+		s.full = true
+	}
+}
+
+// Return the stored lines from the oldest to the newest.
+// If input is not nil, return only the lines printed while processing that input.
+func (s *Store) List(input *int) []Entry {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var ordered []Entry
+	if s.full {
+		ordered = append(ordered, s.entries[s.next:]...)
+	}
+	ordered = append(ordered, s.entries[:s.next]...)
+	list := []Entry{}
+	for _, entry := range ordered {
+		if input != nil && (entry.InputIndex == nil || *entry.InputIndex != *input) {
+			continue
+		}
+		list = append(list, entry)
+	}
+	return list
+}
+
+// Register the log API to echo
+func Register(e *echo.Echo, store *Store) {
+	e.GET("/nonodo/logs", func(c echo.Context) error {
+		var input *int
+		if value := c.QueryParam("input"); value != "" {
+			index, err := strconv.Atoi(value)
+			if err != nil || index < 0 {
+				return c.String(http.StatusBadRequest, "invalid input index")
+			}
+			input = &index
+		}
+		return c.JSON(http.StatusOK, store.List(input))
+	})
+}
@@ -0,0 +1,134 @@
+// (c) Cartesi and individual authors (see AUTHORS)
+// SPDX-License-Identifier: Apache-2.0 (see LICENSE)
+
+package applog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+)
+
+type AppLogSuite struct {
+	suite.Suite
+	input *int
+	store *Store
+}
+
+func TestAppLogSuite(t *testing.T) {
+	suite.Run(t, new(AppLogSuite))
+}
+
+func (s *AppLogSuite) SetupTest() {
+	s.input = nil
+	s.store = NewStore(3, func() (int, bool) {
+		if s.input == nil {
+			return 0, false
+		}
+		return *s.input, true
+	})
+}
+
+func (s *AppLogSuite) TestItTagsLinesWithTheCurrentInput() {
+	s.store.Add("stdout", "booting")
+	s.setInput(0)
+	s.store.Add("stdout", "handling")
+	s.store.Add("stderr", "failed")
+
+	entries := s.store.List(nil)
+	s.Len(entries, 3)
+	s.Nil(entries[0].InputIndex)
+	s.Equal(0, *entries[1].InputIndex)
+	s.Equal("stderr", entries[2].Stream)
+
+	zero := 0
+	entries = s.store.List(&zero)
+	s.Len(entries, 2)
+	s.Equal("handling", entries[0].Line)
+	s.Equal("failed", entries[1].Line)
+}
+
+func (s *AppLogSuite) TestItDiscardsTheOldestLines() {
+	for _, line := range []string{"a", "b", "c", "d", "e"} {
+		s.store.Add("stdout", line)
+	}
+	var lines []string
+	for _, entry := range s.store.List(nil) {
+		lines = append(lines, entry.Line)
+	}
+	s.Equal([]string{"c", "d", "e"}, lines)
+}
+
+func (s *AppLogSuite) TestItServesLogsOverHttp() {
+	e := echo.New()
+	Register(e, s.store)
+	s.setInput(1)
+	s.store.Add("stdout", "one")
+	s.setInput(2)
+	s.store.Add("stdout", "two")
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nonodo/logs?input=2", nil))
+	s.Equal(http.StatusOK, rec.Code)
+	var entries []Entry
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &entries))
+	s.Len(entries, 1)
+	s.Equal("two", entries[0].Line)
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nonodo/logs?input=x", nil))
+	s.Equal(http.StatusBadRequest, rec.Code)
+}
+
+func (s *AppLogSuite) TestItAddsTheLogsToTheGraphQLExtensions() {
+	e := echo.New()
+	e.Use(GraphQLLogs(s.store))
+	e.POST("/graphql", func(c echo.Context) error {
+		return c.JSONBlob(http.StatusOK, []byte(`{"data":{
+			"inputs":{"edges":[{"node":{"index":1}}]},
+			"notices":{"edges":[{"node":{"index":7,"input":{"index":2}}}]}
+		}}`))
+	})
+	s.setInput(1)
+	s.store.Add("stdout", "one")
+	s.setInput(2)
+	s.store.Add("stderr", "two")
+	s.setInput(3)
+	s.store.Add("stdout", "three")
+
+	var response struct {
+		Data       map[string]any `json:"data"`
+		Extensions struct {
+			AppLogs map[string][]Entry `json:"appLogs"`
+		} `json:"extensions"`
+	}
+	rec := s.postGraphQL(e, `{"query":"{ inputs { edges { node { index } } } }","extensions":{"appLogs":true}}`)
+	s.Equal(http.StatusOK, rec.Code)
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &response))
+	s.Contains(response.Data, "inputs")
+	s.Len(response.Extensions.AppLogs, 2)
+	s.Equal("one", response.Extensions.AppLogs["1"][0].Line)
+	s.Equal("two", response.Extensions.AppLogs["2"][0].Line)
+
+	// without the extension in the request, the response is left as is
+	rec = s.postGraphQL(e, `{"query":"{ inputs { edges { node { index } } } }"}`)
+	s.Equal(http.StatusOK, rec.Code)
+	s.NotContains(rec.Body.String(), "appLogs")
+}
+
+func (s *AppLogSuite) postGraphQL(e *echo.Echo, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func (s *AppLogSuite) setInput(index int) {
+	s.input = &index
+}
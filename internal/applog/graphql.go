@@ -0,0 +1,139 @@
+// (c) Cartesi and individual authors (see AUTHORS)
+// SPDX-License-Identifier: Apache-2.0 (see LICENSE)
+
+package applog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Key of the application logs in the extensions of the GraphQL requests and responses.
+const GraphQLExtension = "appLogs"
+
+// Middleware that adds the lines printed while processing the inputs of a GraphQL response
+// to its extensions, keyed by the input index.
+// Only the requests with {"extensions": {"appLogs": true}} get the logs.
+func GraphQLLogs(store *Store) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			request := c.Request()
+			if request.Method != http.MethodPost || !strings.HasPrefix(c.Path(), "/graphql") {
+				return next(c)
+			}
+			body, err := io.ReadAll(request.Body)
+			if err != nil {
+				return err
+			}
+			request.Body = io.NopCloser(bytes.NewReader(body))
+			var params struct {
+				Extensions map[string]any `json:"extensions"`
+			}
+			if err := json.Unmarshal(body, &params); err != nil || params.Extensions[GraphQLExtension] != true {
+				return next(c)
+			}
+
+			writer := c.Response().Writer
+			buffered := &bufferedWriter{ResponseWriter: writer, status: http.StatusOK}
+			c.Response().Writer = buffered
+			err = next(c)
+			c.Response().Writer = writer
+			if err != nil {
+				return err
+			}
+			output := buffered.body.Bytes()
+			if withLogs, err := addLogs(store, output); err == nil {
+				output = withLogs
+			}
+			writer.Header().Del(echo.HeaderContentLength)
+			writer.WriteHeader(buffered.status)
+			_, err = writer.Write(output)
+			return err
+		}
+	}
+}
+
+// Add the logs of the inputs in the data of the GraphQL response to its extensions.
+func addLogs(store *Store, output []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	decoder.UseNumber()
+	var response map[string]any
+	if err := decoder.Decode(&response); err != nil {
+		return nil, err
+	}
+	indexes := make(map[int]bool)
+	collectInputs(response["data"], indexes)
+	logs := make(map[string][]Entry)
+	for index := range indexes {
+		logs[strconv.Itoa(index)] = store.List(&index)
+	}
+	extensions, ok := response["extensions"].(map[string]any)
+	if !ok {
+		extensions = make(map[string]any)
+	}
+	extensions[GraphQLExtension] = logs
+	response["extensions"] = extensions
+	return json.Marshal(response)
+}
+
+// Collect the index of every input in the value, including the inputs of the outputs.
+func collectInputs(value any, indexes map[int]bool) {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, child := range value {
+			switch key {
+			case "input":
+				addIndex(child, indexes)
+			case "inputs":
+				if connection, ok := child.(map[string]any); ok {
+					edges, _ := connection["edges"].([]any)
+					for _, edge := range edges {
+						if edge, ok := edge.(map[string]any); ok {
+							addIndex(edge["node"], indexes)
+						}
+					}
+				}
+			}
+			collectInputs(child, indexes)
+		}
+	case []any:
+		for _, child := range value {
+			collectInputs(child, indexes)
+		}
+	}
+}
+
+func addIndex(input any, indexes map[int]bool) {
+	fields, ok := input.(map[string]any)
+	if !ok {
+		return
+	}
+	number, ok := fields["index"].(json.Number)
+	if !ok {
+		return
+	}
+	if index, err := strconv.Atoi(number.String()); err == nil {
+		indexes[index] = true
+	}
+}
+
+// Response writer that keeps the response to change it before sending.
+type bufferedWriter struct {
+	http.ResponseWriter
+	body   bytes.Buffer
+	status int
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
@@ -0,0 +1,56 @@
+package celestia
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/labstack/echo/v4"
+)
+
+// Body of POST /celestia/blobs.
+type BlobRequest struct {
+	// Hex namespace; the pipeline namespace when empty.
+	Namespace string `json:"namespace"`
+	// Hex data of the blob.
+	Data string `json:"data"`
+}
+
+// Serve the pipeline at /celestia/blobs.
+func Register(e *echo.Echo, pipeline *Pipeline) {
+	e.POST("/celestia/blobs", func(c echo.Context) error {
+		var request BlobRequest
+		if err := c.Bind(&request); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+		}
+		data, err := hexutil.Decode(request.Data)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid data: " + err.Error()})
+		}
+		blob, err := pipeline.Enqueue(c.Request().Context(), request.Namespace, data)
+		if errors.Is(err, ErrBlobExists) {
+			return c.JSON(http.StatusConflict, echo.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+		}
+		return c.JSON(http.StatusCreated, blob)
+	})
+	e.GET("/celestia/blobs", func(c echo.Context) error {
+		blobs, err := pipeline.Repository.FindAll(c.Request().Context())
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, blobs)
+	})
+	e.GET("/celestia/blobs/:id", func(c echo.Context) error {
+		blob, err := pipeline.Repository.FindByID(c.Request().Context(), c.Param("id"))
+		if err != nil {
+			return err
+		}
+		if blob == nil {
+			return c.JSON(http.StatusNotFound, echo.Map{"error": "blob not found"})
+		}
+		return c.JSON(http.StatusOK, blob)
+	})
+}
@@ -0,0 +1,220 @@
+package celestia
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/jmoiron/sqlx"
+)
+
+// Stage of a blob in the pipeline.
+type BlobStatus string
+
+const (
+	// Waiting to be submitted to Celestia.
+	BlobPending BlobStatus = "pending"
+	// Included in a Celestia block, waiting for the Blobstream data commitment.
+	BlobSubmitted BlobStatus = "submitted"
+	// Covered by a data commitment, waiting to be relayed.
+	BlobCommitted BlobStatus = "committed"
+	// Relayed to the application.
+	BlobRelayed BlobStatus = "relayed"
+	// Gave up after too many failed attempts.
+	BlobFailed BlobStatus = "failed"
+)
+
+var ErrBlobExists = errors.New("blob already submitted")
+
+// Blobstream data commitment of a range of Celestia blocks, from StartBlock to EndBlock exclusive.
+type DataCommitment struct {
+	ProofNonce uint64 `json:"proofNonce"`
+	StartBlock uint64 `json:"startBlock"`
+	EndBlock   uint64 `json:"endBlock"`
+	Root       string `json:"root"`
+}
+
+// Blob handled by the pipeline.
+type Blob struct {
+	// Keccak-256 of the namespace and the data.
+	ID        string     `json:"id"`
+	Namespace string     `json:"namespace"`
+	Data      []byte     `json:"-"`
+	Size      int        `json:"size"`
+	Status    BlobStatus `json:"status"`
+	// Celestia block and share range of the blob, once submitted.
+	Height     uint64          `json:"height,omitempty"`
+	Start      uint64          `json:"start,omitempty"`
+	End        uint64          `json:"end,omitempty"`
+	Commitment *DataCommitment `json:"commitment,omitempty"`
+	RelayTx    string          `json:"relayTx,omitempty"`
+	// Failed submissions or relays; the last error is kept in Error.
+	Attempts  int       `json:"attempts"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Store the blobs of the pipeline and their status.
+type BlobRepository struct {
+	Db *sqlx.DB
+}
+
+func (r *BlobRepository) CreateTables() error {
+	schema := `CREATE TABLE IF NOT EXISTS celestia_blobs (
+		id                text NOT NULL PRIMARY KEY,
+		position          integer NOT NULL,
+		namespace         text NOT NULL,
+		data              text NOT NULL,
+		status            text NOT NULL,
+		height            integer NOT NULL DEFAULT 0,
+		share_start       integer NOT NULL DEFAULT 0,
+		share_end         integer NOT NULL DEFAULT 0,
+		commitment_nonce  integer NOT NULL DEFAULT 0,
+		commitment_start  integer NOT NULL DEFAULT 0,
+		commitment_end    integer NOT NULL DEFAULT 0,
+		commitment_root   text NOT NULL DEFAULT '',
+		relay_tx          text NOT NULL DEFAULT '',
+		attempts          integer NOT NULL DEFAULT 0,
+		error             text NOT NULL DEFAULT '',
+		created_at        integer NOT NULL,
+		updated_at        integer NOT NULL);
+
+	CREATE INDEX IF NOT EXISTS idx_celestia_blobs_status ON celestia_blobs(status);`
+	_, err := r.Db.Exec(schema)
+	if err == nil {
+		slog.Debug("Celestia blobs table created")
+	} else {
+		slog.Error("Create table error", "error", err)
+	}
+	return err
+}
+
+func (r *BlobRepository) Create(ctx context.Context, blob Blob) error {
+	exist, err := r.FindByID(ctx, blob.ID)
+	if err != nil {
+		return err
+	}
+	if exist != nil {
+		return ErrBlobExists
+	}
+	// the position keeps the blobs in arrival order
+	_, err = r.Db.ExecContext(ctx, `INSERT INTO celestia_blobs (
+		id,
+		position,
+		namespace,
+		data,
+		status,
+		created_at,
+		updated_at) VALUES ($1, (SELECT COALESCE(MAX(position), 0) + 1 FROM celestia_blobs), $2, $3, $4, $5, $6)`,
+		blob.ID,
+		blob.Namespace,
+		common.Bytes2Hex(blob.Data),
+		string(blob.Status),
+		blob.CreatedAt.UnixMilli(),
+		blob.UpdatedAt.UnixMilli(),
+	)
+	return err
+}
+
+func (r *BlobRepository) Update(ctx context.Context, blob Blob) error {
+	commitment := blob.Commitment
+	if commitment == nil {
+		commitment = &DataCommitment{}
+	}
+	_, err := r.Db.ExecContext(ctx, `UPDATE celestia_blobs SET
+		status = $1,
+		height = $2,
+		share_start = $3,
+		share_end = $4,
+		commitment_nonce = $5,
+		commitment_start = $6,
+		commitment_end = $7,
+		commitment_root = $8,
+		relay_tx = $9,
+		attempts = $10,
+		error = $11,
+		updated_at = $12
+		WHERE id = $13`,
+		string(blob.Status),
+		blob.Height,
+		blob.Start,
+		blob.End,
+		commitment.ProofNonce,
+		commitment.StartBlock,
+		commitment.EndBlock,
+		commitment.Root,
+		blob.RelayTx,
+		blob.Attempts,
+		blob.Error,
+		blob.UpdatedAt.UnixMilli(),
+		blob.ID,
+	)
+	return err
+}
+
+const selectBlobs = `SELECT id, namespace, data, status, height, share_start, share_end,
+	commitment_nonce, commitment_start, commitment_end, commitment_root,
+	relay_tx, attempts, error, created_at, updated_at FROM celestia_blobs`
+
+// Return the blob or nil when there is none with the id.
+func (r *BlobRepository) FindByID(ctx context.Context, id string) (*Blob, error) {
+	row := r.Db.QueryRowxContext(ctx, selectBlobs+` WHERE id = $1`, id)
+	blob, err := scanBlob(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return blob, err
+}
+
+// Return the blobs in arrival order.
+func (r *BlobRepository) FindAll(ctx context.Context) ([]Blob, error) {
+	return r.query(ctx, selectBlobs+` ORDER BY position`)
+}
+
+// Return the blobs with the status in arrival order.
+func (r *BlobRepository) FindByStatus(ctx context.Context, status BlobStatus) ([]Blob, error) {
+	return r.query(ctx, selectBlobs+` WHERE status = $1 ORDER BY position`, string(status))
+}
+
+func (r *BlobRepository) query(ctx context.Context, query string, args ...any) ([]Blob, error) {
+	rows, err := r.Db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	blobs := []Blob{}
+	for rows.Next() {
+		blob, err := scanBlob(rows)
+		if err != nil {
+			return nil, err
+		}
+		blobs = append(blobs, *blob)
+	}
+	return blobs, rows.Err()
+}
+
+func scanBlob(row interface{ Scan(dest ...any) error }) (*Blob, error) {
+	var blob Blob
+	var data, status string
+	var commitment DataCommitment
+	var createdAt, updatedAt int64
+	err := row.Scan(&blob.ID, &blob.Namespace, &data, &status, &blob.Height, &blob.Start, &blob.End,
+		&commitment.ProofNonce, &commitment.StartBlock, &commitment.EndBlock, &commitment.Root,
+		&blob.RelayTx, &blob.Attempts, &blob.Error, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	blob.Data = common.Hex2Bytes(data)
+	blob.Size = len(blob.Data)
+	blob.Status = BlobStatus(status)
+	if commitment.Root != "" {
+		blob.Commitment = &commitment
+	}
+	blob.CreatedAt = time.UnixMilli(createdAt)
+	blob.UpdatedAt = time.UnixMilli(updatedAt)
+	return &blob, nil
+}
@@ -0,0 +1,147 @@
+package celestia
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/celestiaorg/celestia-openrpc/types/share"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	DefaultLocalBlockTime        = 2 * time.Second
+	DefaultLocalCommitmentWindow = 10
+)
+
+// Bytes of a blob carried by its first and by each following share.
+const (
+	firstShareBytes        = 478
+	continuationShareBytes = 482
+)
+
+// In-process stand-in of a Celestia node, its Tendermint chain and the Blobstream contract.
+//
+// A Tendermint block is produced every BlockTime and Blobstream stores the data commitment of
+// every CommitmentWindow blocks once the last of them is produced. The blobs are laid out in
+// shares as Celestia does, but the data roots are plain SHA-256 digests and the relay only checks
+// the commitment before returning a made-up transaction hash.
+type LocalBlobstream struct {
+	BlockTime        time.Duration
+	CommitmentWindow uint64
+	Clock            func() time.Time
+	mutex            sync.Mutex
+	genesis          time.Time
+	// leaves of the data root of each block, one per blob
+	blocks map[uint64][][]byte
+	// shares used in each block
+	shares map[uint64]uint64
+}
+
+func NewLocalBlobstream(blockTime time.Duration, commitmentWindow uint64) *LocalBlobstream {
+	return &LocalBlobstream{
+		BlockTime:        blockTime,
+		CommitmentWindow: commitmentWindow,
+		Clock:            time.Now,
+		blocks:           map[uint64][][]byte{},
+		shares:           map[uint64]uint64{},
+	}
+}
+
+// Current Tendermint height; the first block is produced by the first call.
+func (l *LocalBlobstream) Height() uint64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.height()
+}
+
+func (l *LocalBlobstream) height() uint64 {
+	now := l.Clock()
+	if l.genesis.IsZero() {
+		l.genesis = now
+	}
+	return 1 + uint64(now.Sub(l.genesis)/l.BlockTime)
+}
+
+func (l *LocalBlobstream) SubmitBlob(ctx context.Context, namespace string, data []byte) (uint64, uint64, uint64, error) {
+	ns, err := share.NewBlobNamespaceV0(common.FromHex(namespace))
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	height := l.height()
+	start := l.shares[height]
+	end := start + sharesOf(len(data))
+	l.shares[height] = end
+	leaf := sha256.Sum256(append(ns, data...))
+	l.blocks[height] = append(l.blocks[height], leaf[:])
+	return height, start, end, nil
+}
+
+func sharesOf(size int) uint64 {
+	if size <= firstShareBytes {
+		return 1
+	}
+	rest := size - firstShareBytes
+	return 1 + uint64((rest+continuationShareBytes-1)/continuationShareBytes)
+}
+
+func (l *LocalBlobstream) FindDataCommitment(ctx context.Context, height uint64) (*DataCommitment, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if height == 0 {
+		return nil, fmt.Errorf("invalid height 0")
+	}
+	window := (height - 1) / l.CommitmentWindow
+	commitment := &DataCommitment{
+		ProofNonce: window + 1,
+		StartBlock: window*l.CommitmentWindow + 1,
+		EndBlock:   (window+1)*l.CommitmentWindow + 1,
+	}
+	if l.height() < commitment.EndBlock {
+		return nil, nil
+	}
+	commitment.Root = l.root(commitment.StartBlock, commitment.EndBlock)
+	return commitment, nil
+}
+
+// Digest of the data roots of the blocks from start to end exclusive.
+func (l *LocalBlobstream) root(start uint64, end uint64) string {
+	digest := sha256.New()
+	for height := start; height < end; height++ {
+		dataRoot := sha256.New()
+		for _, leaf := range l.blocks[height] {
+			dataRoot.Write(leaf)
+		}
+		digest.Write(dataRoot.Sum(nil))
+	}
+	return common.BytesToHash(digest.Sum(nil)).Hex()
+}
+
+func (l *LocalBlobstream) Relay(ctx context.Context, blob Blob) (string, error) {
+	if blob.Commitment == nil {
+		return "", fmt.Errorf("blob %s has no data commitment", blob.ID)
+	}
+	expected, err := l.FindDataCommitment(ctx, blob.Height)
+	if err != nil {
+		return "", err
+	}
+	if expected == nil || *expected != *blob.Commitment {
+		return "", fmt.Errorf("invalid data commitment for height %d", blob.Height)
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if blob.End <= blob.Start || blob.End > l.shares[blob.Height] {
+		return "", fmt.Errorf("shares %d to %d are not in block %d", blob.Start, blob.End, blob.Height)
+	}
+	var position [24]byte
+	binary.BigEndian.PutUint64(position[0:8], blob.Height)
+	binary.BigEndian.PutUint64(position[8:16], blob.Start)
+	binary.BigEndian.PutUint64(position[16:24], blob.End)
+	return crypto.Keccak256Hash(common.FromHex(expected.Root), position[:]).Hex(), nil
+}
@@ -0,0 +1,57 @@
+package celestia
+
+import (
+	"context"
+	"errors"
+
+	"github.com/calindra/nonodo/internal/dataavailability"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Blocks of the Blobstream chain scanned for a data commitment, about 7 hours of Arbitrum Sepolia.
+const DefaultCommitmentScanBlocks = 100_000
+
+// Celestia node, Tendermint RPC and Blobstream used by the manual celestia commands.
+type Network struct {
+	// Celestia node and its auth token, as TIA_URL and TIA_AUTH_TOKEN.
+	Url   string
+	Token string
+	// Chain of the relay contract, signed with PK_CELESTIA.
+	RpcUrl      string
+	ChainId     int64
+	Application common.Address
+	ScanBlocks  uint64
+}
+
+func (n Network) SubmitBlob(ctx context.Context, namespace string, data []byte) (uint64, uint64, uint64, error) {
+	return dataavailability.SubmitBlob(ctx, n.Url, n.Token, namespace, data)
+}
+
+func (n Network) FindDataCommitment(ctx context.Context, height uint64) (*DataCommitment, error) {
+	scanBlocks := n.ScanBlocks
+	if scanBlocks == 0 {
+		scanBlocks = DefaultCommitmentScanBlocks
+	}
+	event, err := dataavailability.FindDataCommitment(ctx, height, scanBlocks)
+	if errors.Is(err, dataavailability.ErrDataCommitmentNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &DataCommitment{
+		ProofNonce: event.ProofNonce.Uint64(),
+		StartBlock: event.StartBlock,
+		EndBlock:   event.EndBlock,
+		Root:       common.Hash(event.DataCommitment).Hex(),
+	}, nil
+}
+
+func (n Network) Relay(ctx context.Context, blob Blob) (string, error) {
+	tx, err := dataavailability.CallCelestiaRelay(ctx, blob.Height, blob.Start, blob.End,
+		n.Application, []byte{}, n.RpcUrl, n.ChainId)
+	if err != nil {
+		return "", err
+	}
+	return tx.Hex(), nil
+}
@@ -0,0 +1,220 @@
+// Package celestia runs blobs through Celestia and Blobstream up to the application.
+//
+// Each blob is submitted to Celestia, waits for the Blobstream data commitment that covers its
+// block and is relayed to the application with its share proof as soon as the commitment is there.
+package celestia
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/celestiaorg/celestia-openrpc/types/share"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	DefaultPollInterval = 10 * time.Second
+	DefaultMaxAttempts  = 5
+)
+
+// Submit blobs to Celestia.
+type Submitter interface {
+	// Return the block and the share range of the blob.
+	SubmitBlob(ctx context.Context, namespace string, data []byte) (height uint64, start uint64, end uint64, err error)
+}
+
+// Find the Blobstream data commitments.
+type CommitmentFinder interface {
+	// Return the commitment that covers the Celestia height or nil while there is none.
+	FindDataCommitment(ctx context.Context, height uint64) (*DataCommitment, error)
+}
+
+// Relay the share proofs of the blobs to the application.
+type Relayer interface {
+	// Return the hash of the relay transaction.
+	Relay(ctx context.Context, blob Blob) (string, error)
+}
+
+// Worker that takes each blob from submission to the relay.
+type Pipeline struct {
+	Repository  *BlobRepository
+	Submitter   Submitter
+	Commitments CommitmentFinder
+	Relayer     Relayer
+	// Namespace of the blobs enqueued without one.
+	Namespace    string
+	PollInterval time.Duration
+	MaxAttempts  int
+	Clock        func() time.Time
+	wake         chan struct{}
+}
+
+func NewPipeline(
+	repository *BlobRepository,
+	submitter Submitter,
+	commitments CommitmentFinder,
+	relayer Relayer,
+) *Pipeline {
+	return &Pipeline{
+		Repository:   repository,
+		Submitter:    submitter,
+		Commitments:  commitments,
+		Relayer:      relayer,
+		PollInterval: DefaultPollInterval,
+		MaxAttempts:  DefaultMaxAttempts,
+		Clock:        time.Now,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+func (p *Pipeline) String() string {
+	return "celestia_pipeline"
+}
+
+func (p *Pipeline) Start(ctx context.Context, ready chan<- struct{}) error {
+	ready <- struct{}{}
+	slog.Info("celestia: pipeline started", "pollInterval", p.PollInterval)
+	ticker := time.NewTicker(p.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := p.Step(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-p.wake:
+		}
+	}
+}
+
+// Store a new blob to be submitted.
+func (p *Pipeline) Enqueue(ctx context.Context, namespace string, data []byte) (*Blob, error) {
+	if namespace == "" {
+		namespace = p.Namespace
+	}
+	namespace = strings.TrimPrefix(namespace, "0x")
+	if _, err := share.NewBlobNamespaceV0(common.FromHex(namespace)); err != nil {
+		return nil, fmt.Errorf("invalid namespace %q: %w", namespace, err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty blob")
+	}
+	now := p.Clock()
+	blob := Blob{
+		ID:        common.BytesToHash(crypto.Keccak256(common.FromHex(namespace), data)).Hex(),
+		Namespace: namespace,
+		Data:      data,
+		Size:      len(data),
+		Status:    BlobPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := p.Repository.Create(ctx, blob); err != nil {
+		return nil, err
+	}
+	slog.Info("celestia: blob enqueued", "id", blob.ID, "namespace", namespace, "size", blob.Size)
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+	return &blob, nil
+}
+
+// Move every blob forward as far as it can go now.
+// Only the repository errors stop the pipeline; the others are kept in the blob.
+func (p *Pipeline) Step(ctx context.Context) error {
+	pending, err := p.Repository.FindByStatus(ctx, BlobPending)
+	if err != nil {
+		return err
+	}
+	for _, blob := range pending {
+		height, start, end, err := p.Submitter.SubmitBlob(ctx, blob.Namespace, blob.Data)
+		if err != nil {
+			if err := p.fail(ctx, blob, "submit", err); err != nil {
+				return err
+			}
+			continue
+		}
+		blob.Status = BlobSubmitted
+		blob.Height, blob.Start, blob.End = height, start, end
+		if err := p.update(ctx, &blob); err != nil {
+			return err
+		}
+		slog.Info("celestia: blob submitted", "id", blob.ID, "height", height, "start", start, "end", end)
+	}
+
+	submitted, err := p.Repository.FindByStatus(ctx, BlobSubmitted)
+	if err != nil {
+		return err
+	}
+	for _, blob := range submitted {
+		commitment, err := p.Commitments.FindDataCommitment(ctx, blob.Height)
+		if err != nil {
+			slog.Warn("celestia: data commitment lookup failed", "id", blob.ID, "height", blob.Height, "error", err)
+			continue
+		}
+		if commitment == nil {
+			slog.Debug("celestia: waiting for the data commitment", "id", blob.ID, "height", blob.Height)
+			continue
+		}
+		blob.Status = BlobCommitted
+		blob.Commitment = commitment
+		if err := p.update(ctx, &blob); err != nil {
+			return err
+		}
+		slog.Info("celestia: blob committed", "id", blob.ID, "proofNonce", commitment.ProofNonce)
+	}
+
+	committed, err := p.Repository.FindByStatus(ctx, BlobCommitted)
+	if err != nil {
+		return err
+	}
+	for _, blob := range committed {
+		tx, err := p.Relayer.Relay(ctx, blob)
+		if err != nil {
+			if err := p.fail(ctx, blob, "relay", err); err != nil {
+				return err
+			}
+			continue
+		}
+		blob.Status = BlobRelayed
+		blob.RelayTx = tx
+		blob.Error = ""
+		if err := p.update(ctx, &blob); err != nil {
+			return err
+		}
+		slog.Info("celestia: blob relayed", "id", blob.ID, "tx", tx)
+	}
+	return nil
+}
+
+// Keep the error and give up on the blob after MaxAttempts.
+func (p *Pipeline) fail(ctx context.Context, blob Blob, stage string, cause error) error {
+	if errors.Is(cause, context.Canceled) {
+		return cause
+	}
+	blob.Attempts++
+	blob.Error = fmt.Sprintf("%s: %v", stage, cause)
+	if blob.Attempts >= p.MaxAttempts {
+		blob.Status = BlobFailed
+		slog.Error("celestia: blob failed", "id", blob.ID, "attempts", blob.Attempts, "error", blob.Error)
+	} else {
+		slog.Warn("celestia: retrying blob", "id", blob.ID, "attempts", blob.Attempts, "error", blob.Error)
+	}
+	return p.update(ctx, &blob)
+}
+
+func (p *Pipeline) update(ctx context.Context, blob *Blob) error {
+	blob.UpdatedAt = p.Clock()
+	return p.Repository.Update(ctx, *blob)
+}
@@ -0,0 +1,198 @@
+package celestia
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cartesi/rollups-graphql/pkg/commons"
+	"github.com/labstack/echo/v4"
+	_ "github.com/ncruces/go-sqlite3/driver"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	"github.com/stretchr/testify/suite"
+)
+
+type PipelineSuite struct {
+	suite.Suite
+	ctx        context.Context
+	dbFactory  *commons.DbFactory
+	now        time.Time
+	blobstream *LocalBlobstream
+	pipeline   *Pipeline
+}
+
+func TestPipelineSuite(t *testing.T) {
+	suite.Run(t, new(PipelineSuite))
+}
+
+func (s *PipelineSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.now = time.Unix(1700000000, 0) // nolint
+	s.dbFactory = commons.NewDbFactory()
+	db := s.dbFactory.CreateDb("celestia.sqlite3")
+	repository := &BlobRepository{Db: db}
+	s.Require().NoError(repository.CreateTables())
+	s.blobstream = NewLocalBlobstream(time.Second, 4) // nolint
+	s.blobstream.Clock = s.clock
+	s.pipeline = NewPipeline(repository, s.blobstream, s.blobstream, s.blobstream)
+	s.pipeline.Namespace = "deadbeef"
+	s.pipeline.Clock = s.clock
+}
+
+func (s *PipelineSuite) TearDownTest() {
+	s.dbFactory.Cleanup()
+}
+
+func (s *PipelineSuite) clock() time.Time {
+	return s.now
+}
+
+func (s *PipelineSuite) find(id string) *Blob {
+	blob, err := s.pipeline.Repository.FindByID(s.ctx, id)
+	s.Require().NoError(err)
+	s.Require().NotNil(blob)
+	return blob
+}
+
+func (s *PipelineSuite) TestItRelaysTheBlobOnceItIsCommitted() {
+	s.now = s.now.Add(time.Second) // height 2
+	blob, err := s.pipeline.Enqueue(s.ctx, "", []byte("Hello, World!"))
+	s.Require().NoError(err)
+	s.Equal(BlobPending, blob.Status)
+	s.Require().NoError(s.pipeline.Step(s.ctx))
+
+	blob = s.find(blob.ID)
+	s.Equal(BlobSubmitted, blob.Status)
+	s.Equal(uint64(1), blob.Height) // the first call to the stand-in is its genesis
+	s.Equal(uint64(0), blob.Start)
+	s.Equal(uint64(1), blob.End)
+	s.Nil(blob.Commitment)
+
+	// blocks 1 to 4 are committed once block 5 is produced
+	s.now = s.now.Add(3 * time.Second) // nolint
+	s.Require().NoError(s.pipeline.Step(s.ctx))
+	s.Equal(BlobSubmitted, s.find(blob.ID).Status)
+
+	s.now = s.now.Add(time.Second)
+	s.Require().NoError(s.pipeline.Step(s.ctx))
+	blob = s.find(blob.ID)
+	s.Equal(BlobRelayed, blob.Status)
+	s.Require().NotNil(blob.Commitment)
+	s.Equal(uint64(1), blob.Commitment.ProofNonce)
+	s.Equal(uint64(1), blob.Commitment.StartBlock)
+	s.Equal(uint64(5), blob.Commitment.EndBlock) // nolint
+	s.True(strings.HasPrefix(blob.RelayTx, "0x"))
+	s.Equal("Hello, World!", string(blob.Data))
+}
+
+func (s *PipelineSuite) TestTheBlobsShareTheBlock() {
+	first, err := s.pipeline.Enqueue(s.ctx, "deadbeef", make([]byte, 1000)) // nolint
+	s.Require().NoError(err)
+	second, err := s.pipeline.Enqueue(s.ctx, "0xcafe", []byte{1})
+	s.Require().NoError(err)
+	s.Require().NoError(s.pipeline.Step(s.ctx))
+
+	// 478 bytes in the first share and 482 in each of the next ones
+	s.Equal(uint64(0), s.find(first.ID).Start)
+	s.Equal(uint64(3), s.find(first.ID).End)    // nolint
+	s.Equal(uint64(3), s.find(second.ID).Start) // nolint
+	s.Equal(uint64(4), s.find(second.ID).End)   // nolint
+	s.Equal("cafe", s.find(second.ID).Namespace)
+}
+
+func (s *PipelineSuite) TestItRejectsInvalidBlobs() {
+	_, err := s.pipeline.Enqueue(s.ctx, "", []byte("Hello"))
+	s.Require().NoError(err)
+	_, err = s.pipeline.Enqueue(s.ctx, "deadbeef", []byte("Hello"))
+	s.ErrorIs(err, ErrBlobExists)
+	_, err = s.pipeline.Enqueue(s.ctx, "00112233445566778899aabb", []byte("Hello"))
+	s.ErrorContains(err, "invalid namespace")
+	_, err = s.pipeline.Enqueue(s.ctx, "deadbeef", nil)
+	s.ErrorContains(err, "empty blob")
+}
+
+func (s *PipelineSuite) TestItGivesUpAfterTheMaxAttempts() {
+	s.pipeline.Relayer = failingRelayer{}
+	s.pipeline.MaxAttempts = 2
+	blob, err := s.pipeline.Enqueue(s.ctx, "", []byte("Hello"))
+	s.Require().NoError(err)
+	s.Require().NoError(s.pipeline.Step(s.ctx))
+	s.now = s.now.Add(time.Minute)
+
+	s.Require().NoError(s.pipeline.Step(s.ctx))
+	blob = s.find(blob.ID)
+	s.Equal(BlobCommitted, blob.Status)
+	s.Equal(1, blob.Attempts)
+	s.Equal("relay: out of gas", blob.Error)
+
+	s.Require().NoError(s.pipeline.Step(s.ctx))
+	blob = s.find(blob.ID)
+	s.Equal(BlobFailed, blob.Status)
+	s.Equal(2, blob.Attempts) // nolint
+}
+
+func (s *PipelineSuite) TestTheStandInRejectsForgedCommitments() {
+	blob, err := s.pipeline.Enqueue(s.ctx, "", []byte("Hello"))
+	s.Require().NoError(err)
+	s.Require().NoError(s.pipeline.Step(s.ctx))
+	s.now = s.now.Add(time.Minute)
+	blob = s.find(blob.ID)
+	commitment, err := s.blobstream.FindDataCommitment(s.ctx, blob.Height)
+	s.Require().NoError(err)
+	commitment.Root = "0x" + strings.Repeat("00", 32) // nolint
+	blob.Commitment = commitment
+	_, err = s.blobstream.Relay(s.ctx, *blob)
+	s.ErrorContains(err, "invalid data commitment")
+}
+
+func (s *PipelineSuite) TestTheAPI() {
+	e := echo.New()
+	Register(e, s.pipeline)
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	post := func(body string) *http.Response {
+		res, err := http.Post(server.URL+"/celestia/blobs", "application/json", strings.NewReader(body))
+		s.Require().NoError(err)
+		return res
+	}
+	res := post(`{"data":"0x48656c6c6f"}`)
+	defer res.Body.Close()
+	s.Equal(http.StatusCreated, res.StatusCode)
+	var blob Blob
+	s.Require().NoError(json.NewDecoder(res.Body).Decode(&blob))
+	s.Equal(BlobPending, blob.Status)
+	s.Equal(5, blob.Size) // nolint
+
+	conflict := post(`{"namespace":"deadbeef","data":"0x48656c6c6f"}`)
+	defer conflict.Body.Close()
+	s.Equal(http.StatusConflict, conflict.StatusCode)
+	invalid := post(`{"data":"Hello"}`)
+	defer invalid.Body.Close()
+	s.Equal(http.StatusBadRequest, invalid.StatusCode)
+
+	s.Require().NoError(s.pipeline.Step(s.ctx))
+	status, err := http.Get(server.URL + "/celestia/blobs/" + blob.ID)
+	s.Require().NoError(err)
+	defer status.Body.Close()
+	s.Equal(http.StatusOK, status.StatusCode)
+	s.Require().NoError(json.NewDecoder(status.Body).Decode(&blob))
+	s.Equal(BlobSubmitted, blob.Status)
+	s.Equal(uint64(1), blob.Height)
+
+	missing, err := http.Get(server.URL + "/celestia/blobs/0x01")
+	s.Require().NoError(err)
+	defer missing.Body.Close()
+	s.Equal(http.StatusNotFound, missing.StatusCode)
+}
+
+type failingRelayer struct{}
+
+func (failingRelayer) Relay(ctx context.Context, blob Blob) (string, error) {
+	return "", errors.New("out of gas")
+}
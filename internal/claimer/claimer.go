@@ -20,12 +20,21 @@ const MAX_OUTPUT_TREE_HEIGHT = 63
 
 type Claimer struct {
 	ethClient *ethclient.Client
+	// Address book with the factories; the embedded devnet one if nil.
+	AddressBook *devnet.ContractInfo
 }
 
 func NewClaimer(
 	ethClient *ethclient.Client,
 ) *Claimer {
-	return &Claimer{ethClient}
+	return &Claimer{ethClient: ethClient}
+}
+
+func (c *Claimer) addressBook() *devnet.ContractInfo {
+	if c.AddressBook != nil {
+		return c.AddressBook
+	}
+	return devnet.GetContractInfo()
 }
 
 func (c *Claimer) MakeTheClaim(ctx context.Context,
@@ -87,8 +96,10 @@ func (c *Claimer) MakeTheClaim(ctx context.Context,
 }
 
 func (c *Claimer) CreateConsensusTypeAuthority(ctx context.Context) (*common.Address, error) {
-	contractInfo := devnet.GetContractInfo()
-	authorityFactoryAddress := common.HexToAddress(contractInfo.Contracts["AuthorityFactory"].Address)
+	authorityFactoryAddress, err := c.addressBook().Address("AuthorityFactory")
+	if err != nil {
+		return nil, err
+	}
 	authorityFactory, err := contracts.NewIAuthorityFactory(authorityFactoryAddress, c.ethClient)
 	if err != nil {
 		return nil, err
@@ -130,8 +141,10 @@ func (c *Claimer) CreateNewOnChainApp(
 	ctx context.Context,
 	consensusAddress common.Address,
 ) (*common.Address, error) {
-	addressBook := devnet.GetContractInfo()
-	appFactoryAddress := common.HexToAddress(addressBook.Contracts["ApplicationFactory"].Address)
+	appFactoryAddress, err := c.addressBook().Address("ApplicationFactory")
+	if err != nil {
+		return nil, err
+	}
 	applicationFactory, err := contracts.NewIApplicationFactory(appFactoryAddress, c.ethClient)
 	if err != nil {
 		return nil, err
@@ -8,6 +8,8 @@ import (
 	"math/big"
 	"sort"
 
+	"github.com/calindra/nonodo/internal/events"
+	"github.com/calindra/nonodo/internal/metrics"
 	"github.com/cartesi/rollups-graphql/pkg/convenience/model"
 	"github.com/cartesi/rollups-graphql/pkg/convenience/repository"
 	"github.com/ethereum/go-ethereum/common"
@@ -17,6 +19,8 @@ type ClaimerService struct {
 	VoucherRepository *repository.VoucherRepository
 	NoticeRepository  *repository.NoticeRepository
 	claimer           *Claimer
+	// Receives the submitted claims; optional.
+	Publisher events.Publisher
 }
 
 func NewClaimService(
@@ -113,8 +117,18 @@ func (c *ClaimerService) CreateProofsAndSendClaim(
 	doesNotMatter := new(big.Int).SetInt64(10) // nolint
 	err = c.claimer.MakeTheClaim(ctx, &consensusAddress, &appAddress, claim, doesNotMatter, nil)
 	if err != nil {
+		metrics.Claims.WithLabelValues(metrics.ClaimFailed).Inc()
 		return err
 	}
+	metrics.Claims.WithLabelValues(metrics.ClaimSubmitted).Inc()
+	if c.Publisher != nil {
+		c.Publisher.Publish(events.Event{
+			Type:        events.ClaimSubmitted,
+			AppContract: appAddress.Hex(),
+			Claim:       claim.Hex(),
+			BlockNumber: endBlockLt,
+		})
+	}
 	return nil
 }
 
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"log"
 	"log/slog"
+	"sync"
 
+	"github.com/calindra/nonodo/internal/devnet"
+	"github.com/calindra/nonodo/internal/events"
 	"github.com/cartesi/rollups-graphql/pkg/convenience/repository"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -15,6 +18,8 @@ import (
 const DEFAULT_EPOCH_BLOCKS = 10
 
 type ClaimerWorker struct {
+	// Serializes the claims; guards the consensus address, set once the worker started.
+	mutex             sync.Mutex
 	RpcUrl            string
 	ethClient         *ethclient.Client
 	ClaimerService    *ClaimerService
@@ -23,6 +28,10 @@ type ClaimerWorker struct {
 	consensusAddress  *common.Address
 	appAddress        *common.Address
 	epochBlocks       uint64
+	// Address book with the factories; the embedded devnet one if nil.
+	AddressBook *devnet.ContractInfo
+	// Receives the submitted claims; optional.
+	Publisher events.Publisher
 }
 
 func NewClaimerWorker(
@@ -50,11 +59,13 @@ func (c *ClaimerWorker) Start(ctx context.Context, ready chan<- struct{}) error
 	}
 	c.ethClient = client
 	claimer := NewClaimer(c.ethClient)
+	claimer.AddressBook = c.AddressBook
 	c.ClaimerService = NewClaimService(
 		c.voucherRepository,
 		c.noticeRepository,
 		claimer,
 	)
+	c.ClaimerService.Publisher = c.Publisher
 	consensusAddress, err := claimer.CreateConsensusTypeAuthority(ctx)
 	if err != nil {
 		return err
@@ -62,7 +73,9 @@ func (c *ClaimerWorker) Start(ctx context.Context, ready chan<- struct{}) error
 	if consensusAddress == nil {
 		return fmt.Errorf("fail to create consensus")
 	}
+	c.mutex.Lock()
 	c.consensusAddress = consensusAddress
+	c.mutex.Unlock()
 
 	appAddress, err := claimer.CreateNewOnChainApp(ctx, *c.consensusAddress)
 	if err != nil {
@@ -91,12 +104,7 @@ func (c *ClaimerWorker) watchNewBlocks(ctx context.Context) error {
 			)
 			blockNumber := header.Number.Uint64()
 			if blockNumber > 0 && blockNumber%c.epochBlocks == 0 {
-				err := c.ClaimerService.CreateProofsAndSendClaim(
-					ctx,
-					*c.consensusAddress,
-					blockNumber-c.epochBlocks,
-					blockNumber,
-				)
+				err := c.claim(ctx, blockNumber-c.epochBlocks, blockNumber)
 				if err != nil {
 					slog.Error("Error creating proofs and claim", "err", err)
 				}
@@ -111,3 +119,25 @@ func (c *ClaimerWorker) watchNewBlocks(ctx context.Context) error {
 		return err
 	}
 }
+
+func (c *ClaimerWorker) claim(ctx context.Context, startBlockGte uint64, endBlockLt uint64) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.ClaimerService.CreateProofsAndSendClaim(ctx, *c.consensusAddress, startBlockGte, endBlockLt)
+}
+
+// Claim the outputs up to the latest block now, without waiting for the end of the epoch.
+func (c *ClaimerWorker) ClaimNow(ctx context.Context) error {
+	c.mutex.Lock()
+	started := c.consensusAddress != nil
+	c.mutex.Unlock()
+	if !started {
+		return fmt.Errorf("the claimer is not started yet")
+	}
+	blockNumber, err := c.ethClient.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	slog.Info("claimer: claiming now", "blockNumber", blockNumber)
+	return c.claim(ctx, blockNumber-blockNumber%c.epochBlocks, blockNumber+1)
+}
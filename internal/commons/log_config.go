@@ -7,6 +7,9 @@ import (
 	"github.com/lmittmann/tint"
 )
 
+// Level of the nonodo log, which may be changed while it runs.
+var LogLevel = new(slog.LevelVar)
+
 func ConfigureLog(level slog.Leveler) {
 	logOpts := new(tint.Options)
 	logOpts.Level = level
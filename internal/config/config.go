@@ -0,0 +1,302 @@
+// Package config loads the options of the nonodo command from a TOML or YAML file.
+//
+// The keys of the file are the names of the flags, and nested tables are joined with a dash, so
+// `[http] port = 8080` is the same as `http-port = 8080`. The env table sets the environment
+// variables that have no flag. The precedence is flags, then environment, then file, then defaults.
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Table of the environment variables.
+	EnvKey = "env"
+	// Command line of the application, as the arguments after --.
+	ApplicationKey = "application"
+	// Flag of the config file itself, which can't be set by the file.
+	ConfigFlag = "config"
+)
+
+// Environment variables read by nonodo that have no flag.
+var EnvKeys = []string{
+	"ANVIL_TAG",
+	"AVAIL_MNEMONIC",
+	"AVAIL_RPC_URL",
+	"CELESTIA_RELAY_ADDRESS",
+	"DB_CONN_MAX_IDLE_TIME",
+	"DB_CONN_MAX_LIFETIME",
+	"DB_MAX_IDLE_CONNS",
+	"DB_MAX_OPEN_CONNS",
+	"EPOCH_DURATION",
+	"L1_READ_DELAY_IN_SECONDS",
+	"NONODO_ADMIN_TOKEN",
+	"NONODO_WEBHOOK_SECRET",
+	"PAIO_TAG",
+	"PK_CELESTIA",
+	"POSTGRES_DB",
+	"POSTGRES_GRAPHQL_DB_URL",
+	"POSTGRES_HOST",
+	"POSTGRES_PASSWORD",
+	"POSTGRES_PORT",
+	"POSTGRES_USER",
+	"SENDER_ADDRESS",
+	"SENDER_PRIVATE_KEY",
+	"TIA_AUTH_TOKEN",
+	"TIA_URL",
+}
+
+// Environment variables masked by Print.
+var secretEnvKeys = map[string]bool{
+	"AVAIL_MNEMONIC":          true,
+	"NONODO_ADMIN_TOKEN":      true,
+	"NONODO_WEBHOOK_SECRET":   true,
+	"PK_CELESTIA":             true,
+	"POSTGRES_GRAPHQL_DB_URL": true,
+	"POSTGRES_PASSWORD":       true,
+	"SENDER_PRIVATE_KEY":      true,
+	"TIA_AUTH_TOKEN":          true,
+}
+
+// Flags that are not options of nonodo.
+var ignoredFlags = map[string]bool{
+	ConfigFlag: true,
+	"help":     true,
+	"version":  true,
+}
+
+// Config file of the nonodo command.
+type File struct {
+	Path string
+	// Values of the flags by name.
+	Flags map[string]any
+	Env   map[string]string
+	// Application command line; empty if not set.
+	Application []string
+}
+
+// Load the file, with the format given by its extension.
+func Load(path string) (*File, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]any{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".toml":
+		err = toml.Unmarshal(content, &values)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &values)
+	default:
+		return nil, fmt.Errorf("%s: unknown format %q; use .toml, .yaml or .yml", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	file := &File{Path: path, Flags: map[string]any{}, Env: map[string]string{}}
+	for key, value := range values {
+		switch key {
+		case EnvKey:
+			err = file.loadEnv(value)
+		case ApplicationKey:
+			file.Application, err = file.strings(key, value)
+		default:
+			err = file.flatten(key, value)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+func (f *File) loadEnv(value any) error {
+	table, ok := value.(map[string]any)
+	if !ok {
+		return f.errorf(EnvKey, "must be a table")
+	}
+	for name, value := range table {
+		key := EnvKey + "." + name
+		if !slices.Contains(EnvKeys, name) {
+			return f.errorf(key, "unknown environment variable")
+		}
+		scalar, err := f.scalar(key, value)
+		if err != nil {
+			return err
+		}
+		f.Env[name] = scalar
+	}
+	return nil
+}
+
+func (f *File) flatten(key string, value any) error {
+	table, ok := value.(map[string]any)
+	if !ok {
+		f.Flags[key] = value
+		return nil
+	}
+	for name, value := range table {
+		if err := f.flatten(key+"-"+name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Set the flags that were not given in the command line and the environment variables that
+// are not set. It fails on the first key that has no flag or that has an invalid value.
+func (f *File) Apply(flags *pflag.FlagSet) error {
+	for _, key := range sortedKeys(f.Flags) {
+		flag := flags.Lookup(key)
+		if flag == nil || ignoredFlags[key] {
+			return f.errorf(key, "unknown key")
+		}
+		if flag.Changed {
+			continue
+		}
+		if err := f.set(flag, f.Flags[key]); err != nil {
+			return err
+		}
+	}
+	for _, name := range sortedKeys(f.Env) {
+		if _, ok := os.LookupEnv(name); ok {
+			continue
+		}
+		if err := os.Setenv(name, f.Env[name]); err != nil {
+			return f.errorf(EnvKey+"."+name, "%v", err)
+		}
+	}
+	return nil
+}
+
+func (f *File) set(flag *pflag.Flag, value any) error {
+	if slice, ok := flag.Value.(pflag.SliceValue); ok {
+		values, err := f.strings(flag.Name, value)
+		if err != nil {
+			return err
+		}
+		if err := slice.Replace(values); err != nil {
+			return f.errorf(flag.Name, "invalid value: %v", err)
+		}
+		flag.Changed = true
+		return nil
+	}
+	scalar, err := f.scalar(flag.Name, value)
+	if err != nil {
+		return err
+	}
+	if err := flag.Value.Set(scalar); err != nil {
+		return f.errorf(flag.Name, "invalid %s %q: %v", flag.Value.Type(), scalar, err)
+	}
+	flag.Changed = true
+	return nil
+}
+
+func (f *File) scalar(key string, value any) (string, error) {
+	switch value := value.(type) {
+	case string:
+		return value, nil
+	case bool, int, int64, uint64, float64:
+		return fmt.Sprint(value), nil
+	default:
+		return "", f.errorf(key, "must be a single value")
+	}
+}
+
+func (f *File) strings(key string, value any) ([]string, error) {
+	list, ok := value.([]any)
+	if !ok {
+		return nil, f.errorf(key, "must be a list")
+	}
+	values := make([]string, len(list))
+	for i, item := range list {
+		scalar, err := f.scalar(fmt.Sprintf("%s[%d]", key, i), item)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = scalar
+	}
+	return values, nil
+}
+
+func (f *File) errorf(key string, format string, args ...any) error {
+	return fmt.Errorf("%s: %q: %s", f.Path, key, fmt.Sprintf(format, args...))
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Write the effective configuration of the flags and of the environment in the format, toml or
+// yaml, so it can be loaded back. The secrets in the environment are masked.
+func Print(w io.Writer, format string, flags *pflag.FlagSet, application []string) error {
+	values := map[string]any{}
+	var err error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if ignoredFlags[flag.Name] || err != nil {
+			return
+		}
+		values[flag.Name], err = typedValue(flag)
+	})
+	if err != nil {
+		return err
+	}
+	if len(application) > 0 {
+		values[ApplicationKey] = application
+	}
+	env := map[string]string{}
+	for _, name := range EnvKeys {
+		if value, ok := os.LookupEnv(name); ok {
+			if secretEnvKeys[name] {
+				value = "***"
+			}
+			env[name] = value
+		}
+	}
+	if len(env) > 0 {
+		values[EnvKey] = env
+	}
+	switch format {
+	case "toml":
+		return toml.NewEncoder(w).Encode(values)
+	case "yaml":
+		encoder := yaml.NewEncoder(w)
+		defer encoder.Close()
+		return encoder.Encode(values)
+	default:
+		return fmt.Errorf("unknown format %q; use toml or yaml", format)
+	}
+}
+
+func typedValue(flag *pflag.Flag) (any, error) {
+	if slice, ok := flag.Value.(pflag.SliceValue); ok {
+		return slice.GetSlice(), nil
+	}
+	value := flag.Value.String()
+	switch flag.Value.Type() {
+	case "bool":
+		return strconv.ParseBool(value)
+	case "int", "int64":
+		return strconv.ParseInt(value, 10, 64)
+	case "uint64":
+		return strconv.ParseUint(value, 10, 64)
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/suite"
+)
+
+type ConfigSuite struct {
+	suite.Suite
+	tempDir   string
+	flags     *pflag.FlagSet
+	port      int
+	sequencer string
+	mock      bool
+	timeout   time.Duration
+	watch     []string
+}
+
+func TestConfigSuite(t *testing.T) {
+	suite.Run(t, new(ConfigSuite))
+}
+
+func (s *ConfigSuite) SetupTest() {
+	s.tempDir = s.T().TempDir()
+	s.flags = pflag.NewFlagSet("nonodo", pflag.ContinueOnError)
+	s.flags.IntVar(&s.port, "http-port", 8080, "") // nolint
+	s.flags.StringVar(&s.sequencer, "sequencer", "inputbox", "")
+	s.flags.BoolVar(&s.mock, "espresso-mock", false, "")
+	s.flags.DurationVar(&s.timeout, "timeout-worker", time.Second, "")
+	s.flags.StringArrayVar(&s.watch, "watch", nil, "")
+	s.flags.String(ConfigFlag, "", "")
+}
+
+func (s *ConfigSuite) write(name string, content string) string {
+	path := filepath.Join(s.tempDir, name)
+	s.Require().NoError(os.WriteFile(path, []byte(content), 0600)) // nolint
+	return path
+}
+
+func (s *ConfigSuite) load(name string, content string) *File {
+	file, err := Load(s.write(name, content))
+	s.Require().NoError(err)
+	return file
+}
+
+func (s *ConfigSuite) TestItLoadsTOML() {
+	file := s.load("nonodo.toml", `
+sequencer = "espresso"
+watch = ["*.py", "src/**/*.py"]
+application = ["python3", "app.py"]
+
+[http]
+port = 9090
+
+[espresso]
+mock = true
+
+[timeout]
+worker = "30s"
+`)
+	s.Require().NoError(file.Apply(s.flags))
+	s.Equal(9090, s.port) // nolint
+	s.Equal("espresso", s.sequencer)
+	s.True(s.mock)
+	s.Equal(30*time.Second, s.timeout) // nolint
+	s.Equal([]string{"*.py", "src/**/*.py"}, s.watch)
+	s.Equal([]string{"python3", "app.py"}, file.Application)
+	s.True(s.flags.Changed("http-port"))
+}
+
+func (s *ConfigSuite) TestItLoadsYAML() {
+	file := s.load("nonodo.yml", `
+http-port: 9090
+espresso:
+  mock: true
+`)
+	s.Require().NoError(file.Apply(s.flags))
+	s.Equal(9090, s.port) // nolint
+	s.True(s.mock)
+}
+
+func (s *ConfigSuite) TestTheFlagsTakePrecedence() {
+	s.Require().NoError(s.flags.Parse([]string{"--http-port", "7000", "--watch", "*.go"}))
+	file := s.load("nonodo.toml", `
+http-port = 9090
+sequencer = "espresso"
+watch = ["*.py"]
+`)
+	s.Require().NoError(file.Apply(s.flags))
+	s.Equal(7000, s.port) // nolint
+	s.Equal("espresso", s.sequencer)
+	s.Equal([]string{"*.go"}, s.watch)
+}
+
+func (s *ConfigSuite) TestTheEnvironmentTakesPrecedence() {
+	s.T().Setenv("TIA_URL", "http://env")
+	s.T().Setenv("TIA_AUTH_TOKEN", "")
+	s.Require().NoError(os.Unsetenv("TIA_AUTH_TOKEN"))
+	file := s.load("nonodo.toml", `
+[env]
+TIA_URL = "http://file"
+TIA_AUTH_TOKEN = "token"
+`)
+	s.Require().NoError(file.Apply(s.flags))
+	s.Equal("http://env", os.Getenv("TIA_URL"))
+	s.Equal("token", os.Getenv("TIA_AUTH_TOKEN"))
+}
+
+func (s *ConfigSuite) TestTheErrorsNameTheKey() {
+	file := s.load("nonodo.toml", `[htp]
+port = 1`)
+	s.ErrorContains(file.Apply(s.flags), `"htp-port": unknown key`)
+
+	file = s.load("nonodo.toml", `http-port = "abc"`)
+	s.ErrorContains(file.Apply(s.flags), `"http-port": invalid int "abc"`)
+
+	file = s.load("nonodo.toml", `watch = "*.py"`)
+	s.ErrorContains(file.Apply(s.flags), `"watch": must be a list`)
+
+	file = s.load("nonodo.toml", `config = "other.toml"`)
+	s.ErrorContains(file.Apply(s.flags), `"config": unknown key`)
+
+	_, err := Load(s.write("nonodo.toml", `[env]
+TIA_ULR = "http://file"`))
+	s.ErrorContains(err, `"env.TIA_ULR": unknown environment variable`)
+
+	_, err = Load(s.write("nonodo.json", `{}`))
+	s.ErrorContains(err, `unknown format ".json"`)
+}
+
+func (s *ConfigSuite) TestItPrintsTheEffectiveConfiguration() {
+	s.T().Setenv("TIA_AUTH_TOKEN", "token")
+	s.Require().NoError(s.flags.Parse([]string{"--http-port", "7000"}))
+	var out bytes.Buffer
+	s.Require().NoError(Print(&out, "toml", s.flags, []string{"python3", "app.py"}))
+	s.Contains(out.String(), "http-port = 7000\n")
+	s.Contains(out.String(), "timeout-worker = '1s'\n")
+	s.Contains(out.String(), "application = ['python3', 'app.py']\n")
+	s.Contains(out.String(), "TIA_AUTH_TOKEN = '***'\n")
+	s.NotContains(out.String(), "config")
+
+	// the output can be loaded back
+	file := s.load("printed.toml", out.String())
+	s.Equal(int64(7000), file.Flags["http-port"]) // nolint
+	s.Equal([]string{"python3", "app.py"}, file.Application)
+}
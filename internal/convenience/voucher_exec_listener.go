@@ -8,6 +8,9 @@ import (
 	"time"
 
 	"github.com/calindra/nonodo/internal/contracts"
+	"github.com/calindra/nonodo/internal/events"
+	"github.com/calindra/nonodo/internal/metrics"
+	"github.com/cartesi/rollups-graphql/pkg/convenience/model"
 	"github.com/cartesi/rollups-graphql/pkg/convenience/services"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
@@ -25,6 +28,8 @@ type VoucherExecListener struct {
 	EventName          string
 	ConvenienceService *services.ConvenienceService
 	FromBlock          *big.Int
+	// Receives the executed vouchers; optional.
+	Publisher events.Publisher
 }
 
 func NewExecListener(
@@ -43,38 +48,80 @@ func NewExecListener(
 }
 
 // on event callback
+// The values are those of the OutputExecuted(uint64 outputIndex, bytes output) event of the
+// application, or the voucher id of the v1 VoucherExecuted event.
 func (x VoucherExecListener) OnEvent(
 	eventValues []interface{},
 	timestamp,
 	blockNumber uint64,
 ) error {
-	if len(eventValues) != 1 {
-		return fmt.Errorf("wrong event values length != 1")
-	}
-	voucherId, ok := eventValues[0].(*big.Int)
-	if !ok {
-		return fmt.Errorf("cannot cast voucher id to big.Int")
-	}
+	ctx := context.Background()
+	var inputIndex, outputIndex uint64
+	switch len(eventValues) {
+	case 1:
+		voucherId, ok := eventValues[0].(*big.Int)
+		if !ok {
+			return fmt.Errorf("cannot cast voucher id to big.Int")
+		}
 
-	// Extract voucher and input using bit masking and shifting
-	var bitsToShift uint = 128
-	var maxHexBytes uint64 = 0xFFFFFFFFFFFFFFFF
-	bitMask := new(big.Int).SetUint64(maxHexBytes)
-	voucher := new(big.Int).Rsh(voucherId, bitsToShift)
-	input := new(big.Int).And(voucherId, bitMask)
+		// Extract voucher and input using bit masking and shifting
+		var bitsToShift uint = 128
+		var maxHexBytes uint64 = 0xFFFFFFFFFFFFFFFF
+		bitMask := new(big.Int).SetUint64(maxHexBytes)
+		outputIndex = new(big.Int).Rsh(voucherId, bitsToShift).Uint64()
+		inputIndex = new(big.Int).And(voucherId, bitMask).Uint64()
+		slog.Debug("Voucher Executed", "voucherId", voucherId.String())
+	case 2: // nolint
+		index, ok := eventValues[0].(uint64)
+		if !ok {
+			return fmt.Errorf("cannot cast output index to uint64")
+		}
+		voucher, err := x.findVoucher(ctx, index)
+		if err != nil {
+			return err
+		}
+		if voucher == nil {
+			slog.Warn("execlistener: executed output is not a known voucher", "outputIndex", index)
+			return nil
+		}
+		inputIndex, outputIndex = voucher.InputIndex, voucher.OutputIndex
+	default:
+		return fmt.Errorf("wrong event values length %d", len(eventValues))
+	}
 
-	// Print the extracted voucher and input
 	slog.Debug("Decoded voucher params",
-		"voucher", voucher,
-		"input", input,
+		"voucher", outputIndex,
+		"input", inputIndex,
 		"blockNumber", blockNumber,
 	)
 
-	// Print decoded event data
-	slog.Debug("Voucher Executed", "voucherId", voucherId.String())
+	err := x.ConvenienceService.UpdateExecuted(ctx, inputIndex, outputIndex, true)
+	if err != nil {
+		return err
+	}
+	if x.Publisher != nil {
+		index := int(outputIndex)
+		x.Publisher.Publish(events.Event{
+			Type:        events.VoucherExecuted,
+			AppContract: x.ApplicationAddress.Hex(),
+			InputIndex:  int(inputIndex),
+			OutputIndex: &index,
+			BlockNumber: blockNumber,
+		})
+	}
+	return nil
+}
 
-	ctx := context.Background()
-	return x.ConvenienceService.UpdateExecuted(ctx, input.Uint64(), voucher.Uint64(), true)
+// Find the voucher, or delegate call voucher, of the application by its output index.
+func (x VoucherExecListener) findVoucher(ctx context.Context, outputIndex uint64) (*model.ConvenienceVoucher, error) {
+	for _, isDelegatedCall := range []bool{false, true} {
+		voucher, err := x.ConvenienceService.VoucherRepository.FindVoucherByOutputIndexAndAppContract(
+			ctx, outputIndex, &x.ApplicationAddress, isDelegatedCall)
+		if err != nil || voucher != nil {
+			return voucher, err
+		}
+	}
+	return nil, nil
 }
 
 // String implements supervisor.Worker.
@@ -90,16 +137,17 @@ func (x VoucherExecListener) Start(ctx context.Context, ready chan<- struct{}) e
 	var err error
 
 	for {
-		ctxDial, cancel := context.WithCancel(ctx)
-		defer cancel()
-
-		client, err = ethclient.DialContext(ctxDial, x.Provider)
+		client, err = ethclient.DialContext(ctx, x.Provider)
 		if err == nil {
 			break
 		}
 
 		slog.Error("execlistener: dial: ", "error", err)
-		time.Sleep(delay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
 	}
 	ready <- struct{}{}
 	return x.WatchExecutions(ctx, client)
@@ -150,6 +198,7 @@ func (x *VoucherExecListener) WatchExecutions(ctx context.Context, client *ethcl
 		err = x.ReadPastExecutions(ctxPastInputs, client, contractABI, query)
 		if err != nil {
 			slog.Error("unexpected readPastExecutions error", "error", err)
+			metrics.Reconnects.WithLabelValues("voucher_exec").Inc()
 			time.Sleep(reconnectDelay)
 			continue
 		}
@@ -162,6 +211,7 @@ func (x *VoucherExecListener) WatchExecutions(ctx context.Context, client *ethcl
 		sub, err := client.SubscribeFilterLogs(ctxEth, query, logs)
 		if err != nil {
 			slog.Error("unexpected subscribe error", "error", err)
+			metrics.Reconnects.WithLabelValues("voucher_exec").Inc()
 			time.Sleep(reconnectDelay)
 			continue
 		}
@@ -200,6 +250,7 @@ func (x *VoucherExecListener) WatchExecutions(ctx context.Context, client *ethcl
 		if err != nil {
 			slog.Error("VoucherExecListener", "error", err)
 			slog.Info("VoucherExecListener reconnecting", "reconnectDelay", reconnectDelay)
+			metrics.Reconnects.WithLabelValues("voucher_exec").Inc()
 			time.Sleep(reconnectDelay)
 		} else {
 			return nil
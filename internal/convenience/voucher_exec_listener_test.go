@@ -5,7 +5,10 @@ import (
 	"log/slog"
 	"math/big"
 	"testing"
+	"time"
 
+	"github.com/calindra/nonodo/internal/contracts"
+	"github.com/calindra/nonodo/internal/events"
 	"github.com/cartesi/rollups-graphql/pkg/commons"
 	"github.com/cartesi/rollups-graphql/pkg/convenience/model"
 	"github.com/cartesi/rollups-graphql/pkg/convenience/repository"
@@ -126,3 +129,63 @@ func createVoucherMetadataOrFail(s *ExecListenerSuite, voucher *model.Convenienc
 		panic(err)
 	}
 }
+
+type channelSink chan events.Event
+
+func (c channelSink) String() string {
+	return "channel"
+}
+
+func (c channelSink) Send(ctx context.Context, event events.Event) error {
+	c <- event
+	return nil
+}
+
+func (s *ExecListenerSuite) TestItPublishesTheExecutedOutput() {
+	createVoucherMetadataOrFail(s, &model.ConvenienceVoucher{
+		Destination: Bob,
+		Payload:     "0x1122",
+		InputIndex:  4,
+		OutputIndex: 7,
+		AppContract: Token,
+	})
+	sink := make(channelSink, 1)
+	dispatcher := events.NewDispatcher(sink)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second) // nolint
+	defer cancel()
+	ready := make(chan struct{}, 1)
+	go func() {
+		_ = dispatcher.Start(ctx, ready)
+	}()
+	<-ready
+
+	// the values as unpacked from the OutputExecuted event of the application
+	contractABI, err := contracts.ApplicationMetaData.GetAbi()
+	s.Require().NoError(err)
+	data, err := contractABI.Events["OutputExecuted"].Inputs.Pack(uint64(7), []byte{0x11, 0x22}) // nolint
+	s.Require().NoError(err)
+	values, err := contractABI.Unpack("OutputExecuted", data)
+	s.Require().NoError(err)
+
+	listener := NewExecListener("not a problem", Token, s.ConvenienceService, nil)
+	listener.Publisher = dispatcher
+	s.Require().NoError(listener.OnEvent(values, 9999, 2008)) // nolint
+
+	voucher, err := s.repository.FindVoucherByInputAndOutputIndex(ctx, 4, 7) // nolint
+	s.Require().NoError(err)
+	s.True(voucher.Executed)
+	select {
+	case event := <-sink:
+		s.Equal(events.VoucherExecuted, event.Type)
+		s.Equal(Token.Hex(), event.AppContract)
+		s.Equal(4, event.InputIndex)
+		s.Equal(7, *event.OutputIndex)
+		s.Equal(uint64(2008), event.BlockNumber)
+	case <-ctx.Done():
+		s.Fail("the event didn't reach the sink")
+	}
+
+	// the notices are not vouchers
+	values[0] = uint64(8)
+	s.NoError(listener.OnEvent(values, 9999, 2008)) // nolint
+}
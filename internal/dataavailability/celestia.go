@@ -27,12 +27,16 @@ import (
 	"github.com/tendermint/tendermint/rpc/client/http"
 
 	"github.com/calindra/nonodo/internal/contracts"
+	blobstreamx "github.com/calindra/nonodo/internal/dataavailability/contracts/BlobstreamX.sol"
 )
 
 var CELESTIA_RELAY_ADDRESS common.Address = common.HexToAddress("0x4e64020dc800f02decb8Bd45bB4D0f74048e7535")
 
 // var CELESTIA_RELAY_ADDRESS common.Address = common.HexToAddress("0x096a7847B754647e06A887BeF0192689148A0C33")
 
+// Chain of the BlobstreamX contract.
+const BlobstreamRpcUrl = "https://arbitrum-sepolia-rpc.publicnode.com"
+
 type GioReqParams struct {
 	Namespace []byte
 	Height    *big.Int
@@ -222,7 +226,7 @@ func GetBlob(ctx context.Context, id string, url string, token string) ([]byte,
 }
 
 func connections() (eth *ethclient.Client, trpc *http.HTTP, err error) {
-	ethEndpoint := "https://arbitrum-sepolia-rpc.publicnode.com"
+	ethEndpoint := BlobstreamRpcUrl
 	trpcEndpoint := "https://celestia-mocha-rpc.publicnode.com:443"
 
 	eth, err = ethclient.Dial(ethEndpoint)
@@ -237,6 +241,18 @@ func connections() (eth *ethclient.Client, trpc *http.HTTP, err error) {
 	return eth, trpc, nil
 }
 
+// FindDataCommitment returns the Blobstream data commitment that covers the Celestia height,
+// scanning the last `blocks` blocks of the Blobstream chain.
+// It returns ErrDataCommitmentNotFound while there is none.
+func FindDataCommitment(ctx context.Context, height uint64, blocks uint64) (*blobstreamx.BlobstreamXDataCommitmentStored, error) {
+	eth, err := ethclient.DialContext(ctx, BlobstreamRpcUrl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the Ethereum node: %w", err)
+	}
+	defer eth.Close()
+	return GetDataCommitment(eth, int64(height), blocks)
+}
+
 // GetShareProof returns the share proof for the given share pointer.
 // Ready to be used with the DAVerifier library.
 // RE: https://docs.celestia.org/developers/blobstream-proof-queries#example-rollup-that-uses-the-daverifier
@@ -303,56 +319,57 @@ func GetShareProof(ctx context.Context, height uint64, start uint64, end uint64)
 	}, blockDataRoot, nil
 }
 
-func CallCelestiaRelay(ctx context.Context, height uint64, start uint64, end uint64, dappAddress common.Address, execLayerData []byte, ethEndpointRPC string, chainId int64) error {
+// CallCelestiaRelay relays the shares of a blob to the application and returns the transaction hash.
+func CallCelestiaRelay(ctx context.Context, height uint64, start uint64, end uint64, dappAddress common.Address, execLayerData []byte, ethEndpointRPC string, chainId int64) (common.Hash, error) {
 	pk_celestia := os.Getenv("PK_CELESTIA")
 
 	if pk_celestia == "" {
-		return fmt.Errorf("missing Celestia private key")
+		return common.Hash{}, fmt.Errorf("missing Celestia private key")
 	}
 
 	// Connect to an Ethereum node
 	eth, err := ethclient.Dial(ethEndpointRPC)
 	if err != nil {
-		return fmt.Errorf("failed to connect to the Ethereum node: %w", err)
+		return common.Hash{}, fmt.Errorf("failed to connect to the Ethereum node: %w", err)
 	}
 	defer eth.Close()
 
 	proofs, _, err := GetShareProof(ctx, height, start, end)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	// Load your private key
 	privateKey, err := crypto.HexToECDSA(pk_celestia)
 	if err != nil {
-		return fmt.Errorf("failed to create private key: %w", err)
+		return common.Hash{}, fmt.Errorf("failed to create private key: %w", err)
 	}
 
 	// Get the public key address
 	publicKey := privateKey.Public()
 	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
 	if !ok {
-		return fmt.Errorf("failed to create public key: %w", err)
+		return common.Hash{}, fmt.Errorf("failed to create public key: %w", err)
 	}
 	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
 
 	// Get the nonce (number of transactions sent by the sender)
 	nonce, err := eth.PendingNonceAt(ctx, fromAddress)
 	if err != nil {
-		return fmt.Errorf("failed to get nonce: %w", err)
+		return common.Hash{}, fmt.Errorf("failed to get nonce: %w", err)
 	}
 
 	// Set the gas price
 	gasPrice, err := eth.SuggestGasPrice(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get gas price: %w", err)
+		return common.Hash{}, fmt.Errorf("failed to get gas price: %w", err)
 	}
 
 	// Set up the transaction options
 	gasLimit := 3000000
 	auth, err := bind.NewKeyedTransactorWithChainID(privateKey, big.NewInt(chainId))
 	if err != nil {
-		return fmt.Errorf("failed to create transactor: %w", err)
+		return common.Hash{}, fmt.Errorf("failed to create transactor: %w", err)
 	}
 	auth.Nonce = big.NewInt(int64(nonce))
 	auth.Value = big.NewInt(0)
@@ -367,19 +384,19 @@ func CallCelestiaRelay(ctx context.Context, height uint64, start uint64, end uin
 	}
 	relay, err := contracts.NewCelestiaRelay(celestiaRelayAddress, eth)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	// Call the contract
 	slog.Debug("call relay shares", "dappAddress", dappAddress)
 	trx, err := relay.RelayShares(auth, dappAddress, *proofs, execLayerData)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	slog.Info("Transaction", "trx", trx)
 
-	return nil
+	return trx.Hash(), nil
 }
 
 func FetchFromTendermint(ctx context.Context, id string) (*string, error) {
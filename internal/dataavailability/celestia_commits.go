@@ -2,6 +2,7 @@ package dataavailability
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	blobstreamx "github.com/calindra/nonodo/internal/dataavailability/contracts/BlobstreamX.sol"
@@ -15,6 +16,9 @@ var contractAddress common.Address = common.HexToAddress("0xc3e209eb245Fd59c8586
 
 const maxFilterRange = uint64(10_000)
 
+// Returned while Blobstream has not stored a data commitment for the height.
+var ErrDataCommitmentNotFound = errors.New("no matching data commitment found")
+
 // GetDataCommitment returns the data commitment event matching the given height
 // within the last `blocks` blocks.
 // Example usage:
@@ -60,7 +64,7 @@ func GetDataCommitment(eth *ethclient.Client, height int64, blocks uint64) (*blo
 		}
 	}
 
-	return nil, fmt.Errorf("no matching data commitment found")
+	return nil, ErrDataCommitmentNotFound
 }
 
 func findMatchingDataCommitment(contract *blobstreamx.BlobstreamX, start uint64, end uint64, height int64) (*blobstreamx.BlobstreamXDataCommitmentStored, error) {
@@ -18,6 +18,9 @@ import (
 
 	"github.com/calindra/nonodo/internal/commons"
 	"github.com/calindra/nonodo/internal/supervisor"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 // Default port for the Ethereum node.
@@ -47,15 +50,35 @@ type AnvilWorker struct {
 	AnvilCmd           string
 	AnvilBlockTime     time.Duration
 	AnvilStateFileName *string
+	// If set, fork the chain from this RPC instead of loading the state file.
+	ForkUrl string
+	// Block number to fork from; zero means the latest block.
+	ForkBlock uint64
 }
 
 // Define a struct to represent the structure of your JSON data
 type ContractInfo struct {
+	Name      string `json:"name"`
+	ChainId   string `json:"chainId"`
 	Contracts map[string]struct {
 		Address string `json:"address"`
+		// Block of the deployment; optional.
+		BlockNumber uint64 `json:"blockNumber,omitempty"`
 	} `json:"contracts"`
 }
 
+// Return the address of the contract with the given name.
+func (c *ContractInfo) Address(name string) (common.Address, error) {
+	contract, ok := c.Contracts[name]
+	if !ok {
+		return common.Address{}, fmt.Errorf("anvil: contract %s not found in the address book", name)
+	}
+	if !common.IsHexAddress(contract.Address) {
+		return common.Address{}, fmt.Errorf("anvil: invalid address for contract %s: %q", name, contract.Address)
+	}
+	return common.HexToAddress(contract.Address), nil
+}
+
 func (w AnvilWorker) String() string {
 	return anvilCommand
 }
@@ -103,27 +126,49 @@ func GetContractInfo() *ContractInfo {
 	return &contracts
 }
 
+// Load the address book from a deployment file in the same format as localhost.json.
+// The InputBox, ApplicationFactory and AuthorityFactory contracts are required.
+func LoadContractInfo(path string) (*ContractInfo, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("anvil: failed to read deployment file: %w", err)
+	}
+	var contracts ContractInfo
+	if err := json.Unmarshal(content, &contracts); err != nil {
+		return nil, fmt.Errorf("anvil: failed to parse deployment file %s: %w", path, err)
+	}
+	for _, name := range []string{"InputBox", "ApplicationFactory", "AuthorityFactory"} {
+		if _, err := contracts.Address(name); err != nil {
+			return nil, fmt.Errorf("%w (file %s)", err, path)
+		}
+	}
+	return &contracts, nil
+}
+
 func ShowAddresses() {
 	contracts := GetContractInfo()
-	var names []string
-	for name := range contracts.Contracts {
-		names = append(names, name)
-	}
-	names = append(names, ApplicationContractName)
 	contracts.Contracts[ApplicationContractName] = struct {
-		Address string "json:\"address\""
+		Address     string `json:"address"`
+		BlockNumber uint64 `json:"blockNumber,omitempty"`
 	}{
 		Address: ApplicationAddress,
 	}
+	PrintAddresses(contracts)
+}
+
+// Print the contracts of the address book sorted by name.
+func PrintAddresses(contracts *ContractInfo) {
+	var names []string
+	for name := range contracts.Contracts {
+		names = append(names, name)
+	}
 	sort.Strings(names)
 	space := 28
 	addressSpace := 42
 	fmt.Printf("%-28s %s\n", "Contract", "Address")
 	fmt.Printf("%-28s %s\n", strings.Repeat("─", space), strings.Repeat("─", addressSpace))
 	for _, name := range names {
-		if contract, ok := contracts.Contracts[name]; ok {
-			fmt.Printf("%-28s %s\n", name, contract.Address)
-		}
+		fmt.Printf("%-28s %s\n", name, contracts.Contracts[name].Address)
 	}
 }
 
@@ -141,38 +186,78 @@ func (w AnvilWorker) loadState() ([]byte, error) {
 }
 
 func (w AnvilWorker) Start(ctx context.Context, ready chan<- struct{}) error {
-	content, err := w.loadState()
-	if err != nil {
-		return err
-	}
-	dir, err := makeStateTemp(content)
-	if err != nil {
-		return err
+	var stateFile string
+	if w.ForkUrl == "" {
+		content, err := w.loadState()
+		if err != nil {
+			return err
+		}
+		dir, err := makeStateTemp(content)
+		if err != nil {
+			return err
+		}
+		defer removeTemp(dir)
+		slog.Debug("anvil: created temp dir with state file", "dir", dir)
+		stateFile = filepath.Join(dir, StateFileName)
+	} else {
+		slog.Info("anvil: forking chain", "url", w.ForkUrl, "block", w.ForkBlock)
 	}
-	defer removeTemp(dir)
-	slog.Debug("anvil: created temp dir with state file", "dir", dir)
 
 	if w.AnvilCmd == "" {
 		w.AnvilCmd = anvilCommand
 	}
 
-	var seconds uint64 = uint64(w.AnvilBlockTime.Seconds())
-
 	var server supervisor.ServerWorker
 	server.Name = anvilCommand
 	server.Command = w.AnvilCmd
 	server.Port = w.Port
-	server.Args = append(server.Args, "--host", fmt.Sprint(w.Address))
-	server.Args = append(server.Args, "--port", fmt.Sprint(w.Port))
-	server.Args = append(server.Args, "--load-state", filepath.Join(dir, StateFileName))
+	server.Args = w.args(stateFile)
+	return server.Start(ctx, ready)
+}
+
+// Build the anvil arguments; the state file is ignored when forking.
+func (w AnvilWorker) args(stateFile string) []string {
+	var seconds uint64 = uint64(w.AnvilBlockTime.Seconds())
+
+	var args []string
+	args = append(args, "--host", fmt.Sprint(w.Address))
+	args = append(args, "--port", fmt.Sprint(w.Port))
+	if w.ForkUrl != "" {
+		args = append(args, "--fork-url", w.ForkUrl)
+		if w.ForkBlock > 0 {
+			args = append(args, "--fork-block-number", fmt.Sprint(w.ForkBlock))
+		}
+	} else {
+		args = append(args, "--load-state", stateFile)
+	}
 	if seconds > 0 {
-		server.Args = append(server.Args, "--block-time", fmt.Sprint(seconds))
+		args = append(args, "--block-time", fmt.Sprint(seconds))
 	}
-	// server.Args = append(server.Args, "--tracing")
+	// args = append(args, "--tracing")
 	if !w.Verbose {
-		server.Args = append(server.Args, "--silent")
+		args = append(args, "--silent")
 	}
-	return server.Start(ctx, ready)
+	return args
+}
+
+// Probe implements supervisor.Prober by checking whether Anvil accepts connections.
+func (w AnvilWorker) Probe(ctx context.Context) error {
+	var server supervisor.ServerWorker
+	server.Port = w.Port
+	return server.Probe(ctx)
+}
+
+// Mine the blocks on the Anvil at the url.
+func Mine(ctx context.Context, rpcUrl string, blocks uint64) error {
+	client, err := rpc.DialContext(ctx, rpcUrl)
+	if err != nil {
+		return fmt.Errorf("anvil: failed to dial: %w", err)
+	}
+	defer client.Close()
+	if err := client.CallContext(ctx, nil, "anvil_mine", hexutil.Uint64(blocks)); err != nil {
+		return fmt.Errorf("anvil: failed to mine: %w", err)
+	}
+	return nil
 }
 
 // Create a temporary directory with the state file in it.
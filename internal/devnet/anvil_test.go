@@ -6,6 +6,8 @@ package devnet
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -83,6 +85,40 @@ func (s *AnvilSuite) TestAnvilWorker() {
 	s.True(canceled)
 }
 
+func (s *AnvilSuite) TestAnvilArgsWhenForking() {
+	w := AnvilWorker{
+		Address:   AnvilDefaultAddress,
+		Port:      AnvilDefaultPort,
+		ForkUrl:   "http://localhost:9999",
+		ForkBlock: 42,
+	}
+	args := w.args("state.json")
+	s.Subset(args, []string{"--fork-url", "http://localhost:9999", "--fork-block-number", "42"})
+	s.NotContains(args, "--load-state")
+
+	w.ForkUrl = ""
+	args = w.args("state.json")
+	s.Subset(args, []string{"--load-state", "state.json"})
+	s.NotContains(args, "--fork-url")
+}
+
+func (s *AnvilSuite) TestLoadContractInfo() {
+	dir := s.T().TempDir()
+	valid := filepath.Join(dir, "valid.json")
+	s.Require().NoError(os.WriteFile(valid, localhost, 0644)) // nolint
+	contracts, err := LoadContractInfo(valid)
+	s.Require().NoError(err)
+	inputBox, err := contracts.Address("InputBox")
+	s.NoError(err)
+	s.Equal(common.HexToAddress(InputBoxAddress), inputBox)
+
+	missing := filepath.Join(dir, "missing.json")
+	content := `{"contracts": {"InputBox": {"address": "0x593E5BCf894D6829Dd26D0810DA7F064406aebB6"}}}`
+	s.Require().NoError(os.WriteFile(missing, []byte(content), 0644)) // nolint
+	_, err = LoadContractInfo(missing)
+	s.ErrorContains(err, "contract ApplicationFactory not found")
+}
+
 //
 // Suite entry point
 //
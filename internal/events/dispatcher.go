@@ -0,0 +1,91 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Number of events kept for each sink while it is busy; newer events are dropped when it is full.
+const DispatcherBuffer = 1024
+
+// Time to deliver the queued events when nonodo stops.
+const DrainTimeout = 5 * time.Second
+
+// Worker that delivers the published events to every sink, in order.
+// Publishing never blocks the model, and each sink has its own queue, so a slow sink doesn't
+// hold back the others; a sink that fails only logs the error.
+type Dispatcher struct {
+	sinks  []Sink
+	queues []chan Event
+}
+
+func NewDispatcher(sinks ...Sink) *Dispatcher {
+	queues := make([]chan Event, len(sinks))
+	for i := range queues {
+		queues[i] = make(chan Event, DispatcherBuffer)
+	}
+	return &Dispatcher{sinks: sinks, queues: queues}
+}
+
+func (d *Dispatcher) String() string {
+	return "events"
+}
+
+func (d *Dispatcher) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	for i, queue := range d.queues {
+		select {
+		case queue <- event:
+		default:
+			slog.Warn("events: queue is full; dropping the event", "sink", d.sinks[i], "type", event.Type)
+		}
+	}
+}
+
+func (d *Dispatcher) Start(ctx context.Context, ready chan<- struct{}) error {
+	var wg sync.WaitGroup
+	for i, sink := range d.sinks {
+		slog.Info("events: publishing", "sink", sink)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			deliver(ctx, sink, d.queues[i])
+		}()
+	}
+	ready <- struct{}{}
+	<-ctx.Done()
+	wg.Wait()
+	return ctx.Err()
+}
+
+// Send the events of the queue to the sink until the context is done, and then the events
+// still in the queue, so the last changes aren't lost on exit.
+func deliver(ctx context.Context, sink Sink, queue chan Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			drainCtx, cancel := context.WithTimeout(context.Background(), DrainTimeout)
+			defer cancel()
+			for {
+				select {
+				case event := <-queue:
+					send(drainCtx, sink, event)
+				default:
+					return
+				}
+			}
+		case event := <-queue:
+			send(ctx, sink, event)
+		}
+	}
+}
+
+func send(ctx context.Context, sink Sink, event Event) {
+	if err := sink.Send(ctx, event); err != nil {
+		slog.Warn("events: failed to send the event", "sink", sink, "type", event.Type, "error", err)
+	}
+}
@@ -0,0 +1,80 @@
+// Package events publishes the changes of the nonodo state, such as finished inputs and new
+// outputs, to pluggable sinks, so consumers can react without polling the GraphQL API.
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cartesi/rollups-graphql/pkg/convenience/model"
+)
+
+type Type string
+
+const (
+	// An advance input was added, with the status UNPROCESSED.
+	InputAdded Type = "input_added"
+	// An advance input was processed and has its final status.
+	InputFinished Type = "input_finished"
+	VoucherAdded  Type = "voucher_added"
+	NoticeAdded   Type = "notice_added"
+	ReportAdded   Type = "report_added"
+	// The claim of an epoch was sent to the consensus.
+	ClaimSubmitted Type = "claim_submitted"
+	// A voucher was executed on the L1.
+	VoucherExecuted Type = "voucher_executed"
+)
+
+// Every type of event.
+var Types = []Type{InputAdded, InputFinished, VoucherAdded, NoticeAdded, ReportAdded, ClaimSubmitted, VoucherExecuted}
+
+// Change of the nonodo state.
+type Event struct {
+	Type        Type      `json:"type"`
+	Time        time.Time `json:"time"`
+	AppContract string    `json:"appContract"`
+	// Index of the input; 0 for the claims, which are for every input of an epoch.
+	InputIndex int `json:"inputIndex"`
+	// Index of the output within the input; nil for the input events.
+	OutputIndex *int   `json:"outputIndex,omitempty"`
+	Status      string `json:"status,omitempty"`
+	Destination string `json:"destination,omitempty"`
+	Payload     string `json:"payload,omitempty"`
+	// Hash of the outputs of a claim.
+	Claim string `json:"claim,omitempty"`
+	// Last block of the epoch of a claim, or block of a voucher execution.
+	BlockNumber uint64 `json:"blockNumber,omitempty"`
+	// Set when a sink dropped the payload because it was too large.
+	PayloadOmitted bool `json:"payloadOmitted,omitempty"`
+}
+
+// Receive the events of nonodo.
+type Publisher interface {
+	// Publish the event without blocking.
+	Publish(event Event)
+}
+
+// Deliver the events to a consumer.
+type Sink interface {
+	fmt.Stringer
+	Send(ctx context.Context, event Event) error
+}
+
+// Names of the completion statuses, as in the GraphQL API.
+var statusNames = map[model.CompletionStatus]string{
+	model.CompletionStatusUnprocessed:                "UNPROCESSED",
+	model.CompletionStatusAccepted:                   "ACCEPTED",
+	model.CompletionStatusRejected:                   "REJECTED",
+	model.CompletionStatusException:                  "EXCEPTION",
+	model.CompletionStatusMachineHalted:              "MACHINE_HALTED",
+	model.CompletionStatusCycleLimitExceeded:         "CYCLE_LIMIT_EXCEEDED",
+	model.CompletionStatusTimeLimitExceeded:          "TIME_LIMIT_EXCEEDED",
+	model.CompletionStatusPayloadLengthLimitExceeded: "PAYLOAD_LENGTH_LIMIT_EXCEEDED",
+}
+
+// Name of the status in the GraphQL API.
+func StatusName(status model.CompletionStatus) (string, bool) {
+	name, ok := statusNames[status]
+	return name, ok
+}
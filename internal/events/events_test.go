@@ -0,0 +1,106 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type EventsSuite struct {
+	suite.Suite
+}
+
+func TestEventsSuite(t *testing.T) {
+	suite.Run(t, new(EventsSuite))
+}
+
+// Sink that keeps the events it receives.
+type memorySink struct {
+	mutex  sync.Mutex
+	events []Event
+}
+
+func (s *memorySink) String() string {
+	return "memory"
+}
+
+func (s *memorySink) Send(ctx context.Context, event Event) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *memorySink) received() []Event {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Event(nil), s.events...)
+}
+
+func (s *EventsSuite) TestTheDispatcherDeliversToEverySinkInOrder() {
+	first, second := &memorySink{}, &memorySink{}
+	dispatcher := NewDispatcher(first, second)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ready := make(chan struct{}, 1)
+	go dispatcher.Start(ctx, ready) // nolint: errcheck
+	<-ready
+	for i := 0; i < 3; i++ {
+		dispatcher.Publish(Event{Type: InputAdded, InputIndex: i})
+	}
+	s.Eventually(func() bool { return len(second.received()) == 3 }, time.Second, 10*time.Millisecond) // nolint
+	for i, event := range first.received() {
+		s.Equal(i, event.InputIndex)
+		s.False(event.Time.IsZero())
+	}
+	s.Equal(first.received(), second.received())
+}
+
+func (s *EventsSuite) TestTheDispatcherDrainsTheQueueOnExit() {
+	sink := &memorySink{}
+	dispatcher := NewDispatcher(sink)
+	dispatcher.Publish(Event{Type: InputAdded})
+	dispatcher.Publish(Event{Type: InputFinished})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := dispatcher.Start(ctx, make(chan struct{}, 1))
+	s.ErrorIs(err, context.Canceled)
+	s.Len(sink.received(), 2)
+}
+
+func (s *EventsSuite) TestTheDispatcherDropsTheEventsWhenFull() {
+	dispatcher := NewDispatcher(&memorySink{})
+	for i := 0; i < DispatcherBuffer+10; i++ {
+		dispatcher.Publish(Event{Type: InputAdded, InputIndex: i})
+	}
+	s.Len(dispatcher.queues[0], DispatcherBuffer)
+}
+
+func (s *EventsSuite) TestTheJSONLSinkWritesALinePerEvent() {
+	var buffer bytes.Buffer
+	sink := NewJSONLSink("stdout", &buffer)
+	outputIndex := 2
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	s.Require().NoError(sink.Send(context.Background(), Event{Type: InputFinished, Time: at, InputIndex: 1, Status: "ACCEPTED"}))
+	s.Require().NoError(sink.Send(context.Background(), Event{Type: NoticeAdded, Time: at, InputIndex: 1, OutputIndex: &outputIndex, Payload: "0x12"}))
+	s.Equal(`{"type":"input_finished","time":"2024-01-02T03:04:05Z","appContract":"","inputIndex":1,"status":"ACCEPTED"}
+{"type":"notice_added","time":"2024-01-02T03:04:05Z","appContract":"","inputIndex":1,"outputIndex":2,"payload":"0x12"}
+`, buffer.String())
+}
+
+func (s *EventsSuite) TestTheNotifyPayloadOmitsLargePayloads() {
+	small, err := notifyPayload(Event{Type: NoticeAdded, Payload: "0x1234"})
+	s.Require().NoError(err)
+	s.Contains(string(small), `"payload":"0x1234"`)
+
+	large, err := notifyPayload(Event{Type: NoticeAdded, Payload: "0x" + strings.Repeat("ab", maxNotifyPayload)})
+	s.Require().NoError(err)
+	s.LessOrEqual(len(large), maxNotifyPayload)
+	s.NotContains(string(large), `"payload"`)
+	s.Contains(string(large), `"payloadOmitted":true`)
+}
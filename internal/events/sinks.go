@@ -0,0 +1,69 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Channel of the Postgres notifications.
+const PostgresChannel = "nonodo_events"
+
+// Postgres rejects notification payloads of 8000 bytes or more.
+const maxNotifyPayload = 7999
+
+// Send each event as the JSON payload of a NOTIFY on the channel.
+// Consumers run LISTEN nonodo_events on the same database.
+type PostgresSink struct {
+	Db      *sqlx.DB
+	Channel string
+}
+
+func NewPostgresSink(db *sqlx.DB) *PostgresSink {
+	return &PostgresSink{Db: db, Channel: PostgresChannel}
+}
+
+func (s *PostgresSink) String() string {
+	return "postgres:" + s.Channel
+}
+
+func (s *PostgresSink) Send(ctx context.Context, event Event) error {
+	payload, err := notifyPayload(event)
+	if err != nil {
+		return err
+	}
+	_, err = s.Db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, s.Channel, string(payload))
+	return err
+}
+
+// JSON of the event that fits in a notification; the payload of the output is omitted if it
+// doesn't, and can be queried by the index.
+func notifyPayload(event Event) ([]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil || len(data) <= maxNotifyPayload {
+		return data, err
+	}
+	event.Payload = ""
+	event.PayloadOmitted = true
+	return json.Marshal(event)
+}
+
+// Write each event as a line of JSON.
+type JSONLSink struct {
+	Name    string
+	encoder *json.Encoder
+}
+
+func NewJSONLSink(name string, w io.Writer) *JSONLSink {
+	return &JSONLSink{Name: name, encoder: json.NewEncoder(w)}
+}
+
+func (s *JSONLSink) String() string {
+	return s.Name
+}
+
+func (s *JSONLSink) Send(ctx context.Context, event Event) error {
+	return s.encoder.Encode(event)
+}
@@ -0,0 +1,224 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Time to wait for a webhook to answer.
+const WebhookTimeout = 10 * time.Second
+
+// Attempts to deliver an event to a webhook, by default.
+const DefaultWebhookAttempts = 5
+
+// Time to wait before the second attempt; it doubles after each failure.
+const WebhookBackoff = time.Second
+
+// Headers of the webhook requests.
+const (
+	// HMAC-SHA256 of the body with the secret, as sha256=<hex>.
+	SignatureHeader = "X-Nonodo-Signature"
+	EventHeader     = "X-Nonodo-Event"
+	// Same for every attempt of a delivery, so the receiver can ignore the duplicates.
+	DeliveryHeader = "X-Nonodo-Delivery"
+)
+
+// POST each event as JSON to the url, retrying when the webhook is unreachable, answers 429
+// or fails with a 5xx status.
+type WebhookSink struct {
+	Url string
+	// Types of the events sent; every type if empty.
+	Types []Type
+	// Key of the signature of the body; unsigned if empty.
+	Secret      string
+	MaxAttempts int
+	Backoff     time.Duration
+	Client      *http.Client
+	// Records the result of each delivery; optional.
+	Log *DeliveryLog
+}
+
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{
+		Url:         url,
+		MaxAttempts: DefaultWebhookAttempts,
+		Backoff:     WebhookBackoff,
+		Client:      &http.Client{Timeout: WebhookTimeout},
+	}
+}
+
+func (s *WebhookSink) String() string {
+	return s.Url
+}
+
+func (s *WebhookSink) Send(ctx context.Context, event Event) error {
+	if len(s.Types) > 0 && !slices.Contains(s.Types, event.Type) {
+		return nil
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	id, err := newDeliveryId()
+	if err != nil {
+		return err
+	}
+	delivery := Delivery{Id: id, Url: s.Url, Type: event.Type, InputIndex: event.InputIndex}
+	backoff := s.Backoff
+	for {
+		delivery.Attempts++
+		delivery.StatusCode, err = s.post(ctx, id, event.Type, body)
+		if err == nil {
+			s.record(delivery, DeliverySucceeded, nil)
+			return nil
+		}
+		if delivery.Attempts >= s.MaxAttempts || !retryable(delivery.StatusCode) {
+			s.record(delivery, DeliveryFailed, err)
+			return err
+		}
+		slog.Debug("events: retrying the webhook", "url", s.Url, "attempt", delivery.Attempts, "error", err)
+		select {
+		case <-ctx.Done():
+			s.record(delivery, DeliveryFailed, err)
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// Post the body and return the status of the answer, 0 if there is none.
+func (s *WebhookSink) post(ctx context.Context, id string, eventType Type, body []byte) (int, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, s.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(EventHeader, string(eventType))
+	request.Header.Set(DeliveryHeader, id)
+	if s.Secret != "" {
+		request.Header.Set(SignatureHeader, Sign(s.Secret, body))
+	}
+	response, err := s.Client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 { // nolint
+		return response.StatusCode, fmt.Errorf("unexpected status %s", response.Status)
+	}
+	return response.StatusCode, nil
+}
+
+func (s *WebhookSink) record(delivery Delivery, status DeliveryStatus, err error) {
+	if s.Log == nil {
+		return
+	}
+	delivery.Status = status
+	delivery.Time = time.Now()
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+	s.Log.Add(delivery)
+}
+
+func retryable(statusCode int) bool {
+	return statusCode == 0 || statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// Signature of the body sent in the SignatureHeader.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newDeliveryId() (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id[:]), nil
+}
+
+type DeliveryStatus string
+
+const (
+	DeliverySucceeded DeliveryStatus = "delivered"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// Result of sending an event to a webhook, after every attempt.
+type Delivery struct {
+	Id         string         `json:"id"`
+	Url        string         `json:"url"`
+	Type       Type           `json:"type"`
+	InputIndex int            `json:"inputIndex"`
+	Status     DeliveryStatus `json:"status"`
+	Attempts   int            `json:"attempts"`
+	StatusCode int            `json:"statusCode,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	Time       time.Time      `json:"time"`
+}
+
+// Path of the delivery log in the nonodo HTTP server.
+const DeliveriesPath = "/nonodo/webhooks/deliveries"
+
+// Number of deliveries kept by the log.
+const DeliveryLogSize = 100
+
+// Latest deliveries to the webhooks.
+type DeliveryLog struct {
+	mutex      sync.Mutex
+	size       int
+	deliveries []Delivery
+}
+
+func NewDeliveryLog(size int) *DeliveryLog {
+	return &DeliveryLog{size: size}
+}
+
+// Add the delivery, forgetting the oldest one if the log is full.
+func (l *DeliveryLog) Add(delivery Delivery) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.deliveries = append(l.deliveries, delivery)
+	if len(l.deliveries) > l.size {
+		l.deliveries = l.deliveries[len(l.deliveries)-l.size:]
+	}
+}
+
+// Deliveries in the log, the newest first.
+func (l *DeliveryLog) List() []Delivery {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	deliveries := slices.Clone(l.deliveries)
+	slices.Reverse(deliveries)
+	return deliveries
+}
+
+// Serve the deliveries as JSON.
+func (l *DeliveryLog) Register(e *echo.Echo) {
+	e.GET(DeliveriesPath, func(c echo.Context) error {
+		deliveries := l.List()
+		if status := c.QueryParam("status"); status != "" {
+			deliveries = slices.DeleteFunc(deliveries, func(d Delivery) bool {
+				return string(d.Status) != status
+			})
+		}
+		return c.JSON(http.StatusOK, deliveries)
+	})
+}
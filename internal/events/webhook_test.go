@@ -0,0 +1,131 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+)
+
+type WebhookSuite struct {
+	suite.Suite
+	mutex    sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	statuses []int
+	server   *httptest.Server
+	log      *DeliveryLog
+	sink     *WebhookSink
+}
+
+func TestWebhookSuite(t *testing.T) {
+	suite.Run(t, new(WebhookSuite))
+}
+
+func (s *WebhookSuite) SetupTest() {
+	s.requests, s.bodies, s.statuses = nil, nil, nil
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		s.requests = append(s.requests, r)
+		s.bodies = append(s.bodies, body)
+		status := http.StatusNoContent
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	s.log = NewDeliveryLog(DeliveryLogSize)
+	s.sink = NewWebhookSink(s.server.URL)
+	s.sink.Backoff = time.Millisecond
+	s.sink.Log = s.log
+}
+
+func (s *WebhookSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *WebhookSuite) TestItPostsTheSignedEvent() {
+	s.sink.Secret = "secret"
+	s.Require().NoError(s.sink.Send(context.Background(), Event{Type: InputFinished, InputIndex: 7, Status: "ACCEPTED"}))
+	s.Require().Len(s.requests, 1)
+	request := s.requests[0]
+	s.Equal(http.MethodPost, request.Method)
+	s.Equal("application/json", request.Header.Get("Content-Type"))
+	s.Equal("input_finished", request.Header.Get(EventHeader))
+	s.Len(request.Header.Get(DeliveryHeader), 32) // nolint
+	s.Equal(Sign("secret", s.bodies[0]), request.Header.Get(SignatureHeader))
+	var event Event
+	s.Require().NoError(json.Unmarshal(s.bodies[0], &event))
+	s.Equal(7, event.InputIndex) // nolint
+}
+
+func (s *WebhookSuite) TestItFiltersTheEvents() {
+	s.sink.Types = []Type{VoucherExecuted}
+	s.Require().NoError(s.sink.Send(context.Background(), Event{Type: InputAdded}))
+	s.Require().NoError(s.sink.Send(context.Background(), Event{Type: VoucherExecuted}))
+	s.Require().Len(s.requests, 1)
+	s.Equal("voucher_executed", s.requests[0].Header.Get(EventHeader))
+	s.Len(s.log.List(), 1)
+}
+
+func (s *WebhookSuite) TestItRetriesTheServerErrors() {
+	s.statuses = []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}
+	s.Require().NoError(s.sink.Send(context.Background(), Event{Type: InputAdded, InputIndex: 3}))
+	s.Require().Len(s.requests, 3) // nolint
+	s.Equal(s.requests[0].Header.Get(DeliveryHeader), s.requests[2].Header.Get(DeliveryHeader))
+	deliveries := s.log.List()
+	s.Require().Len(deliveries, 1)
+	s.Equal(DeliverySucceeded, deliveries[0].Status)
+	s.Equal(3, deliveries[0].Attempts)   // nolint
+	s.Equal(3, deliveries[0].InputIndex) // nolint
+}
+
+func (s *WebhookSuite) TestItGivesUpAfterTheLastAttempt() {
+	s.sink.MaxAttempts = 2
+	s.statuses = []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusBadGateway}
+	err := s.sink.Send(context.Background(), Event{Type: InputAdded})
+	s.EqualError(err, "unexpected status 502 Bad Gateway")
+	s.Len(s.requests, 2) // nolint
+	deliveries := s.log.List()
+	s.Require().Len(deliveries, 1)
+	s.Equal(DeliveryFailed, deliveries[0].Status)
+	s.Equal(http.StatusBadGateway, deliveries[0].StatusCode)
+	s.Equal("unexpected status 502 Bad Gateway", deliveries[0].Error)
+}
+
+func (s *WebhookSuite) TestItDoesNotRetryTheClientErrors() {
+	s.statuses = []int{http.StatusBadRequest}
+	s.Error(s.sink.Send(context.Background(), Event{Type: InputAdded}))
+	s.Len(s.requests, 1)
+}
+
+func (s *WebhookSuite) TestTheLogKeepsTheLatestDeliveries() {
+	log := NewDeliveryLog(2) // nolint
+	for i := 0; i < 3; i++ {
+		log.Add(Delivery{InputIndex: i, Status: DeliverySucceeded})
+	}
+	log.Add(Delivery{InputIndex: 3, Status: DeliveryFailed}) // nolint
+	deliveries := log.List()
+	s.Require().Len(deliveries, 2) // nolint
+	s.Equal(3, deliveries[0].InputIndex)
+	s.Equal(2, deliveries[1].InputIndex)
+
+	e := echo.New()
+	log.Register(e)
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, DeliveriesPath+"?status=failed", nil))
+	s.Equal(http.StatusOK, recorder.Code)
+	var served []Delivery
+	s.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &served))
+	s.Require().Len(served, 1)
+	s.Equal(3, served[0].InputIndex) // nolint
+}
@@ -1,14 +1,113 @@
 package health
 
 import (
+	"context"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/labstack/echo/v4"
 )
 
-// Register the health API to echo
-func Register(e *echo.Echo) {
+// Time to wait for each check of the readiness.
+const CheckTimeout = 3 * time.Second
+
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusFail Status = "fail"
+)
+
+// State of a component of nonodo.
+type Component struct {
+	Status  Status         `json:"status"`
+	Error   string         `json:"error,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+func OK(details map[string]any) Component {
+	return Component{Status: StatusOK, Details: details}
+}
+
+func Fail(message string, details map[string]any) Component {
+	return Component{Status: StatusFail, Error: message, Details: details}
+}
+
+// Check the state of a component.
+type Check func(ctx context.Context) Component
+
+// State of every component; ready only if all of them are ok.
+type Report struct {
+	Status     Status               `json:"status"`
+	Components map[string]Component `json:"components"`
+}
+
+// Checks of the readiness, by component.
+// Checks may be added after the handlers are registered, as the components are created.
+type Checker struct {
+	mutex  sync.Mutex
+	checks map[string]Check
+}
+
+func NewChecker() *Checker {
+	return &Checker{checks: map[string]Check{}}
+}
+
+// Add the check of the component, replacing the previous one.
+func (c *Checker) Add(name string, check Check) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.checks[name] = check
+}
+
+// Run every check concurrently.
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mutex.Lock()
+	checks := make(map[string]Check, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.mutex.Unlock()
+
+	report := Report{Status: StatusOK, Components: make(map[string]Component, len(checks))}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, CheckTimeout)
+			defer cancel()
+			component := check(ctx)
+			mutex.Lock()
+			defer mutex.Unlock()
+			report.Components[name] = component
+			if component.Status != StatusOK {
+				report.Status = StatusFail
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+// Register the health API to echo.
+// /health/live answers while nonodo serves HTTP; /health/ready answers 503 until every check
+// of the checker is ok.
+func Register(e *echo.Echo, checker *Checker) {
 	e.GET("/health", func(c echo.Context) error {
 		return c.String(http.StatusOK, "Ok")
 	})
+	e.GET("/health/live", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, Report{Status: StatusOK, Components: map[string]Component{}})
+	})
+	e.GET("/health/ready", func(c echo.Context) error {
+		report := checker.Run(c.Request().Context())
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		return c.JSON(status, report)
+	})
 }
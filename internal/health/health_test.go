@@ -0,0 +1,72 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+)
+
+type HealthSuite struct {
+	suite.Suite
+	checker *Checker
+	e       *echo.Echo
+}
+
+func TestHealthSuite(t *testing.T) {
+	suite.Run(t, new(HealthSuite))
+}
+
+func (s *HealthSuite) SetupTest() {
+	s.checker = NewChecker()
+	s.e = echo.New()
+	Register(s.e, s.checker)
+}
+
+func (s *HealthSuite) get(path string) (int, Report) {
+	recorder := httptest.NewRecorder()
+	s.e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	var report Report
+	if path != "/health" {
+		s.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &report))
+	}
+	return recorder.Code, report
+}
+
+func (s *HealthSuite) TestItIsReadyWhenEveryCheckIsOk() {
+	s.checker.Add("database", func(ctx context.Context) Component {
+		return OK(map[string]any{"driver": "sqlite3"})
+	})
+	code, report := s.get("/health/ready")
+	s.Equal(http.StatusOK, code)
+	s.Equal(StatusOK, report.Status)
+	s.Equal(StatusOK, report.Components["database"].Status)
+	s.Equal("sqlite3", report.Components["database"].Details["driver"])
+}
+
+func (s *HealthSuite) TestItIsNotReadyWhenACheckFails() {
+	s.checker.Add("database", func(ctx context.Context) Component {
+		return OK(nil)
+	})
+	s.checker.Add("backend", func(ctx context.Context) Component {
+		_, ok := ctx.Deadline()
+		s.True(ok)
+		return Fail("the application hasn't called /finish yet", nil)
+	})
+	code, report := s.get("/health/ready")
+	s.Equal(http.StatusServiceUnavailable, code)
+	s.Equal(StatusFail, report.Status)
+	s.Equal(StatusOK, report.Components["database"].Status)
+	s.Equal("the application hasn't called /finish yet", report.Components["backend"].Error)
+
+	// the process is alive anyway
+	code, report = s.get("/health/live")
+	s.Equal(http.StatusOK, code)
+	s.Equal(StatusOK, report.Status)
+	code, _ = s.get("/health")
+	s.Equal(http.StatusOK, code)
+}
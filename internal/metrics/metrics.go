@@ -0,0 +1,158 @@
+// Package metrics exposes the counters and histograms of nonodo in the Prometheus format, so
+// load tests can measure the throughput and the latency of the node.
+package metrics
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Path of the metrics in the nonodo HTTP server.
+const Path = "/metrics"
+
+const namespace = "nonodo"
+
+// Sources of the inputs.
+const (
+	SourceInputBox = "inputbox"
+	SourceEspresso = "espresso"
+	SourceAvail    = "avail"
+	// Transactions submitted to the L2 API of nonodo.
+	SourceL2 = "l2"
+)
+
+// Kinds of the outputs.
+const (
+	OutputVoucher = "voucher"
+	OutputNotice  = "notice"
+	OutputReport  = "report"
+)
+
+// Results of the claims.
+const (
+	ClaimSubmitted = "submitted"
+	ClaimFailed    = "failed"
+)
+
+// Registry of the nonodo metrics, with the Go runtime and process metrics.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	Inputs = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "inputs_total",
+		Help:      "Advance inputs added, by source.",
+	}, []string{"source"})
+
+	AdvanceDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "advance_duration_seconds",
+		Help:      "Time from the delivery of an advance input to the application until it finishes, by status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"status"})
+
+	InspectDuration = factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "inspect_duration_seconds",
+		Help:      "Time from the delivery of an inspect to the application until it finishes.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	Outputs = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outputs_total",
+		Help:      "Outputs stored by the finished advance inputs, by kind.",
+	}, []string{"kind"})
+
+	Claims = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "claims_total",
+		Help:      "Claims sent to the consensus, by result.",
+	}, []string{"result"})
+
+	FetchDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "da_fetch_duration_seconds",
+		Help:      "Time to fetch the data of a GIO request, by domain.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"domain"})
+
+	Reconnects = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_reconnects_total",
+		Help:      "Reconnections of the listeners to their RPC, by listener.",
+	}, []string{"listener"})
+)
+
+// Read the depth of the queues of the model.
+type Queues interface {
+	// Advance inputs not processed yet.
+	UnprocessedInputs() (int, error)
+	// Inspects not processed yet.
+	PendingInspects() int
+}
+
+var (
+	queuesMutex sync.Mutex
+	queues      Queues
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	factory.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "unprocessed_inputs",
+		Help:      "Advance inputs waiting to be processed.",
+	}, func() float64 {
+		q := getQueues()
+		if q == nil {
+			return 0
+		}
+		count, err := q.UnprocessedInputs()
+		if err != nil {
+			slog.Warn("metrics: failed to count the unprocessed inputs", "error", err)
+			return 0
+		}
+		return float64(count)
+	})
+	factory.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pending_inspects",
+		Help:      "Inspects waiting to be processed.",
+	}, func() float64 {
+		q := getQueues()
+		if q == nil {
+			return 0
+		}
+		return float64(q.PendingInspects())
+	})
+}
+
+// Read the queue gauges from q; nil resets them to zero.
+func SetQueues(q Queues) {
+	queuesMutex.Lock()
+	defer queuesMutex.Unlock()
+	queues = q
+}
+
+func getQueues() Queues {
+	queuesMutex.Lock()
+	defer queuesMutex.Unlock()
+	return queues
+}
+
+// Serve the metrics in the Prometheus text format.
+func Register(e *echo.Echo) {
+	e.GET(Path, echo.WrapHandler(promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})))
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+)
+
+type MetricsSuite struct {
+	suite.Suite
+}
+
+func TestMetricsSuite(t *testing.T) {
+	suite.Run(t, new(MetricsSuite))
+}
+
+type fixedQueues struct {
+	inputs   int
+	err      error
+	inspects int
+}
+
+func (q fixedQueues) UnprocessedInputs() (int, error) {
+	return q.inputs, q.err
+}
+
+func (q fixedQueues) PendingInspects() int {
+	return q.inspects
+}
+
+func (s *MetricsSuite) TearDownTest() {
+	SetQueues(nil)
+}
+
+func (s *MetricsSuite) scrape() string {
+	e := echo.New()
+	Register(e)
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, Path, nil))
+	s.Equal(http.StatusOK, recorder.Code)
+	return recorder.Body.String()
+}
+
+func (s *MetricsSuite) TestItServesTheMetrics() {
+	Inputs.WithLabelValues(SourceEspresso).Inc()
+	Claims.WithLabelValues(ClaimFailed).Inc()
+	FetchDuration.WithLabelValues("avail").Observe(0.01) // nolint
+	body := s.scrape()
+	s.Contains(body, `nonodo_inputs_total{source="espresso"} 1`)
+	s.Contains(body, `nonodo_claims_total{result="failed"} 1`)
+	s.Contains(body, `nonodo_da_fetch_duration_seconds_count{domain="avail"} 1`)
+	s.Contains(body, "go_goroutines")
+}
+
+func (s *MetricsSuite) TestItReadsTheQueues() {
+	s.Contains(s.scrape(), "nonodo_unprocessed_inputs 0")
+
+	SetQueues(fixedQueues{inputs: 3, inspects: 2}) // nolint
+	body := s.scrape()
+	s.Contains(body, "nonodo_unprocessed_inputs 3")
+	s.Contains(body, "nonodo_pending_inspects 2")
+
+	SetQueues(fixedQueues{err: errors.New("closed"), inspects: 1})
+	s.Contains(s.scrape(), "nonodo_unprocessed_inputs 0")
+}
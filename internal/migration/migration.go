@@ -0,0 +1,218 @@
+// Package migration applies versioned changes to the schema of a SQLite or Postgres database
+// and records them in the schema_version table.
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Table with the migrations applied to the database.
+const VersionTable = "schema_version"
+
+// Returned when the database was migrated by a newer nonodo.
+var ErrNewerSchema = errors.New("the database schema is newer than this nonodo supports")
+
+// Versioned change of the schema.
+type Migration struct {
+	Version int
+	Name    string
+	// Statements run in the transaction that records the version.
+	SQL string
+	// Change that can't be written in SQL; run before the version is recorded.
+	Apply func(ctx context.Context, db *sqlx.DB) error
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// Dialect of the database, used to pick the migration files: sqlite or postgres.
+func Dialect(db *sqlx.DB) string {
+	if strings.HasPrefix(db.DriverName(), "sqlite") {
+		return "sqlite"
+	}
+	return db.DriverName()
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+?)(?:\.(sqlite|postgres))?\.sql$`)
+
+// Load the migrations of the files named <version>_<name>.sql.
+// A file named <version>_<name>.<dialect>.sql replaces it for that dialect; files of the other
+// dialect are ignored.
+func LoadFS(fsys fs.FS, dialect string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	// the files of every dialect, and then those of this dialect only
+	generic, specific := map[int]Migration{}, map[int]Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q; expected <version>_<name>.sql", entry.Name())
+		}
+		byVersion := generic
+		if match[3] != "" {
+			if match[3] != dialect {
+				continue
+			}
+			byVersion = specific
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		if other, ok := byVersion[version]; ok {
+			return nil, fmt.Errorf("%s: version %d is already used by %s", entry.Name(), version, other)
+		}
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		byVersion[version] = Migration{Version: version, Name: match[2], SQL: string(data)}
+	}
+	for version, m := range specific {
+		generic[version] = m
+	}
+	migrations := make([]Migration, 0, len(generic))
+	for _, m := range generic {
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Apply the migrations a database lacks.
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+}
+
+// Create a migrator for the migrations, which must have increasing positive versions.
+func NewMigrator(db *sqlx.DB, migrations []Migration) (*Migrator, error) {
+	last := 0
+	for _, m := range migrations {
+		if m.Version <= last {
+			return nil, fmt.Errorf("migration %s must come after version %d", m, last)
+		}
+		if (m.SQL == "") == (m.Apply == nil) {
+			return nil, fmt.Errorf("migration %s must have either SQL or Apply", m)
+		}
+		last = m.Version
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Latest version known by the migrator.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version of the database schema; 0 if it was never migrated.
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	var query string
+	if Dialect(m.db) == "sqlite" {
+		query = `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = $1`
+	} else {
+		query = `SELECT COUNT(*) FROM information_schema.tables
+			WHERE table_schema = current_schema() AND table_name = $1`
+	}
+	var tables int
+	if err := m.db.GetContext(ctx, &tables, query, VersionTable); err != nil {
+		return 0, err
+	}
+	if tables == 0 {
+		return 0, nil
+	}
+	var version int
+	err := m.db.GetContext(ctx, &version, `SELECT COALESCE(MAX(version), 0) FROM `+VersionTable)
+	return version, err
+}
+
+// Migrations the database lacks, without changing it.
+// The error wraps ErrNewerSchema if the database has a version the migrator doesn't know.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	version, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if version > m.Latest() {
+		return nil, fmt.Errorf("%w: version %d, expected up to %d; upgrade nonodo", ErrNewerSchema, version, m.Latest())
+	}
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if migration.Version > version {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Apply the pending migrations in order and return them.
+func (m *Migrator) Migrate(ctx context.Context) ([]Migration, error) {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+	_, err = m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+VersionTable+` (
+		version    integer NOT NULL PRIMARY KEY,
+		name       text NOT NULL,
+		applied_at integer NOT NULL)`)
+	if err != nil {
+		return nil, err
+	}
+	for i, migration := range pending {
+		if err := m.apply(ctx, migration); err != nil {
+			return pending[:i], fmt.Errorf("migration %s: %w", migration, err)
+		}
+		slog.Info("migration: applied", "version", migration.Version, "name", migration.Name)
+	}
+	return pending, nil
+}
+
+func (m *Migrator) apply(ctx context.Context, migration Migration) error {
+	if migration.Apply != nil {
+		if err := migration.Apply(ctx, m.db); err != nil {
+			return err
+		}
+	}
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	if migration.SQL != "" {
+		if _, err := tx.ExecContext(ctx, migration.SQL); err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO `+VersionTable+` (version, name, applied_at) VALUES ($1, $2, $3)`,
+		migration.Version, migration.Name, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
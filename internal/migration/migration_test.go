@@ -0,0 +1,144 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/calindra/nonodo/internal/commons"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/ncruces/go-sqlite3/driver"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	"github.com/stretchr/testify/suite"
+)
+
+type MigrationSuite struct {
+	suite.Suite
+	ctx       context.Context
+	dbFactory *commons.DbFactory
+	db        *sqlx.DB
+}
+
+func TestMigrationSuite(t *testing.T) {
+	suite.Run(t, new(MigrationSuite))
+}
+
+func (s *MigrationSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.dbFactory = commons.NewDbFactory()
+	s.db = s.dbFactory.CreateDb("migration.sqlite3")
+}
+
+func (s *MigrationSuite) TearDownTest() {
+	s.db.Close()
+	s.dbFactory.Cleanup()
+}
+
+var files = fstest.MapFS{
+	"0001_users.sql":          {Data: []byte("CREATE TABLE users (id integer);")},
+	"0002_names.sql":          {Data: []byte("ALTER TABLE users ADD COLUMN name text;")},
+	"0002_names.postgres.sql": {Data: []byte("ALTER TABLE users ADD COLUMN IF NOT EXISTS name text;")},
+	"0003_emails.sqlite.sql":  {Data: []byte("ALTER TABLE users ADD COLUMN email text;")},
+	"README.md":               {Data: []byte("not a migration")},
+}
+
+func (s *MigrationSuite) TestItLoadsTheFilesOfTheDialect() {
+	migrations, err := LoadFS(files, "postgres")
+	s.Require().NoError(err)
+	s.Require().Len(migrations, 2) // nolint
+	s.Equal("0001_users", migrations[0].String())
+	s.Equal("ALTER TABLE users ADD COLUMN IF NOT EXISTS name text;", migrations[1].SQL)
+
+	migrations, err = LoadFS(files, "sqlite")
+	s.Require().NoError(err)
+	s.Require().Len(migrations, 3) // nolint
+	s.Equal("ALTER TABLE users ADD COLUMN name text;", migrations[1].SQL)
+	s.Equal("emails", migrations[2].Name)
+
+	_, err = LoadFS(fstest.MapFS{"users.sql": {}}, "sqlite")
+	s.ErrorContains(err, `invalid migration file name "users.sql"`)
+}
+
+func (s *MigrationSuite) TestItAppliesThePendingMigrations() {
+	migrations, err := LoadFS(files, Dialect(s.db))
+	s.Require().NoError(err)
+	migrator, err := NewMigrator(s.db, migrations[:2])
+	s.Require().NoError(err)
+	version, err := migrator.Version(s.ctx)
+	s.Require().NoError(err)
+	s.Zero(version)
+
+	applied, err := migrator.Migrate(s.ctx)
+	s.Require().NoError(err)
+	s.Len(applied, 2) // nolint
+
+	migrator, err = NewMigrator(s.db, migrations)
+	s.Require().NoError(err)
+	pending, err := migrator.Pending(s.ctx)
+	s.Require().NoError(err)
+	s.Equal([]Migration{migrations[2]}, pending)
+	_, err = migrator.Migrate(s.ctx)
+	s.Require().NoError(err)
+	s.db.MustExec("INSERT INTO users (id, name, email) VALUES (1, 'alice', 'alice@example.com')")
+	version, err = migrator.Version(s.ctx)
+	s.Require().NoError(err)
+	s.Equal(3, version) // nolint
+}
+
+func (s *MigrationSuite) TestItRunsTheGoMigrations() {
+	var called int
+	migrator, err := NewMigrator(s.db, []Migration{{Version: 1, Name: "go", Apply: func(ctx context.Context, db *sqlx.DB) error {
+		called++
+		return nil
+	}}})
+	s.Require().NoError(err)
+	_, err = migrator.Migrate(s.ctx)
+	s.Require().NoError(err)
+	_, err = migrator.Migrate(s.ctx)
+	s.Require().NoError(err)
+	s.Equal(1, called)
+}
+
+func (s *MigrationSuite) TestAFailedMigrationIsNotRecorded() {
+	migrator, err := NewMigrator(s.db, []Migration{
+		{Version: 1, Name: "users", SQL: "CREATE TABLE users (id integer);"},
+		{Version: 2, Name: "broken", SQL: "CREATE TABLE logs (id integer); ALTER TABLE nothing ADD COLUMN x text;"},
+	})
+	s.Require().NoError(err)
+	applied, err := migrator.Migrate(s.ctx)
+	s.ErrorContains(err, "migration 0002_broken")
+	s.Len(applied, 1)
+	version, err := migrator.Version(s.ctx)
+	s.Require().NoError(err)
+	s.Equal(1, version)
+	var tables int
+	s.Require().NoError(s.db.Get(&tables, "SELECT COUNT(*) FROM sqlite_master WHERE name = 'logs'"))
+	s.Zero(tables)
+}
+
+func (s *MigrationSuite) TestItRefusesANewerSchema() {
+	newer, err := NewMigrator(s.db, []Migration{
+		{Version: 1, Name: "users", SQL: "CREATE TABLE users (id integer);"},
+		{Version: 2, Name: "names", SQL: "ALTER TABLE users ADD COLUMN name text;"},
+	})
+	s.Require().NoError(err)
+	_, err = newer.Migrate(s.ctx)
+	s.Require().NoError(err)
+
+	older, err := NewMigrator(s.db, []Migration{{Version: 1, Name: "users", SQL: "CREATE TABLE users (id integer);"}})
+	s.Require().NoError(err)
+	_, err = older.Migrate(s.ctx)
+	s.True(errors.Is(err, ErrNewerSchema))
+	s.ErrorContains(err, "version 2, expected up to 1")
+}
+
+func (s *MigrationSuite) TestItChecksTheVersions() {
+	_, err := NewMigrator(s.db, []Migration{
+		{Version: 2, Name: "b", SQL: "SELECT 1"},
+		{Version: 1, Name: "a", SQL: "SELECT 1"},
+	})
+	s.EqualError(err, "migration 0001_a must come after version 2")
+	_, err = NewMigrator(s.db, []Migration{{Version: 1, Name: "empty"}})
+	s.EqualError(err, "migration 0001_empty must have either SQL or Apply")
+}
@@ -9,13 +9,17 @@ import (
 	"fmt"
 	"log/slog"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/calindra/nonodo/internal/events"
+	"github.com/calindra/nonodo/internal/metrics"
 	cModel "github.com/cartesi/rollups-graphql/pkg/convenience/model"
 	cRepos "github.com/cartesi/rollups-graphql/pkg/convenience/repository"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Nonodo model shared among the internal workers.
@@ -29,6 +33,8 @@ type NonodoModel struct {
 	inputRepository   *cRepos.InputRepository
 	voucherRepository *cRepos.VoucherRepository
 	noticeRepository  *cRepos.NoticeRepository
+	publisher         events.Publisher
+	paused            bool
 }
 
 func (m *NonodoModel) GetInputRepository() *cRepos.InputRepository {
@@ -53,6 +59,13 @@ func NewNonodoModel(
 	}
 }
 
+// Publish the changes of the inputs and the new outputs to the publisher.
+func (m *NonodoModel) SetPublisher(publisher events.Publisher) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.publisher = publisher
+}
+
 //
 // Methods for Inputter
 //
@@ -97,9 +110,37 @@ func (m *NonodoModel) AddAdvanceInput(
 	}
 	slog.Info("nonodo: added advance input", "index", input.Index, "sender", input.MsgSender,
 		"payload", input.Payload)
+	m.publishAdded(input)
 	return nil
 }
 
+// Add advance inputs sequenced off chain, such as the transactions of an L2 batch.
+// The inputs are stored in order after the inputs already in the model, and returned with
+// their indexes.
+func (m *NonodoModel) AddSequencedInputs(inputs []cModel.AdvanceInput) ([]cModel.AdvanceInput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	ctx := context.Background()
+	count, err := m.inputRepository.Count(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	added := make([]cModel.AdvanceInput, 0, len(inputs))
+	for i, input := range inputs {
+		input.Index = int(count) + i
+		input.Status = cModel.CompletionStatusUnprocessed
+		_, err := m.inputRepository.RawCreate(ctx, input)
+		if err != nil {
+			return added, err
+		}
+		slog.Info("nonodo: added sequenced input", "index", input.Index, "id", input.ID,
+			"sender", input.MsgSender, "payload", input.Payload)
+		m.publishAdded(input)
+		added = append(added, input)
+	}
+	return added, nil
+}
+
 //
 // Methods for Inspector
 //
@@ -142,7 +183,7 @@ func (m *NonodoModel) GetInspectInput(index int) (InspectInput, error) {
 // Finish the current input and get the next one.
 // If there is no input to be processed return nil.
 //
-// Note: use in v2 the sequencer instead.
+// The sequencers delegate to this method.
 func (m *NonodoModel) FinishAndGetNext(accepted bool) (cModel.Input, error) {
 	ctx := context.Background()
 	m.mutex.Lock()
@@ -160,6 +201,10 @@ func (m *NonodoModel) FinishAndGetNext(accepted bool) (cModel.Input, error) {
 	if err != nil {
 		return nil, err
 	}
+	m.observeFinished()
+	if advance, ok := m.state.(*rollupsStateAdvance); ok {
+		m.publishFinished(advance)
+	}
 
 	// try to get first unprocessed inspect
 	for _, input := range m.inspects {
@@ -169,6 +214,12 @@ func (m *NonodoModel) FinishAndGetNext(accepted bool) (cModel.Input, error) {
 		}
 	}
 
+	// the advances wait while the delivery is paused
+	if m.paused {
+		m.state = newRollupsStateIdle()
+		return nil, nil
+	}
+
 	// try to get first unprocessed advance
 	input, err := m.inputRepository.FindByStatus(ctx, cModel.CompletionStatusUnprocessed)
 
@@ -241,16 +292,231 @@ func (m *NonodoModel) RegisterException(payload []byte) error {
 	if err != nil {
 		return err
 	}
+	m.observeFinished()
+	if advance, ok := m.state.(*rollupsStateAdvance); ok {
+		m.publishFinished(advance)
+	}
 
 	// set state to idle
 	m.state = newRollupsStateIdle()
 	return nil
 }
 
+// Discard the outputs of the input being processed, so the next call to FinishAndGetNext
+// delivers it again instead of finishing it.
+// This is used when the application is restarted in the middle of an input.
+func (m *NonodoModel) ResetCurrentInput() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	switch state := m.state.(type) {
+	case *rollupsStateAdvance:
+		slog.Info("nonodo: advance will be delivered again", "index", state.input.Index)
+		state.span.AddEvent("reset")
+		state.span.End()
+	case *rollupsStateInspect:
+		slog.Info("nonodo: inspect will be delivered again", "index", state.input.Index)
+	}
+	m.state = newRollupsStateIdle()
+}
+
+// Stop delivering the advance inputs; the inspects are still delivered.
+// The advance being processed is not affected.
+func (m *NonodoModel) Pause() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.paused = true
+	slog.Info("nonodo: paused the delivery of inputs")
+}
+
+// Deliver the advance inputs again.
+func (m *NonodoModel) Resume() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.paused = false
+	slog.Info("nonodo: resumed the delivery of inputs")
+}
+
+func (m *NonodoModel) Paused() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.paused
+}
+
+// Finish the advance being processed as rejected, as if the application rejected it.
+// Return its index, or false if there is no advance being processed.
+func (m *NonodoModel) RejectCurrentInput() (int, bool, error) {
+	return m.rejectCurrentInput(false)
+}
+
+// Finish the advance being processed as rejected, discarding its reports too, so the
+// application output has no trace of it.
+// Return its index, or false if there is no advance being processed.
+func (m *NonodoModel) SkipCurrentInput() (int, bool, error) {
+	return m.rejectCurrentInput(true)
+}
+
+func (m *NonodoModel) rejectCurrentInput(discardReports bool) (int, bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	state, ok := m.state.(*rollupsStateAdvance)
+	if !ok {
+		return 0, false, nil
+	}
+	if discardReports {
+		state.reports = nil
+	}
+	if err := state.finish(cModel.CompletionStatusRejected); err != nil {
+		return 0, false, err
+	}
+	m.observeFinished()
+	m.publishFinished(state)
+
+	// the next call to finish gets the next input
+	m.state = newRollupsStateIdle()
+	return state.input.Index, true, nil
+}
+
+// Return the index of the advance input being processed, if any.
+func (m *NonodoModel) CurrentAdvanceIndex() (int, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if state, ok := m.state.(*rollupsStateAdvance); ok {
+		return state.input.Index, true
+	}
+	return 0, false
+}
+
+// Return the context with the span of the advance being processed, if any, so the calls of
+// the application join its trace.
+func (m *NonodoModel) TraceContext(ctx context.Context) context.Context {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if state, ok := m.state.(*rollupsStateAdvance); ok {
+		return trace.ContextWithSpan(ctx, state.span)
+	}
+	return ctx
+}
+
+// Return the number of advance inputs not processed yet.
+func (m *NonodoModel) UnprocessedInputs() (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	processed, err := m.getProcessedInputCount()
+	if err != nil {
+		return 0, err
+	}
+	total, err := m.inputRepository.Count(context.Background(), nil)
+	if err != nil {
+		return 0, err
+	}
+	return int(total) - processed, nil
+}
+
+// Return the number of inspects not processed yet.
+func (m *NonodoModel) PendingInspects() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	pending := 0
+	for _, input := range m.inspects {
+		if input.Status == cModel.CompletionStatusUnprocessed {
+			pending++
+		}
+	}
+	return pending
+}
+
 //
 // Auxiliary Methods
 //
 
+// Record the processing time of the finished input and the outputs it stored.
+func (m *NonodoModel) observeFinished() {
+	switch state := m.state.(type) {
+	case *rollupsStateAdvance:
+		status, _ := events.StatusName(state.input.Status)
+		metrics.AdvanceDuration.WithLabelValues(status).Observe(time.Since(state.started).Seconds())
+		if state.input.Status == cModel.CompletionStatusAccepted {
+			metrics.Outputs.WithLabelValues(metrics.OutputVoucher).Add(float64(len(state.vouchers)))
+			metrics.Outputs.WithLabelValues(metrics.OutputNotice).Add(float64(len(state.notices)))
+		}
+		metrics.Outputs.WithLabelValues(metrics.OutputReport).Add(float64(len(state.reports)))
+	case *rollupsStateInspect:
+		metrics.InspectDuration.Observe(time.Since(state.started).Seconds())
+	}
+}
+
+func (m *NonodoModel) publishAdded(input cModel.AdvanceInput) {
+	if m.publisher == nil {
+		return
+	}
+	status, _ := events.StatusName(input.Status)
+	m.publisher.Publish(events.Event{
+		Type:        events.InputAdded,
+		AppContract: input.AppContract.Hex(),
+		InputIndex:  input.Index,
+		Status:      status,
+		Payload:     hexPayload(input.Payload),
+	})
+}
+
+// Publish the final status of the input and its outputs, which are stored only if it was accepted.
+func (m *NonodoModel) publishFinished(advance *rollupsStateAdvance) {
+	if m.publisher == nil {
+		return
+	}
+	input := advance.input
+	appContract := input.AppContract.Hex()
+	status, _ := events.StatusName(input.Status)
+	m.publisher.Publish(events.Event{
+		Type:        events.InputFinished,
+		AppContract: appContract,
+		InputIndex:  input.Index,
+		Status:      status,
+	})
+	if input.Status == cModel.CompletionStatusAccepted {
+		for _, voucher := range advance.vouchers {
+			outputIndex := int(voucher.OutputIndex)
+			m.publisher.Publish(events.Event{
+				Type:        events.VoucherAdded,
+				AppContract: appContract,
+				InputIndex:  input.Index,
+				OutputIndex: &outputIndex,
+				Destination: voucher.Destination.Hex(),
+				Payload:     hexPayload(voucher.Payload),
+			})
+		}
+		for _, notice := range advance.notices {
+			outputIndex := int(notice.OutputIndex)
+			m.publisher.Publish(events.Event{
+				Type:        events.NoticeAdded,
+				AppContract: appContract,
+				InputIndex:  input.Index,
+				OutputIndex: &outputIndex,
+				Payload:     hexPayload(notice.Payload),
+			})
+		}
+	}
+	for _, report := range advance.reports {
+		outputIndex := report.Index
+		m.publisher.Publish(events.Event{
+			Type:        events.ReportAdded,
+			AppContract: appContract,
+			InputIndex:  input.Index,
+			OutputIndex: &outputIndex,
+			Payload:     hexPayload(report.Payload),
+		})
+	}
+}
+
 func (m *NonodoModel) getProcessedInputCount() (int, error) {
 	ctx := context.Background()
 	filter := []*cModel.ConvenienceFilter{}
@@ -268,3 +534,11 @@ func (m *NonodoModel) getProcessedInputCount() (int, error) {
 
 	return int(total), nil
 }
+
+// Payload with the 0x prefix, which the outputs being processed don't have yet.
+func hexPayload(payload string) string {
+	if strings.HasPrefix(payload, "0x") {
+		return payload
+	}
+	return "0x" + payload
+}
@@ -14,6 +14,9 @@ import (
 	"time"
 
 	"github.com/calindra/nonodo/internal/devnet"
+	"github.com/calindra/nonodo/internal/events"
+	"github.com/calindra/nonodo/internal/metrics"
+	"github.com/calindra/nonodo/internal/tracing"
 	cModel "github.com/cartesi/rollups-graphql/pkg/convenience/model"
 	cRepos "github.com/cartesi/rollups-graphql/pkg/convenience/repository"
 
@@ -24,6 +27,14 @@ import (
 	"github.com/jmoiron/sqlx"
 	_ "github.com/ncruces/go-sqlite3/driver"
 	_ "github.com/ncruces/go-sqlite3/embed"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 
 	"github.com/stretchr/testify/suite"
 )
@@ -254,6 +265,75 @@ func (s *ModelSuite) TestItFinishesAdvanceWithAccept() {
 	s.Equal(1, int(reportPage.Total))
 }
 
+func (s *ModelSuite) TestItDeliversAdvanceAgainAfterReset() {
+	// add input and start processing it
+	err := s.m.AddAdvanceInput(s.senders[0], s.payloads[0], s.blockNumbers[0], s.timestamps[0], 0, "", common.Address{}, "")
+	s.NoError(err)
+	_, err = s.m.FinishAndGetNext(true) // get
+	s.NoError(err)
+	_, err = s.m.AddVoucher(common.HexToAddress(devnet.ApplicationAddress), s.senders[0], "0", common.Hex2Bytes(s.payloads[0]))
+	s.NoError(err)
+
+	// the application restarts and asks for the next input
+	s.m.ResetCurrentInput()
+	input, err := s.m.FinishAndGetNext(true)
+	s.NoError(err)
+	advance, ok := input.(cModel.AdvanceInput)
+	s.Require().True(ok)
+	s.Equal(0, advance.Index)
+	s.Equal(cModel.CompletionStatusUnprocessed, advance.Status)
+
+	// the outputs of the first attempt are discarded
+	_, err = s.m.FinishAndGetNext(true) // finish
+	s.NoError(err)
+	ctx := context.Background()
+	vouchers, err := s.convenienceService.FindAllVouchers(ctx, nil, nil, nil, nil, nil)
+	s.NoError(err)
+	s.Len(vouchers.Rows, 0)
+}
+
+func (s *ModelSuite) TestItReturnsTheCurrentAdvanceIndex() {
+	_, ok := s.m.CurrentAdvanceIndex()
+	s.False(ok)
+
+	for i := 0; i < 2; i++ {
+		err := s.m.AddAdvanceInput(s.senders[i], s.payloads[i], s.blockNumbers[i], s.timestamps[i], i, "", common.Address{}, "")
+		s.NoError(err)
+	}
+	_, err := s.m.FinishAndGetNext(true) // get
+	s.NoError(err)
+	index, ok := s.m.CurrentAdvanceIndex()
+	s.True(ok)
+	s.Equal(0, index)
+
+	_, err = s.m.FinishAndGetNext(true) // finish and get
+	s.NoError(err)
+	index, ok = s.m.CurrentAdvanceIndex()
+	s.True(ok)
+	s.Equal(1, index)
+}
+
+func (s *ModelSuite) TestItAddsSequencedInputsAfterTheExistingOnes() {
+	err := s.m.AddAdvanceInput(s.senders[0], s.payloads[0], s.blockNumbers[0], s.timestamps[0], 0, "", common.Address{}, "")
+	s.NoError(err)
+	added, err := s.m.AddSequencedInputs([]cModel.AdvanceInput{
+		{ID: "0x01", MsgSender: s.senders[1], Payload: s.payloads[1], InputBoxIndex: -2, Type: "L2"},
+		{ID: "0x02", MsgSender: s.senders[2], Payload: s.payloads[2], InputBoxIndex: -2, Type: "L2"},
+	})
+	s.NoError(err)
+	s.Len(added, 2)
+
+	inputs := s.getAllInputs(0, 100)
+	s.Len(inputs, 3)
+	for i, id := range []string{"0x01", "0x02"} {
+		s.Equal(i+1, added[i].Index)
+		s.Equal(id, inputs[i+1].ID)
+		s.Equal(i+1, inputs[i+1].Index)
+		s.Equal(cModel.CompletionStatusUnprocessed, inputs[i+1].Status)
+		s.Equal("L2", inputs[i+1].Type)
+	}
+}
+
 func (s *ModelSuite) TestItFinishesAdvanceWithReject() {
 	// add input and process it
 	err := s.m.AddAdvanceInput(s.senders[0], s.payloads[0], s.blockNumbers[0], s.timestamps[0], 0, "", common.Address{}, "")
@@ -580,6 +660,221 @@ func (s *ModelSuite) TestItRegistersExceptionWhenAdvancing() {
 	s.Equal(1, int(total))
 }
 
+func (s *ModelSuite) TestItPausesTheDeliveryOfTheAdvances() {
+	appContract := common.HexToAddress(devnet.ApplicationAddress)
+	err := s.m.AddAdvanceInput(s.senders[0], s.payloads[0], s.blockNumbers[0], s.timestamps[0], 0, "", appContract, "")
+	s.NoError(err)
+	s.m.AddInspectInput(common.Hex2Bytes(s.payloads[1]))
+
+	s.m.Pause()
+	s.True(s.m.Paused())
+	input, err := s.m.FinishAndGetNext(true)
+	s.NoError(err)
+	_, ok := input.(InspectInput)
+	s.True(ok, "the inspects are delivered while paused")
+	input, err = NewInputBoxSequencer(s.m).FinishAndGetNext(true)
+	s.NoError(err)
+	s.Nil(input)
+
+	s.m.Resume()
+	s.False(s.m.Paused())
+	input, err = s.m.FinishAndGetNext(true)
+	s.NoError(err)
+	s.Require().NotNil(input)
+	s.Equal(0, input.(cModel.AdvanceInput).Index)
+}
+
+func (s *ModelSuite) TestItRejectsTheCurrentInput() {
+	ctx := context.Background()
+	appContract := common.HexToAddress(devnet.ApplicationAddress)
+	_, ok, err := s.m.RejectCurrentInput()
+	s.NoError(err)
+	s.False(ok)
+	for i := 0; i < 2; i++ {
+		err := s.m.AddAdvanceInput(s.senders[i], s.payloads[i], s.blockNumbers[i], s.timestamps[i], i, "", appContract, "")
+		s.NoError(err)
+	}
+
+	_, err = s.m.FinishAndGetNext(true) // get 0
+	s.NoError(err)
+	_, err = s.m.AddVoucher(appContract, s.senders[0], "0", common.Hex2Bytes(s.payloads[0]))
+	s.NoError(err)
+	err = s.m.AddReport(appContract, common.Hex2Bytes(s.payloads[0]))
+	s.NoError(err)
+	index, ok, err := s.m.RejectCurrentInput()
+	s.NoError(err)
+	s.True(ok)
+	s.Equal(0, index)
+	_, err = s.m.AddVoucher(appContract, s.senders[0], "0", common.Hex2Bytes(s.payloads[0]))
+	s.Error(err)
+
+	_, err = s.m.FinishAndGetNext(true) // get 1
+	s.NoError(err)
+	err = s.m.AddReport(appContract, common.Hex2Bytes(s.payloads[1]))
+	s.NoError(err)
+	index, ok, err = s.m.SkipCurrentInput()
+	s.NoError(err)
+	s.True(ok)
+	s.Equal(1, index)
+
+	for i := 0; i < 2; i++ {
+		input, err := s.inputRepository.FindByIDAndAppContract(ctx, strconv.Itoa(i), nil)
+		s.NoError(err)
+		s.Equal(cModel.CompletionStatusRejected, input.Status)
+		s.Empty(input.Vouchers)
+	}
+	// only the report of the rejected input is kept
+	total, err := s.reportRepository.Count(ctx, nil)
+	s.NoError(err)
+	s.Equal(1, int(total))
+}
+
+type eventsRecorder struct {
+	events []events.Event
+}
+
+func (r *eventsRecorder) Publish(event events.Event) {
+	r.events = append(r.events, event)
+}
+
+func (s *ModelSuite) TestItPublishesTheEvents() {
+	recorder := &eventsRecorder{}
+	s.m.SetPublisher(recorder)
+	appContract := common.HexToAddress(devnet.ApplicationAddress)
+	err := s.m.AddAdvanceInput(s.senders[0], s.payloads[0], s.blockNumbers[0], s.timestamps[0], 0, "", appContract, "")
+	s.NoError(err)
+	err = s.m.AddAdvanceInput(s.senders[1], s.payloads[1], s.blockNumbers[1], s.timestamps[1], 1, "", appContract, "")
+	s.NoError(err)
+	_, err = s.m.FinishAndGetNext(true) // get
+	s.NoError(err)
+	_, err = s.m.AddVoucher(appContract, s.senders[0], "0", common.Hex2Bytes(s.payloads[0]))
+	s.NoError(err)
+	_, err = s.m.AddNotice(common.Hex2Bytes(s.payloads[1]), appContract)
+	s.NoError(err)
+	_, err = s.m.FinishAndGetNext(true) // finish 0 and get 1
+	s.NoError(err)
+	err = s.m.AddReport(appContract, common.Hex2Bytes(s.payloads[2]))
+	s.NoError(err)
+	err = s.m.RegisterException(common.Hex2Bytes(s.payloads[2]))
+	s.NoError(err)
+
+	zero := 0
+	app := appContract.Hex()
+	for i := range recorder.events {
+		recorder.events[i].Time = time.Time{}
+	}
+	s.Equal([]events.Event{
+		{Type: events.InputAdded, AppContract: app, InputIndex: 0, Status: "UNPROCESSED", Payload: "0x" + s.payloads[0]},
+		{Type: events.InputAdded, AppContract: app, InputIndex: 1, Status: "UNPROCESSED", Payload: "0x" + s.payloads[1]},
+		{Type: events.InputFinished, AppContract: app, InputIndex: 0, Status: "ACCEPTED"},
+		{Type: events.VoucherAdded, AppContract: app, InputIndex: 0, OutputIndex: &zero,
+			Destination: s.senders[0].Hex(), Payload: "0x" + s.payloads[0]},
+		{Type: events.NoticeAdded, AppContract: app, InputIndex: 0, OutputIndex: &zero, Payload: "0x" + s.payloads[1]},
+		{Type: events.InputFinished, AppContract: app, InputIndex: 1, Status: "EXCEPTION"},
+		{Type: events.ReportAdded, AppContract: app, InputIndex: 1, OutputIndex: &zero, Payload: "0x" + s.payloads[2]},
+	}, recorder.events)
+}
+
+// The rollups API finishes the inputs through the sequencer.
+func (s *ModelSuite) TestTheSequencerPublishesTheFinishedInputs() {
+	recorder := &eventsRecorder{}
+	s.m.SetPublisher(recorder)
+	appContract := common.HexToAddress(devnet.ApplicationAddress)
+	err := s.m.AddAdvanceInput(s.senders[0], s.payloads[0], s.blockNumbers[0], s.timestamps[0], 0, "", appContract, "")
+	s.NoError(err)
+	sequencer := NewInputBoxSequencer(s.m)
+	_, err = sequencer.FinishAndGetNext(true) // get
+	s.NoError(err)
+	_, err = s.m.AddNotice(common.Hex2Bytes(s.payloads[1]), appContract)
+	s.NoError(err)
+	_, err = sequencer.FinishAndGetNext(true) // finish 0
+	s.NoError(err)
+
+	s.Require().Len(recorder.events, 3) // nolint
+	s.Equal(events.InputFinished, recorder.events[1].Type)
+	s.Equal("ACCEPTED", recorder.events[1].Status)
+	s.Equal(events.NoticeAdded, recorder.events[2].Type)
+}
+
+func (s *ModelSuite) TestItRecordsTheMetrics() {
+	vouchers := testutil.ToFloat64(metrics.Outputs.WithLabelValues(metrics.OutputVoucher))
+	reports := testutil.ToFloat64(metrics.Outputs.WithLabelValues(metrics.OutputReport))
+	inspects := sampleCount(metrics.InspectDuration)
+	rejected := sampleCount(metrics.AdvanceDuration.WithLabelValues("REJECTED"))
+	appContract := common.HexToAddress(devnet.ApplicationAddress)
+	for i := 0; i < 2; i++ {
+		err := s.m.AddAdvanceInput(s.senders[i], s.payloads[i], s.blockNumbers[i], s.timestamps[i], i, "", appContract, "")
+		s.NoError(err)
+	}
+	s.m.AddInspectInput(common.Hex2Bytes(s.payloads[0]))
+	unprocessed, err := s.m.UnprocessedInputs()
+	s.NoError(err)
+	s.Equal(2, unprocessed) // nolint
+	s.Equal(1, s.m.PendingInspects())
+
+	// the rollups API finishes the inputs through the sequencer
+	sequencer := NewInputBoxSequencer(s.m)
+	_, err = sequencer.FinishAndGetNext(true) // get the inspect
+	s.NoError(err)
+	_, err = sequencer.FinishAndGetNext(true) // finish the inspect and get 0
+	s.NoError(err)
+	s.Equal(0, s.m.PendingInspects())
+	_, err = s.m.AddVoucher(appContract, s.senders[0], "0", common.Hex2Bytes(s.payloads[0]))
+	s.NoError(err)
+	err = s.m.AddReport(appContract, common.Hex2Bytes(s.payloads[1]))
+	s.NoError(err)
+	_, err = sequencer.FinishAndGetNext(false) // reject 0 and get 1
+	s.NoError(err)
+	unprocessed, err = s.m.UnprocessedInputs()
+	s.NoError(err)
+	s.Equal(1, unprocessed)
+
+	// the vouchers of a rejected input aren't stored
+	s.Equal(vouchers, testutil.ToFloat64(metrics.Outputs.WithLabelValues(metrics.OutputVoucher)))
+	s.Equal(reports+1, testutil.ToFloat64(metrics.Outputs.WithLabelValues(metrics.OutputReport)))
+	s.Equal(inspects+1, sampleCount(metrics.InspectDuration))
+	s.Equal(rejected+1, sampleCount(metrics.AdvanceDuration.WithLabelValues("REJECTED")))
+}
+
+func (s *ModelSuite) TestItTracesTheAdvance() {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+	appContract := common.HexToAddress(devnet.ApplicationAddress)
+	ctx, ingestion := tracing.Start(context.Background(), "ingestion")
+	tracing.Inputs.Add(ctx, appContract.Hex(), "0")
+	ingestion.End()
+	err := s.m.AddAdvanceInput(s.senders[0], s.payloads[0], s.blockNumbers[0], s.timestamps[0], 0, "", appContract, "")
+	s.NoError(err)
+
+	s.False(trace.SpanContextFromContext(s.m.TraceContext(context.Background())).IsValid())
+	_, err = s.m.FinishAndGetNext(true)
+	s.NoError(err)
+	advance := trace.SpanContextFromContext(s.m.TraceContext(context.Background()))
+	s.Equal(ingestion.SpanContext().TraceID(), advance.TraceID())
+	_, err = s.m.FinishAndGetNext(true)
+	s.NoError(err)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	s.Require().Contains(spans, "nonodo.advance")
+	s.Require().Contains(spans, "rollupsStateAdvance.finish")
+	s.Equal(ingestion.SpanContext().SpanID(), spans["nonodo.advance"].Parent().SpanID())
+	s.Equal(advance.SpanID(), spans["rollupsStateAdvance.finish"].Parent().SpanID())
+	s.Contains(spans["nonodo.advance"].Attributes(), tracing.StatusKey.String("ACCEPTED"))
+	ctx = tracing.Inputs.Context(context.Background(), appContract.Hex(), "0")
+	s.False(trace.SpanContextFromContext(ctx).IsValid())
+}
+
+// Number of observations of the histogram.
+func sampleCount(observer prometheus.Observer) uint64 {
+	var metric dto.Metric
+	_ = observer.(prometheus.Metric).Write(&metric)
+	return metric.GetHistogram().GetSampleCount()
+}
+
 func (s *ModelSuite) TestItRegistersExceptionWhenInspecting() {
 	// add input and finish it
 	s.m.AddInspectInput(common.Hex2Bytes(s.payloads[0]))
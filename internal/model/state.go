@@ -7,11 +7,16 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"time"
 
+	"github.com/calindra/nonodo/internal/events"
+	"github.com/calindra/nonodo/internal/tracing"
 	cModel "github.com/cartesi/rollups-graphql/pkg/convenience/model"
 	cRepos "github.com/cartesi/rollups-graphql/pkg/convenience/repository"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Interface that represents the state of the rollup.
@@ -98,6 +103,10 @@ type rollupsStateAdvance struct {
 	inputRepository   *cRepos.InputRepository
 	voucherRepository *cRepos.VoucherRepository
 	noticeRepository  *cRepos.NoticeRepository
+	started           time.Time
+	// Span of the processing, in the trace of the ingestion of the input.
+	ctx  context.Context
+	span trace.Span
 }
 
 func newRollupsStateAdvance(
@@ -109,6 +118,13 @@ func newRollupsStateAdvance(
 	noticeRepository *cRepos.NoticeRepository,
 ) rollupsState {
 	slog.Info("nonodo: processing advance", "index", input.Index)
+	appContract := input.AppContract.Hex()
+	ctx, span := tracing.Start(tracing.Inputs.Context(context.Background(), appContract, input.ID),
+		"nonodo.advance",
+		tracing.AppContractKey.String(appContract),
+		tracing.InputIdKey.String(input.ID),
+		tracing.InputIndexKey.Int(input.Index),
+	)
 	return &rollupsStateAdvance{
 		input:             input,
 		decoder:           decoder,
@@ -116,6 +132,9 @@ func newRollupsStateAdvance(
 		inputRepository:   inputRepository,
 		voucherRepository: voucherRepository,
 		noticeRepository:  noticeRepository,
+		started:           time.Now(),
+		ctx:               ctx,
+		span:              span,
 	}
 }
 
@@ -173,7 +192,12 @@ func saveAllReports(reportRepository *cRepos.ReportRepository, reports []cModel.
 	return nil
 }
 
-func (s *rollupsStateAdvance) finish(status cModel.CompletionStatus) error {
+func (s *rollupsStateAdvance) finish(status cModel.CompletionStatus) (err error) {
+	_, span := tracing.Start(s.ctx, "rollupsStateAdvance.finish")
+	defer func() {
+		endSpan(span, err)
+		s.end(err)
+	}()
 	s.input.Status = status
 	if status == cModel.CompletionStatusAccepted {
 		s.input.Vouchers = s.vouchers
@@ -197,7 +221,7 @@ func (s *rollupsStateAdvance) finish(status cModel.CompletionStatus) error {
 	// s.input.Reports = s.reports
 	ctx := context.Background()
 
-	err := saveAllReports(s.reportRepository, s.reports)
+	err = saveAllReports(s.reportRepository, s.reports)
 
 	if err != nil {
 		slog.Error("Error saving reports", "Error", err)
@@ -271,12 +295,17 @@ func (s *rollupsStateAdvance) addReport(appAddress common.Address, payload []byt
 	return nil
 }
 
-func (s *rollupsStateAdvance) registerException(payload []byte) error {
+func (s *rollupsStateAdvance) registerException(payload []byte) (err error) {
+	_, span := tracing.Start(s.ctx, "rollupsStateAdvance.registerException")
+	defer func() {
+		endSpan(span, err)
+		s.end(err)
+	}()
 	s.input.Status = cModel.CompletionStatusException
 	s.input.Reports = s.reports
 	s.input.Exception = payload
 	ctx := context.Background()
-	_, err := s.inputRepository.Update(ctx, *s.input)
+	_, err = s.inputRepository.Update(ctx, *s.input)
 	if err != nil {
 		return err
 	}
@@ -290,6 +319,22 @@ func (s *rollupsStateAdvance) registerException(payload []byte) error {
 	return nil
 }
 
+// End the span of the processing with the final status of the input.
+func (s *rollupsStateAdvance) end(err error) {
+	status, _ := events.StatusName(s.input.Status)
+	s.span.SetAttributes(tracing.StatusKey.String(status))
+	endSpan(s.span, err)
+	tracing.Inputs.Remove(s.input.AppContract.Hex(), s.input.ID)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
 //
 // Inspect
 //
@@ -299,6 +344,7 @@ type rollupsStateInspect struct {
 	input                  *InspectInput
 	reports                []Report
 	getProcessedInputCount func() (int, error)
+	started                time.Time
 }
 
 func newRollupsStateInspect(
@@ -309,6 +355,7 @@ func newRollupsStateInspect(
 	return &rollupsStateInspect{
 		input:                  input,
 		getProcessedInputCount: getProcessedInputCount,
+		started:                time.Now(),
 	}
 }
 
@@ -0,0 +1,181 @@
+package nonodo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/ncruces/go-sqlite3/vfs/memdb"
+)
+
+// Storage of the nonodo database.
+const (
+	// SQLite in memory, gone when nonodo exits.
+	DbModeMemory = "memory"
+	// SQLite in --sqlite-file, or in a temporary file removed when nonodo exits.
+	DbModeFile = "file"
+	// Postgres of POSTGRES_GRAPHQL_DB_URL.
+	DbModePostgres = "postgres"
+)
+
+var dbModes = []string{DbModeMemory, DbModeFile, DbModePostgres}
+
+// Storage of the database, by --db-mode or by the deprecated --db-implementation.
+func (opts NonodoOpts) DatabaseMode() string {
+	if opts.DbMode != "" {
+		return opts.DbMode
+	}
+	if opts.DbImplementation == "postgres" {
+		return DbModePostgres
+	}
+	return DbModeFile
+}
+
+// Open the database of nonodo.
+// The cleanup closes it and removes the temporary storage, if there is one.
+func OpenDatabase(opts NonodoOpts) (*sqlx.DB, func(), error) {
+	switch mode := opts.DatabaseMode(); mode {
+	case DbModePostgres:
+		slog.Info("Using PostGres DB ...")
+		connectionString, deprecated := postgresConnectionString()
+		if deprecated {
+			slog.Warn("The environment variables POSTGRES_HOST, POSTGRES_PORT, POSTGRES_DB, POSTGRES_USER, and POSTGRES_PASSWORD are deprecated. Please use POSTGRES_GRAPHQL_DB_URL instead.")
+		}
+		db, err := sqlx.Connect("postgres", connectionString)
+		if err != nil {
+			return nil, nil, err
+		}
+		configureConnectionPool(db)
+		return db, closeDatabase(db), nil
+	case DbModeMemory:
+		return openMemorySQLite()
+	case DbModeFile:
+		return openSQLite(opts)
+	default:
+		return nil, nil, fmt.Errorf("unknown database mode %q", mode)
+	}
+}
+
+// Open the database of nonodo or panic.
+// Prefer OpenDatabase, which also removes the temporary storage.
+func CreateDBInstance(opts NonodoOpts) *sqlx.DB {
+	db, _, err := OpenDatabase(opts)
+	if err != nil {
+		panic(err)
+	}
+	return db
+}
+
+func closeDatabase(db *sqlx.DB) func() {
+	return func() {
+		if err := db.Close(); err != nil {
+			slog.Warn("Failed to close the database", "error", err)
+		}
+	}
+}
+
+// Connection string of POSTGRES_GRAPHQL_DB_URL, or of the deprecated POSTGRES_* variables.
+func postgresConnectionString() (connectionString string, deprecated bool) {
+	if dbUrl, ok := os.LookupEnv("POSTGRES_GRAPHQL_DB_URL"); ok {
+		return dbUrl, false
+	}
+	postgresHost := os.Getenv("POSTGRES_HOST")
+	postgresPort := os.Getenv("POSTGRES_PORT")
+	postgresDataBase := os.Getenv("POSTGRES_DB")
+	postgresUser := os.Getenv("POSTGRES_USER")
+	postgresPassword := os.Getenv("POSTGRES_PASSWORD")
+	connectionString = fmt.Sprintf("host=%s port=%s user=%s "+
+		"dbname=%s password=%s sslmode=disable",
+		postgresHost, postgresPort, postgresUser,
+		postgresDataBase, postgresPassword)
+	return connectionString, true
+}
+
+// nolint
+func configureConnectionPool(db *sqlx.DB) {
+	maxOpenConns := getEnvInt("DB_MAX_OPEN_CONNS", 25)
+	maxIdleConns := getEnvInt("DB_MAX_IDLE_CONNS", 10)
+	connMaxLifetime := getEnvInt("DB_CONN_MAX_LIFETIME", 1800) // 30 min
+	connMaxIdleTime := getEnvInt("DB_CONN_MAX_IDLE_TIME", 300) // 5 min
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxIdleConns)
+	db.SetConnMaxLifetime(time.Duration(connMaxLifetime) * time.Second)
+	db.SetConnMaxIdleTime(time.Duration(connMaxIdleTime) * time.Second)
+}
+
+func getEnvInt(envName string, defaultValue int) int {
+	value, exists := os.LookupEnv(envName)
+	if !exists {
+		return defaultValue
+	}
+	intValue, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Error("configuration error", "envName", envName, "value", value)
+		panic(err)
+	}
+	return intValue
+}
+
+func openSQLite(opts NonodoOpts) (*sqlx.DB, func(), error) {
+	slog.Info("Using SQLite ...")
+	if opts.SqliteFile != "" {
+		db, err := sqlx.Connect("sqlite3", opts.SqliteFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return db, closeDatabase(db), nil
+	}
+	sqlitePath, err := os.MkdirTemp("", "nonodo-db-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	sqliteFile := filepath.Join(sqlitePath, "nonodo.sqlite3")
+	slog.Debug("SQLite3 file created", "path", sqliteFile)
+	db, err := sqlx.Connect("sqlite3", sqliteFile)
+	if err != nil {
+		os.RemoveAll(sqlitePath)
+		return nil, nil, err
+	}
+	cleanup := func() {
+		closeDatabase(db)()
+		if err := os.RemoveAll(sqlitePath); err != nil {
+			slog.Warn("Failed to remove the temporary database", "path", sqlitePath, "error", err)
+			return
+		}
+		slog.Debug("SQLite3 file removed", "path", sqliteFile)
+	}
+	return db, cleanup, nil
+}
+
+// Open a SQLite database shared by the connections of the pool, kept in memory
+// while at least one of them is open.
+func openMemorySQLite() (*sqlx.DB, func(), error) {
+	slog.Info("Using SQLite in memory ...")
+	var suffix [8]byte
+	if _, err := rand.Read(suffix[:]); err != nil {
+		return nil, nil, err
+	}
+	dsn := fmt.Sprintf("file:/nonodo-%s?vfs=memdb", hex.EncodeToString(suffix[:]))
+	db, err := sqlx.Connect("sqlite3", dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the pool may close every idle connection, so one is held until the cleanup
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	cleanup := func() {
+		conn.Close()
+		closeDatabase(db)()
+	}
+	return db, cleanup, nil
+}
@@ -0,0 +1,169 @@
+package nonodo
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/calindra/nonodo/internal/migration"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/ncruces/go-sqlite3/driver"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	"github.com/stretchr/testify/suite"
+)
+
+type DatabaseSuite struct {
+	suite.Suite
+	ctx context.Context
+}
+
+func TestDatabaseSuite(t *testing.T) {
+	suite.Run(t, new(DatabaseSuite))
+}
+
+func (s *DatabaseSuite) SetupTest() {
+	s.ctx = context.Background()
+}
+
+func (s *DatabaseSuite) open(mode string, sqliteFile string) (*sqlx.DB, func()) {
+	opts := NewNonodoOpts()
+	opts.DbMode = mode
+	opts.SqliteFile = sqliteFile
+	db, cleanup, err := OpenDatabase(opts)
+	s.Require().NoError(err)
+	s.Require().NoError(MigrateDatabase(s.ctx, db))
+	return db, cleanup
+}
+
+func (s *DatabaseSuite) insertRows(db *sqlx.DB) {
+	app := "0x75135d8ADb7180640d29d822D9AD59E83E8695b2"
+	db.MustExec(`INSERT INTO convenience_inputs (id, input_index, app_contract, status, msg_sender, payload,
+		block_number, block_timestamp, prev_randao, exception, type, chain_id)
+		VALUES ('1', 1, $1, 'ACCEPTED', '0xf39F', '0x1234', 10, 1700000000, '0x00', '', 'inputbox', '31337')`, app)
+	db.MustExec(`INSERT INTO vouchers (destination, payload, executed, input_index, output_index, value,
+		output_hashes_siblings, app_contract, transaction_hash, proof_output_index, is_delegated_call)
+		VALUES ('0x70997970', '0xabcd', true, 1, 0, '0', '["0x01","0x02"]', $1, '', 3, false)`, app)
+	db.MustExec(`INSERT INTO notices (payload, input_index, output_index, app_contract, output_hashes_siblings)
+		VALUES ('0xdead', 1, 1, $1, '')`, app)
+	db.MustExec(`INSERT INTO convenience_reports (output_index, payload, input_index, app_contract, app_id)
+		VALUES (0, '0xbeef', 1, $1, 0)`, app)
+}
+
+func (s *DatabaseSuite) TestTheMemoryDatabaseIsSharedByTheConnections() {
+	db, cleanup := s.open(DbModeMemory, "")
+	defer cleanup()
+	db.SetMaxIdleConns(0)
+	db.MustExec("CREATE TABLE t (x INTEGER)")
+	db.MustExec("INSERT INTO t VALUES (1)")
+	var count int
+	s.Require().NoError(db.Get(&count, "SELECT COUNT(*) FROM t"))
+	s.Equal(1, count)
+}
+
+func (s *DatabaseSuite) TestTheTemporaryFileIsRemoved() {
+	db, cleanup := s.open(DbModeFile, "")
+	var rows []struct {
+		Seq  int
+		Name string
+		File string
+	}
+	s.Require().NoError(db.Select(&rows, "PRAGMA database_list"))
+	s.Require().NotEmpty(rows)
+	dir := filepath.Dir(rows[0].File)
+	s.DirExists(dir)
+	cleanup()
+	s.NoDirExists(dir)
+}
+
+func (s *DatabaseSuite) TestTheExportRoundTrips() {
+	source, cleanupSource := s.open(DbModeMemory, "")
+	defer cleanupSource()
+	s.insertRows(source)
+	var export bytes.Buffer
+	counts, err := ExportDatabase(s.ctx, source, &export)
+	s.Require().NoError(err)
+	s.Equal(map[string]int{"convenience_inputs": 1, "vouchers": 1, "notices": 1, "convenience_reports": 1}, counts)
+	s.Contains(export.String(), `"output_hashes_siblings":"[\"0x01\",\"0x02\"]"`)
+
+	sqliteFile := filepath.Join(s.T().TempDir(), "nonodo.sqlite3")
+	target, cleanupTarget := s.open(DbModeFile, sqliteFile)
+	defer cleanupTarget()
+	counts, err = ImportDatabase(s.ctx, target, bytes.NewReader(export.Bytes()))
+	s.Require().NoError(err)
+	s.Equal(map[string]int{"convenience_inputs": 1, "vouchers": 1, "notices": 1, "convenience_reports": 1}, counts)
+
+	var again bytes.Buffer
+	_, err = ExportDatabase(s.ctx, target, &again)
+	s.Require().NoError(err)
+	s.Equal(export.String(), again.String())
+	s.FileExists(sqliteFile)
+
+	_, err = ImportDatabase(s.ctx, target, bytes.NewReader(export.Bytes()))
+	s.ErrorContains(err, "table convenience_inputs is not empty")
+}
+
+func (s *DatabaseSuite) TestTheImportReportsTheLine() {
+	db, cleanup := s.open(DbModeMemory, "")
+	defer cleanup()
+	export := strings.Join([]string{
+		`{"format":"nonodo","version":1}`,
+		`{"table":"notices","row":{"payload":"0x","input_index":0,"output_index":0,"app_contract":"0x"}}`,
+		`{"table":"outputs","row":{}}`,
+	}, "\n")
+	_, err := ImportDatabase(s.ctx, db, strings.NewReader(export))
+	s.EqualError(err, `line 3: unknown table "outputs"`)
+
+	// nothing is imported on error
+	var count int
+	s.Require().NoError(db.Get(&count, "SELECT COUNT(*) FROM notices"))
+	s.Zero(count)
+
+	_, err = ImportDatabase(s.ctx, db, strings.NewReader(`{"format":"nonodo","version":2}`))
+	s.EqualError(err, `unsupported export "nonodo" version 2; expected "nonodo" version 1`)
+}
+
+func (s *DatabaseSuite) TestTheMigrationsAreValidForEachDialect() {
+	for _, dialect := range []string{"sqlite", "postgres"} {
+		migrations, err := Migrations(dialect)
+		s.Require().NoError(err)
+		s.Equal("0001_baseline", migrations[0].String())
+		_, err = migration.NewMigrator(nil, migrations)
+		s.NoError(err, dialect)
+	}
+
+	db, cleanup := s.open(DbModeMemory, "")
+	defer cleanup()
+	migrator, err := NewMigrator(db)
+	s.Require().NoError(err)
+	pending, err := migrator.Pending(s.ctx)
+	s.Require().NoError(err)
+	s.Empty(pending)
+}
+
+func (s *DatabaseSuite) TestTheEspressoInputIdsGetThePrefix() {
+	db, cleanup := s.open(DbModeMemory, "")
+	defer cleanup()
+	app := "0x75135d8ADb7180640d29d822D9AD59E83E8695b2"
+	for _, id := range []string{"abcd", "0xef01"} {
+		db.MustExec(`INSERT INTO convenience_inputs (id, input_index, app_contract, status, type)
+			VALUES ($1, 0, $2, 'UNPROCESSED', 'Espresso')`, id, app)
+	}
+	db.MustExec(`INSERT INTO convenience_inputs (id, input_index, app_contract, status, type)
+		VALUES ('2', 2, $1, 'UNPROCESSED', 'inputbox')`, app)
+	db.MustExec(`INSERT INTO ordering_decisions (app_contract, input_index, input_id, source, policy)
+		VALUES ($1, 0, 'abcd', 'espresso', 'l1-finalized')`, app)
+
+	migrations, err := Migrations(migration.Dialect(db))
+	s.Require().NoError(err)
+	s.Equal("0003_espresso_input_ids", migrations[2].String())
+	db.MustExec(migrations[2].SQL)
+
+	var ids []string
+	s.Require().NoError(db.Select(&ids, "SELECT id FROM convenience_inputs ORDER BY id"))
+	s.Equal([]string{"0xabcd", "0xef01", "2"}, ids)
+	var decision string
+	s.Require().NoError(db.Get(&decision, "SELECT input_id FROM ordering_decisions"))
+	s.Equal("0xabcd", decision)
+}
@@ -0,0 +1,150 @@
+package nonodo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/calindra/nonodo/internal/config"
+	"github.com/calindra/nonodo/internal/devnet"
+	"github.com/calindra/nonodo/internal/sequencers/avail"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Time to wait for each remote service checked by the doctor.
+const DoctorTimeout = 5 * time.Second
+
+type CheckStatus string
+
+const (
+	CheckOK   CheckStatus = "ok"
+	CheckWarn CheckStatus = "warn"
+	CheckFail CheckStatus = "fail"
+)
+
+// Result of a doctor check.
+type Check struct {
+	Name    string
+	Status  CheckStatus
+	Details []string
+}
+
+// Run the checks of Validate, one per group, then diagnose the services and the environment
+// nonodo depends on.
+func (opts NonodoOpts) Doctor(ctx context.Context) []Check {
+	var checks []Check
+	for _, v := range validations {
+		check := Check{Name: v.Name, Status: CheckOK}
+		if problems := v.check(opts); len(problems) > 0 {
+			check.Status = CheckFail
+			check.Details = problems
+		}
+		checks = append(checks, check)
+	}
+	if opts.usesDevnet() {
+		checks = append(checks, opts.checkAnvil())
+	}
+	if opts.RpcUrl != "" {
+		checks = append(checks, opts.checkRpc(ctx))
+	}
+	if opts.Sequencer == "espresso" && !opts.EspressoMock {
+		checks = append(checks, checkUrl(ctx, "espresso", opts.EspressoUrl))
+	}
+	if opts.AvailEnabled && !opts.AvailEmulator {
+		checks = append(checks, checkUrl(ctx, "paio", opts.PaioServerUrl), checkAvailEnv())
+	}
+	return append(checks, checkEnvironment())
+}
+
+// Whether any check failed.
+func HasFailures(checks []Check) bool {
+	for _, check := range checks {
+		if check.Status == CheckFail {
+			return true
+		}
+	}
+	return false
+}
+
+func (opts NonodoOpts) checkAnvil() Check {
+	check := Check{Name: "anvil", Status: CheckOK}
+	if opts.AnvilCommand == "" {
+		check.Details = []string{"downloaded from the foundry releases on start"}
+		return check
+	}
+	version, err := devnet.GetAnvilVersion(opts.AnvilCommand)
+	if err != nil {
+		check.Status = CheckFail
+		check.Details = []string{err.Error()}
+		return check
+	}
+	check.Details = []string{version}
+	return check
+}
+
+func (opts NonodoOpts) checkRpc(ctx context.Context) Check {
+	ctx, cancel := context.WithTimeout(ctx, DoctorTimeout)
+	defer cancel()
+	check := Check{Name: "rpc", Status: CheckFail}
+	client, err := ethclient.DialContext(ctx, opts.RpcUrl)
+	if err != nil {
+		check.Details = []string{fmt.Sprintf("%s: %v", opts.RpcUrl, err)}
+		return check
+	}
+	defer client.Close()
+	chainId, err := client.ChainID(ctx)
+	if err != nil {
+		check.Details = []string{fmt.Sprintf("%s: %v", opts.RpcUrl, err)}
+		return check
+	}
+	check.Status = CheckOK
+	check.Details = []string{fmt.Sprintf("%s: chain id %v", opts.RpcUrl, chainId)}
+	return check
+}
+
+// Check that the url answers HTTP requests, whatever the status.
+func checkUrl(ctx context.Context, name string, url string) Check {
+	ctx, cancel := context.WithTimeout(ctx, DoctorTimeout)
+	defer cancel()
+	check := Check{Name: name, Status: CheckWarn}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		check.Details = []string{err.Error()}
+		return check
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		check.Details = []string{fmt.Sprintf("%s is not reachable: %v", url, err)}
+		return check
+	}
+	response.Body.Close()
+	check.Status = CheckOK
+	check.Details = []string{url}
+	return check
+}
+
+func checkAvailEnv() Check {
+	check := Check{Name: "avail", Status: CheckOK}
+	if _, ok := os.LookupEnv("AVAIL_RPC_URL"); !ok {
+		check.Status = CheckWarn
+		check.Details = append(check.Details, "AVAIL_RPC_URL is not set; using "+avail.DEFAULT_AVAIL_RPC_URL)
+	}
+	if _, ok := os.LookupEnv("AVAIL_MNEMONIC"); !ok {
+		check.Status = CheckWarn
+		check.Details = append(check.Details, "AVAIL_MNEMONIC is not set; using the default mnemonic")
+	}
+	return check
+}
+
+// List the environment variables read by nonodo that are set, without their values.
+func checkEnvironment() Check {
+	check := Check{Name: "environment", Status: CheckOK}
+	for _, name := range config.EnvKeys {
+		if _, ok := os.LookupEnv(name); ok {
+			check.Details = append(check.Details, name+" is set")
+		}
+	}
+	return check
+}
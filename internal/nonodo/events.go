@@ -0,0 +1,108 @@
+package nonodo
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+
+	"github.com/calindra/nonodo/internal/events"
+	"github.com/jmoiron/sqlx"
+)
+
+// Sinks of --event-sinks, besides the webhook urls.
+const (
+	EventSinkPostgres = "postgres"
+	EventSinkStdout   = "stdout"
+	EventSinkNone     = "none"
+)
+
+// Environment variable with the key of the webhook signatures.
+const WebhookSecretEnv = "NONODO_WEBHOOK_SECRET"
+
+// Sinks of the events, with the default of the database mode.
+func (opts NonodoOpts) eventSinkNames() []string {
+	if opts.EventSinks != nil {
+		return opts.EventSinks
+	}
+	if opts.DatabaseMode() == DbModePostgres {
+		return []string{EventSinkPostgres}
+	}
+	return nil
+}
+
+// Whether the logs go to stderr, because the stdout sink keeps stdout for the events.
+func (opts NonodoOpts) LogsToStderr() bool {
+	return slices.Contains(opts.eventSinkNames(), EventSinkStdout)
+}
+
+// Create the sinks of the events; the webhooks record their deliveries in the log.
+func (opts NonodoOpts) eventSinks(db *sqlx.DB, deliveries *events.DeliveryLog) ([]events.Sink, error) {
+	var sinks []events.Sink
+	for _, name := range append(slices.Clone(opts.eventSinkNames()), opts.WebhookUrls...) {
+		switch name {
+		case EventSinkNone:
+		case EventSinkPostgres:
+			sinks = append(sinks, events.NewPostgresSink(db))
+		case EventSinkStdout:
+			sinks = append(sinks, events.NewJSONLSink(EventSinkStdout, os.Stdout))
+		default:
+			if err := checkWebhookUrl(name); err != nil {
+				return nil, err
+			}
+			webhook := events.NewWebhookSink(name)
+			for _, eventType := range opts.WebhookEvents {
+				webhook.Types = append(webhook.Types, events.Type(eventType))
+			}
+			webhook.Secret = os.Getenv(WebhookSecretEnv)
+			webhook.MaxAttempts = opts.WebhookMaxAttempts
+			webhook.Log = deliveries
+			sinks = append(sinks, webhook)
+		}
+	}
+	return sinks, nil
+}
+
+func (opts NonodoOpts) checkEventSinks() []string {
+	var problems []string
+	for _, name := range opts.EventSinks {
+		switch name {
+		case EventSinkStdout:
+		case EventSinkNone:
+			if len(opts.EventSinks) > 1 {
+				problems = append(problems, "--event-sinks none can't be used with other sinks")
+			}
+		case EventSinkPostgres:
+			if opts.DatabaseMode() != DbModePostgres {
+				problems = append(problems, "--event-sinks postgres requires --db-mode postgres")
+			}
+		default:
+			if checkWebhookUrl(name) != nil {
+				problems = append(problems, fmt.Sprintf(
+					"invalid value for --event-sinks: %q is not postgres, stdout, none or an http(s) url", name))
+			}
+		}
+	}
+	for _, webhookUrl := range opts.WebhookUrls {
+		if err := checkWebhookUrl(webhookUrl); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid value for --webhook-url: %v", err))
+		}
+	}
+	for _, eventType := range opts.WebhookEvents {
+		if !slices.Contains(events.Types, events.Type(eventType)) {
+			problems = append(problems, fmt.Sprintf("invalid value for --webhook-events: unknown event %q", eventType))
+		}
+	}
+	if opts.WebhookMaxAttempts <= 0 {
+		problems = append(problems, "--webhook-max-attempts must be positive")
+	}
+	return problems
+}
+
+func checkWebhookUrl(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http(s) url", value)
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package nonodo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Format and version of the first line of an export.
+const (
+	ExportFormat  = "nonodo"
+	ExportVersion = 1
+)
+
+type columnKind int
+
+const (
+	textColumn columnKind = iota
+	integerColumn
+	booleanColumn
+)
+
+type exportColumn struct {
+	name string
+	kind columnKind
+}
+
+// Table of the inputs, outputs or proofs moved by export and import.
+type exportTable struct {
+	name    string
+	orderBy string
+	columns []exportColumn
+}
+
+var exportTables = []exportTable{
+	{
+		name:    "convenience_inputs",
+		orderBy: "app_contract, input_index",
+		columns: []exportColumn{
+			{"id", textColumn},
+			{"input_index", integerColumn},
+			{"app_contract", textColumn},
+			{"status", textColumn},
+			{"msg_sender", textColumn},
+			{"payload", textColumn},
+			{"block_number", integerColumn},
+			{"block_timestamp", integerColumn},
+			{"prev_randao", textColumn},
+			{"exception", textColumn},
+			{"espresso_block_number", integerColumn},
+			{"espresso_block_timestamp", integerColumn},
+			{"input_box_index", integerColumn},
+			{"avail_block_number", integerColumn},
+			{"avail_block_timestamp", integerColumn},
+			{"type", textColumn},
+			{"cartesi_transaction_id", textColumn},
+			{"chain_id", textColumn},
+		},
+	},
+	{
+		name:    "vouchers",
+		orderBy: "app_contract, input_index, output_index",
+		columns: []exportColumn{
+			{"destination", textColumn},
+			{"payload", textColumn},
+			{"executed", booleanColumn},
+			{"input_index", integerColumn},
+			{"output_index", integerColumn},
+			{"value", textColumn},
+			{"output_hashes_siblings", textColumn},
+			{"app_contract", textColumn},
+			{"transaction_hash", textColumn},
+			{"proof_output_index", integerColumn},
+			{"is_delegated_call", booleanColumn},
+		},
+	},
+	{
+		name:    "notices",
+		orderBy: "app_contract, input_index, output_index",
+		columns: []exportColumn{
+			{"payload", textColumn},
+			{"input_index", integerColumn},
+			{"output_index", integerColumn},
+			{"app_contract", textColumn},
+			{"output_hashes_siblings", textColumn},
+			{"proof_output_index", integerColumn},
+		},
+	},
+	{
+		name:    "convenience_reports",
+		orderBy: "app_contract, input_index, output_index",
+		columns: []exportColumn{
+			{"output_index", integerColumn},
+			{"payload", textColumn},
+			{"input_index", integerColumn},
+			{"app_contract", textColumn},
+			{"app_id", integerColumn},
+		},
+	},
+}
+
+// First line of an export.
+type exportHeader struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+}
+
+// Line of an export with a row of a table; NULL columns are null.
+type exportRecord struct {
+	Table string                     `json:"table"`
+	Row   map[string]json.RawMessage `json:"row"`
+}
+
+// Write the inputs, outputs and proofs of the migrated database as JSON lines, the same for
+// SQLite and Postgres. Return the number of rows of each table.
+func ExportDatabase(ctx context.Context, db *sqlx.DB, w io.Writer) (map[string]int, error) {
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(exportHeader{Format: ExportFormat, Version: ExportVersion}); err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, table := range exportTables {
+		count, err := table.export(ctx, db, encoder)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", table.name, err)
+		}
+		counts[table.name] = count
+	}
+	return counts, nil
+}
+
+func (t exportTable) export(ctx context.Context, db *sqlx.DB, encoder *json.Encoder) (int, error) {
+	selects := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		selects[i] = column.name
+		if column.kind == integerColumn {
+			// Postgres returns the NUMERIC columns as text
+			selects[i] = fmt.Sprintf("CAST(%s AS BIGINT)", column.name)
+		}
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s ORDER BY %s", strings.Join(selects, ", "), t.name, t.orderBy)
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	count := 0
+	for rows.Next() {
+		values := make([]any, len(t.columns))
+		for i, column := range t.columns {
+			switch column.kind {
+			case integerColumn:
+				values[i] = new(sql.NullInt64)
+			case booleanColumn:
+				values[i] = new(sql.NullBool)
+			default:
+				values[i] = new(sql.NullString)
+			}
+		}
+		if err := rows.Scan(values...); err != nil {
+			return count, err
+		}
+		record := exportRecord{Table: t.name, Row: map[string]json.RawMessage{}}
+		for i, column := range t.columns {
+			var value any
+			switch v := values[i].(type) {
+			case *sql.NullInt64:
+				value = nullable(v.Int64, v.Valid)
+			case *sql.NullBool:
+				value = nullable(v.Bool, v.Valid)
+			case *sql.NullString:
+				value = nullable(v.String, v.Valid)
+			}
+			raw, err := json.Marshal(value)
+			if err != nil {
+				return count, err
+			}
+			record.Row[column.name] = raw
+		}
+		if err := encoder.Encode(record); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, rows.Err()
+}
+
+func nullable[T any](value T, valid bool) any {
+	if !valid {
+		return nil
+	}
+	return value
+}
+
+// Read an export into the migrated database, whose tables must be empty, in a single transaction.
+// Return the number of rows of each table.
+func ImportDatabase(ctx context.Context, db *sqlx.DB, r io.Reader) (map[string]int, error) {
+	for _, table := range exportTables {
+		var count int
+		if err := db.GetContext(ctx, &count, "SELECT COUNT(*) FROM "+table.name); err != nil {
+			return nil, fmt.Errorf("%s: %w", table.name, err)
+		}
+		if count > 0 {
+			return nil, fmt.Errorf("table %s is not empty; import into a new database", table.name)
+		}
+	}
+
+	decoder := json.NewDecoder(r)
+	var header exportHeader
+	if err := decoder.Decode(&header); err != nil {
+		return nil, fmt.Errorf("invalid export header: %w", err)
+	}
+	if header.Format != ExportFormat || header.Version != ExportVersion {
+		return nil, fmt.Errorf("unsupported export %q version %d; expected %q version %d",
+			header.Format, header.Version, ExportFormat, ExportVersion)
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() // nolint: errcheck
+	counts := map[string]int{}
+	for line := 2; ; line++ {
+		var record exportRecord
+		err := decoder.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := importRecord(ctx, tx, record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		counts[record.Table]++
+	}
+	return counts, tx.Commit()
+}
+
+func importRecord(ctx context.Context, tx *sqlx.Tx, record exportRecord) error {
+	var table *exportTable
+	for i := range exportTables {
+		if exportTables[i].name == record.Table {
+			table = &exportTables[i]
+		}
+	}
+	if table == nil {
+		return fmt.Errorf("unknown table %q", record.Table)
+	}
+	var names, placeholders []string
+	var args []any
+	for _, column := range table.columns {
+		raw, ok := record.Row[column.name]
+		if !ok {
+			continue
+		}
+		value, err := column.decode(raw)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", table.name, column.name, err)
+		}
+		names = append(names, column.name)
+		args = append(args, value)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+	if len(record.Row) != len(names) {
+		return fmt.Errorf("unknown columns in %s", table.name)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		table.name, strings.Join(names, ", "), strings.Join(placeholders, ", "))
+	_, err := tx.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (c exportColumn) decode(raw json.RawMessage) (any, error) {
+	if string(raw) == "null" {
+		return nil, nil
+	}
+	var err error
+	switch c.kind {
+	case integerColumn:
+		var value int64
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	case booleanColumn:
+		var value bool
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	default:
+		var value string
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	}
+}
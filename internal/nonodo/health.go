@@ -0,0 +1,109 @@
+package nonodo
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/calindra/nonodo/internal/health"
+	"github.com/calindra/nonodo/internal/rollup"
+	"github.com/calindra/nonodo/internal/sequencers/inputter"
+	"github.com/calindra/nonodo/internal/supervisor"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/jmoiron/sqlx"
+)
+
+func checkDatabaseHealth(db *sqlx.DB) health.Check {
+	return func(ctx context.Context) health.Component {
+		if err := db.PingContext(ctx); err != nil {
+			return health.Fail(err.Error(), nil)
+		}
+		return health.OK(map[string]any{"driver": db.DriverName()})
+	}
+}
+
+// Latest block of the chain at the url.
+func latestBlock(ctx context.Context, url string) (uint64, error) {
+	client, err := ethclient.DialContext(ctx, url)
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+	return client.BlockNumber(ctx)
+}
+
+func checkRpcHealth(url string) health.Check {
+	return func(ctx context.Context) health.Component {
+		details := map[string]any{"url": url}
+		block, err := latestBlock(ctx, url)
+		if err != nil {
+			return health.Fail(err.Error(), details)
+		}
+		details["latestBlock"] = block
+		return health.OK(details)
+	}
+}
+
+// Ready when the inputter is subscribed to the new inputs; the lag is the number of blocks
+// between the head and the last block read.
+func checkInputterHealth(url string, progress *inputter.Progress) health.Check {
+	return func(ctx context.Context) health.Component {
+		block, live := progress.Get()
+		details := map[string]any{"lastBlock": block, "live": live}
+		head, err := latestBlock(ctx, url)
+		if err != nil {
+			return health.Fail(err.Error(), details)
+		}
+		if live {
+			block = head
+			details["lastBlock"] = block
+		}
+		details["lag"] = head - min(block, head)
+		if !live {
+			return health.Fail("the inputter is reading the past inputs or reconnecting", details)
+		}
+		return health.OK(details)
+	}
+}
+
+func checkBackendHealth(backend *rollup.Backend) health.Check {
+	return func(ctx context.Context) health.Component {
+		attached, lastCall := backend.Attached()
+		details := map[string]any{}
+		if !lastCall.IsZero() {
+			details["lastFinish"] = lastCall.UTC().Format(time.RFC3339)
+		}
+		switch {
+		case attached:
+			return health.OK(details)
+		case lastCall.IsZero():
+			return health.Fail("the application hasn't called /finish yet", details)
+		default:
+			return health.Fail(fmt.Sprintf("the application hasn't called /finish for %v",
+				time.Since(lastCall).Round(time.Second)), details)
+		}
+	}
+}
+
+// Ready when every worker, or every named one, is running or stopped.
+func checkWorkersHealth(registry *supervisor.StatusRegistry, names ...string) health.Check {
+	return func(ctx context.Context) health.Component {
+		details := map[string]any{}
+		var notReady []string
+		for _, status := range registry.List() {
+			if len(names) > 0 && !slices.Contains(names, status.Name) {
+				continue
+			}
+			details[status.Name] = status.State
+			if status.State != supervisor.WorkerRunning && status.State != supervisor.WorkerStopped {
+				notReady = append(notReady, fmt.Sprintf("%s is %s", status.Name, status.State))
+			}
+		}
+		if len(notReady) > 0 {
+			return health.Fail(strings.Join(notReady, "; "), details)
+		}
+		return health.OK(details)
+	}
+}
@@ -0,0 +1,70 @@
+package nonodo
+
+import (
+	"context"
+	"embed"
+	"io/fs"
+
+	"github.com/calindra/nonodo/internal/celestia"
+	"github.com/calindra/nonodo/internal/migration"
+	"github.com/calindra/nonodo/internal/paio"
+	"github.com/calindra/nonodo/internal/sequencers/ordering"
+	"github.com/cartesi/rollups-graphql/pkg/convenience"
+	"github.com/jmoiron/sqlx"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Schema changes of the nonodo database, in order.
+// The baseline creates the tables of the nonodo versions before the migrations, so existing
+// databases adopt it; every later change is a file in the migrations directory.
+func Migrations(dialect string) ([]migration.Migration, error) {
+	files, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	changes, err := migration.LoadFS(files, dialect)
+	if err != nil {
+		return nil, err
+	}
+	baseline := migration.Migration{Version: 1, Name: "baseline", Apply: createBaselineTables}
+	return append([]migration.Migration{baseline}, changes...), nil
+}
+
+// Create the migrator of the nonodo database.
+func NewMigrator(db *sqlx.DB) (*migration.Migrator, error) {
+	migrations, err := Migrations(migration.Dialect(db))
+	if err != nil {
+		return nil, err
+	}
+	return migration.NewMigrator(db, migrations)
+}
+
+// Apply the pending migrations; fail if the database was migrated by a newer nonodo.
+func MigrateDatabase(ctx context.Context, db *sqlx.DB) error {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	_, err = migrator.Migrate(ctx)
+	return err
+}
+
+func createBaselineTables(ctx context.Context, db *sqlx.DB) error {
+	container := convenience.NewContainer(*db, false)
+	container.GetInputRepository()
+	container.GetVoucherRepository()
+	container.GetNoticeRepository()
+	container.GetReportRepository()
+	for _, repository := range []interface{ CreateTables() error }{
+		&ordering.DecisionRepository{Db: db},
+		&paio.BatchRepository{Db: db},
+		&celestia.BlobRepository{Db: db},
+	} {
+		if err := repository.CreateTables(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
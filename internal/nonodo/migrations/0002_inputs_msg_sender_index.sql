@@ -0,0 +1,2 @@
+-- the nonce of a sender counts its inputs on every L2 transaction
+CREATE INDEX IF NOT EXISTS idx_convenience_inputs_msg_sender ON convenience_inputs(app_contract, msg_sender);
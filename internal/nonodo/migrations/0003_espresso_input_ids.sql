@@ -0,0 +1,3 @@
+-- the Espresso inputs are identified by the 0x-prefixed transaction id returned by /submit
+UPDATE convenience_inputs SET id = '0x' || id WHERE type = 'Espresso' AND id NOT LIKE '0x%';
+UPDATE ordering_decisions SET input_id = '0x' || input_id WHERE source = 'espresso' AND input_id NOT LIKE '0x%';
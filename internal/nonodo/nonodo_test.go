@@ -223,7 +223,8 @@ func (s *NonodoSuite) setupTest(opts *NonodoOpts) {
 	var workerCtx context.Context
 	workerCtx, s.workerCancel = context.WithCancel(s.ctx)
 
-	w := NewSupervisor(*opts)
+	w, err := NewSupervisor(*opts)
+	s.Require().NoError(err)
 	// gqlw := NewInlineHLGraphQLWorker(*opts)
 	// w.Workers = append([]supervisor.Worker{gqlw}, w.Workers...)
 
@@ -246,7 +247,6 @@ func (s *NonodoSuite) setupTest(opts *NonodoOpts) {
 	s.graphqlClient = graphql.NewClient(graphqlEndpoint, nil)
 
 	inspectEndpoint := fmt.Sprintf("http://%s:%v/", opts.HttpAddress, opts.HttpPort)
-	var err error
 	s.inspectClient, err = inspect.NewClientWithResponses(inspectEndpoint)
 	s.NoError(err)
 }
@@ -0,0 +1,276 @@
+package nonodo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/calindra/nonodo/internal/commons"
+	"github.com/calindra/nonodo/internal/sequencers/ordering"
+	"github.com/calindra/nonodo/internal/supervisor"
+	"github.com/calindra/nonodo/internal/tracing"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/jmoiron/sqlx"
+)
+
+// Time to wait for the database to answer a ping.
+const DatabasePingTimeout = 5 * time.Second
+
+var sequencers = []string{"inputbox", "espresso", "paio"}
+
+// Environment variables of the postgres connection pool.
+var connectionPoolEnv = []string{"DB_MAX_OPEN_CONNS", "DB_MAX_IDLE_CONNS", "DB_CONN_MAX_LIFETIME", "DB_CONN_MAX_IDLE_TIME"}
+
+// Problems found in the options by Validate.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return strings.Join(e.Problems, "; ")
+}
+
+// Group of related checks of the options.
+type validation struct {
+	Name  string
+	check func(opts NonodoOpts) []string
+}
+
+// Checks run by Validate, from the options alone to the ports and the database they use.
+var validations = []validation{
+	{"options", NonodoOpts.checkOptions},
+	{"addresses", NonodoOpts.checkAddresses},
+	{"ports", NonodoOpts.checkPorts},
+	{"database", NonodoOpts.checkDatabase},
+}
+
+// Check the options before creating the supervisor and report every problem found.
+// The error is a *ValidationError.
+func (opts NonodoOpts) Validate() error {
+	var problems []string
+	for _, v := range validations {
+		problems = append(problems, v.check(opts)...)
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// Whether nonodo starts its own Anvil.
+func (opts NonodoOpts) usesDevnet() bool {
+	return opts.RpcUrl == "" && !opts.DisableDevnet
+}
+
+func (opts NonodoOpts) checkOptions() []string {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	// sequencers
+	if !slices.Contains(sequencers, opts.Sequencer) {
+		add("--sequencer must be one of %s", strings.Join(sequencers, ", "))
+	}
+	if opts.AvailEnabled && opts.Sequencer != "inputbox" {
+		add("--avail-enabled can't be used with --sequencer %s", opts.Sequencer)
+	}
+	if opts.DisableAdvance && (opts.AvailEnabled || opts.Sequencer != "inputbox") {
+		add("--disable-advance can't be used with --avail-enabled or --sequencer %s", opts.Sequencer)
+	}
+	if opts.EspressoMock && opts.Sequencer != "espresso" {
+		add("--espresso-mock requires --sequencer espresso")
+	}
+	if opts.EspressoMockBlockTime <= 0 {
+		add("--espresso-mock-block-time must be positive")
+	}
+	if opts.AvailEmulator && !opts.AvailEnabled {
+		add("--avail-emulator requires --avail-enabled")
+	}
+	if opts.AvailEmulatorBlockTime <= 0 {
+		add("--avail-emulator-block-time must be positive")
+	}
+	if opts.Sequencer == "paio" && (opts.PaioBatchInterval <= 0 || opts.PaioBatchSize <= 0) {
+		add("--paio-batch-interval and --paio-batch-size must be positive")
+	}
+	if opts.OrderingPolicy != "" {
+		policy, err := ordering.ParsePolicy(opts.OrderingPolicy)
+		if err != nil {
+			add("invalid value for --ordering-policy: %v", err)
+		}
+		if !opts.AvailEnabled && opts.Sequencer != "espresso" && opts.Sequencer != "paio" {
+			add("--ordering-policy requires --sequencer espresso, --sequencer paio or --avail-enabled")
+		}
+		if opts.AvailEnabled && policy == ordering.PolicyL1Finalized {
+			add("--ordering-policy l1-finalized is not supported by Avail; its blocks don't reference the L1")
+		}
+	}
+	if _, err := opts.LoadSchemaRegistry(); err != nil {
+		add("invalid --transaction-schemas: %v", err)
+	}
+
+	// devnet and L1
+	if opts.DisableDevnet && opts.RpcUrl == "" && (!opts.DisableAdvance || opts.EpochBlocks > 0) {
+		add("--disable-devnet requires --rpc-url, unless --disable-advance and --epoch-blocks 0 are set")
+	}
+	if opts.AnvilForkUrl != "" && !opts.usesDevnet() {
+		add("--anvil-fork-url can't be used with --rpc-url or --disable-devnet")
+	}
+	if opts.DeploymentFile != "" {
+		if _, err := opts.LoadAddressBook(); err != nil {
+			add("invalid --contracts-deployment-file: %v", err)
+		}
+	}
+	if opts.EpochBlocks < 0 {
+		add("--epoch-blocks can't be negative")
+	}
+
+	// application
+	if opts.EnableEcho && len(opts.ApplicationArgs) > 0 {
+		add("can't use built-in echo with custom application")
+	}
+	if len(opts.WatchPatterns) > 0 && len(opts.ApplicationArgs) == 0 {
+		add("--watch requires an application; pass it after --")
+	}
+	if _, err := supervisor.ParseRestartMode(opts.AppRestartPolicy); err != nil {
+		add("invalid value for --app-restart-policy: %v", err)
+	}
+	if opts.AppMaxRestarts < 0 {
+		add("--app-max-restarts can't be negative")
+	}
+	if opts.AppLogLines <= 0 {
+		add("--app-log-lines must be positive")
+	}
+
+	// timeouts
+	if opts.TimeoutInspect <= 0 || opts.TimeoutAdvance <= 0 || opts.TimeoutWorker <= 0 {
+		add("--sm-deadline-inspect-state, --sm-deadline-advance-state and --timeout-worker must be positive")
+	}
+	if opts.TimeoutAnvil <= 0 || opts.TimeoutListener <= 0 {
+		add("--timeout-anvil and --timeout-listener must be positive")
+	}
+
+	// database
+	if opts.DbImplementation != "sqlite" && opts.DbImplementation != "postgres" {
+		add("--db-implementation must be sqlite or postgres")
+	}
+	if opts.DbMode != "" && !slices.Contains(dbModes, opts.DbMode) {
+		add("--db-mode must be one of %s", strings.Join(dbModes, ", "))
+	}
+	if opts.DbMode != "" && opts.DbMode != DbModePostgres && opts.DbImplementation == "postgres" {
+		add("--db-mode %s can't be used with --db-implementation postgres", opts.DbMode)
+	}
+	if opts.SqliteFile != "" && opts.DatabaseMode() != DbModeFile {
+		add("--sqlite-file requires --db-mode file")
+	}
+	if opts.RawEnabled {
+		add("--raw-enabled is not supported")
+	}
+
+	// tracing
+	if !slices.Contains(tracing.Exporters, opts.TraceExporter) {
+		add("--trace-exporter must be one of %s", strings.Join(tracing.Exporters, ", "))
+	}
+	if opts.TraceExporter == tracing.ExporterFile && opts.TraceFile == "" {
+		add("--trace-exporter file requires --trace-file")
+	}
+	return append(problems, opts.checkEventSinks()...)
+}
+
+func (opts NonodoOpts) checkAddresses() []string {
+	var problems []string
+	for _, address := range []struct{ flag, value string }{
+		{"contracts-application-address", opts.ApplicationAddress},
+		{"contracts-input-box-address", opts.InputBoxAddress},
+	} {
+		bytes, err := hexutil.Decode(address.value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("invalid address for --%v: %v", address.flag, err))
+		} else if len(bytes) != common.AddressLength {
+			problems = append(problems, fmt.Sprintf("invalid address for --%v: wrong length", address.flag))
+		}
+	}
+	return problems
+}
+
+// Port nonodo listens on and its flag.
+type listenPort struct {
+	flag string
+	port int
+}
+
+func (opts NonodoOpts) listenPorts() []listenPort {
+	ports := []listenPort{
+		{"http-port", opts.HttpPort},
+		{"http-rollups-port", opts.HttpRollupsPort},
+	}
+	if opts.usesDevnet() {
+		ports = append(ports, listenPort{"anvil-port", opts.AnvilPort})
+	}
+	if opts.AvailEnabled && opts.AvailEmulator {
+		ports = append(ports, listenPort{"avail-emulator-port", opts.AvailEmulatorPort})
+	}
+	return ports
+}
+
+func (opts NonodoOpts) checkPorts() []string {
+	var problems []string
+	used := map[int]string{}
+	for _, p := range opts.listenPorts() {
+		if p.port <= 0 || p.port > 65535 { // nolint
+			problems = append(problems, fmt.Sprintf("--%s must be between 1 and 65535", p.flag))
+			continue
+		}
+		if other, ok := used[p.port]; ok {
+			problems = append(problems, fmt.Sprintf("--%s and --%s use the same port %d", other, p.flag, p.port))
+			continue
+		}
+		used[p.port] = p.flag
+		if commons.IsPortInUse(p.port) {
+			problems = append(problems, fmt.Sprintf("--%s %d is already in use", p.flag, p.port))
+		}
+	}
+	return problems
+}
+
+func (opts NonodoOpts) checkDatabase() []string {
+	if opts.DbImplementation != "sqlite" && opts.DbImplementation != "postgres" {
+		return nil // reported by checkOptions
+	}
+	switch opts.DatabaseMode() {
+	case DbModePostgres:
+		connectionString, _ := postgresConnectionString()
+		db, err := sqlx.Open("postgres", connectionString)
+		if err != nil {
+			return []string{fmt.Sprintf("invalid postgres connection: %v", err)}
+		}
+		defer db.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), DatabasePingTimeout)
+		defer cancel()
+		if err := db.PingContext(ctx); err != nil {
+			return []string{fmt.Sprintf("postgres is not reachable: %v", err)}
+		}
+		for _, name := range connectionPoolEnv {
+			if value, ok := os.LookupEnv(name); ok {
+				if _, err := strconv.Atoi(value); err != nil {
+					return []string{fmt.Sprintf("%s must be an integer: %q", name, value)}
+				}
+			}
+		}
+	case DbModeFile:
+		if opts.SqliteFile == "" {
+			return nil
+		}
+		dir := filepath.Dir(opts.SqliteFile)
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return []string{fmt.Sprintf("the directory of --sqlite-file does not exist: %s", dir)}
+		}
+	}
+	return nil
+}
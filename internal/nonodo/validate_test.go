@@ -0,0 +1,237 @@
+package nonodo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/calindra/nonodo/internal/supervisor"
+	"github.com/stretchr/testify/suite"
+)
+
+type ValidateSuite struct {
+	suite.Suite
+	opts NonodoOpts
+}
+
+func TestValidateSuite(t *testing.T) {
+	suite.Run(t, new(ValidateSuite))
+}
+
+func (s *ValidateSuite) SetupTest() {
+	s.opts = NewNonodoOpts()
+	s.opts.HttpPort = s.freePort()
+	s.opts.HttpRollupsPort = s.freePort()
+	s.opts.AnvilPort = s.freePort()
+}
+
+func (s *ValidateSuite) freePort() int {
+	listener, err := net.Listen("tcp", ":0")
+	s.Require().NoError(err)
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *ValidateSuite) problems() []string {
+	err := s.opts.Validate()
+	if err == nil {
+		return nil
+	}
+	var validationErr *ValidationError
+	s.Require().True(errors.As(err, &validationErr))
+	return validationErr.Problems
+}
+
+func (s *ValidateSuite) TestTheDefaultsAreValid() {
+	s.NoError(s.opts.Validate())
+}
+
+func (s *ValidateSuite) TestTheForkIsReadFromTheInputBoxDeployment() {
+	s.opts.AnvilForkUrl = "https://example.com/rpc"
+	_, ok := s.opts.ForkInputBoxBlock()
+	s.False(ok)
+
+	s.opts.AnvilForkBlock = 6000000 // nolint
+	block, ok := s.opts.ForkInputBoxBlock()
+	s.True(ok)
+	s.Equal(uint64(6000000), block) // nolint
+
+	s.opts.DeploymentFile = filepath.Join(s.T().TempDir(), "sepolia.json")
+	content := `{"contracts": {
+		"InputBox": {"address": "0x593E5BCf894D6829Dd26D0810DA7F064406aebB6", "blockNumber": 5500000},
+		"ApplicationFactory": {"address": "0xd7d4d184b82b1a4e08f304DDaB0A2A7a301C2620"},
+		"AuthorityFactory": {"address": "0xB897F7Fe78f220aE34B7FA9493092701a873Ed45"}
+	}}`
+	s.Require().NoError(os.WriteFile(s.opts.DeploymentFile, []byte(content), 0644)) // nolint
+	block, ok = s.opts.ForkInputBoxBlock()
+	s.True(ok)
+	s.Equal(uint64(5500000), block) // nolint
+}
+
+func (s *ValidateSuite) TestTheAppIsNotRestartedByDefault() {
+	mode, err := supervisor.ParseRestartMode(s.opts.AppRestartPolicy)
+	s.Require().NoError(err)
+	s.Equal(supervisor.RestartNever, mode)
+}
+
+func (s *ValidateSuite) TestItReportsEveryProblem() {
+	s.opts.Sequencer = "paio"
+	s.opts.AvailEnabled = true
+	s.opts.EspressoMock = true
+	s.opts.OrderingPolicy = "fifo"
+	s.opts.WatchPatterns = []string{"*.py"}
+	s.opts.RawEnabled = true
+	s.Equal([]string{
+		"--avail-enabled can't be used with --sequencer paio",
+		"--espresso-mock requires --sequencer espresso",
+		`invalid value for --ordering-policy: invalid ordering policy "fifo"; expected l1-finalized, timestamp or l1-first`,
+		"--watch requires an application; pass it after --",
+		"--raw-enabled is not supported",
+	}, s.problems())
+}
+
+func (s *ValidateSuite) TestItChecksTheDevnet() {
+	s.opts.DisableDevnet = true
+	s.Equal([]string{
+		"--disable-devnet requires --rpc-url, unless --disable-advance and --epoch-blocks 0 are set",
+	}, s.problems())
+
+	s.opts.DisableAdvance = true
+	s.opts.EpochBlocks = 0
+	s.Empty(s.problems())
+}
+
+func (s *ValidateSuite) TestItChecksTheAddresses() {
+	s.opts.ApplicationAddress = "0x1234"
+	s.opts.InputBoxAddress = "InputBox"
+	s.Equal([]string{
+		"invalid address for --contracts-application-address: wrong length",
+		"invalid address for --contracts-input-box-address: hex string without 0x prefix",
+	}, s.problems())
+}
+
+func (s *ValidateSuite) TestItChecksThePorts() {
+	listener, err := net.Listen("tcp", ":0")
+	s.Require().NoError(err)
+	defer listener.Close()
+	s.opts.HttpPort = listener.Addr().(*net.TCPAddr).Port
+	s.opts.HttpRollupsPort = s.opts.AnvilPort
+	s.Equal([]string{
+		fmt.Sprintf("--http-port %d is already in use", s.opts.HttpPort),
+		fmt.Sprintf("--http-rollups-port and --anvil-port use the same port %d", s.opts.AnvilPort),
+	}, s.problems())
+
+	// the anvil port is not used with an external chain
+	s.opts.RpcUrl = "http://localhost:8545"
+	s.opts.HttpRollupsPort = 0
+	s.Contains(s.problems(), "--http-rollups-port must be between 1 and 65535")
+	s.Len(s.problems(), 2) // nolint
+}
+
+func (s *ValidateSuite) TestItChecksTheDatabase() {
+	s.opts.SqliteFile = "/nonexistent/nonodo.sqlite3"
+	s.Equal([]string{"the directory of --sqlite-file does not exist: /nonexistent"}, s.problems())
+
+	s.opts.DbImplementation = "mysql"
+	s.Equal([]string{"--db-implementation must be sqlite or postgres"}, s.problems())
+
+	s.opts.DbImplementation = "sqlite"
+	s.opts.SqliteFile = ""
+	s.opts.DbMode = "disk"
+	s.Equal([]string{"--db-mode must be one of memory, file, postgres"}, s.problems())
+
+	s.opts.DbMode = DbModeMemory
+	s.opts.SqliteFile = filepath.Join(s.T().TempDir(), "nonodo.sqlite3")
+	s.Equal([]string{"--sqlite-file requires --db-mode file"}, s.problems())
+}
+
+func (s *ValidateSuite) TestItChecksTheEventSinks() {
+	s.opts.EventSinks = []string{"stdout", "https://example.com/hook"}
+	s.Empty(s.problems())
+
+	s.opts.EventSinks = []string{"postgres", "none", "ftp://example.com"}
+	s.Equal([]string{
+		"--event-sinks postgres requires --db-mode postgres",
+		"--event-sinks none can't be used with other sinks",
+		`invalid value for --event-sinks: "ftp://example.com" is not postgres, stdout, none or an http(s) url`,
+	}, s.problems())
+
+	s.opts.EventSinks = nil
+	s.Empty(s.opts.eventSinkNames())
+	s.opts.DbMode = DbModePostgres
+	s.Equal([]string{EventSinkPostgres}, s.opts.eventSinkNames())
+}
+
+func (s *ValidateSuite) TestTheStdoutSinkMovesTheLogsToStderr() {
+	s.False(s.opts.LogsToStderr())
+	s.opts.EventSinks = []string{"https://example.com/hook", EventSinkStdout}
+	s.True(s.opts.LogsToStderr())
+}
+
+func (s *ValidateSuite) TestItChecksTheWebhooks() {
+	s.opts.WebhookUrls = []string{"http://localhost:9000/hook"}
+	s.opts.WebhookEvents = []string{"input_finished", "voucher_executed"}
+	s.Empty(s.problems())
+
+	s.opts.WebhookUrls = []string{"localhost:9000"}
+	s.opts.WebhookEvents = []string{"input_removed"}
+	s.opts.WebhookMaxAttempts = 0
+	s.Equal([]string{
+		`invalid value for --webhook-url: "localhost:9000" is not an http(s) url`,
+		`invalid value for --webhook-events: unknown event "input_removed"`,
+		"--webhook-max-attempts must be positive",
+	}, s.problems())
+}
+
+func (s *ValidateSuite) TestItChecksTheTracing() {
+	s.opts.TraceExporter = "file"
+	s.Empty(s.problems())
+
+	s.opts.TraceFile = ""
+	s.Equal([]string{"--trace-exporter file requires --trace-file"}, s.problems())
+
+	s.opts.TraceExporter = "jaeger"
+	s.Equal([]string{"--trace-exporter must be one of none, otlp, file"}, s.problems())
+}
+
+func (s *ValidateSuite) TestItChecksTheOrderingPolicy() {
+	s.opts.OrderingPolicy = "l1-first"
+	s.Equal([]string{
+		"--ordering-policy requires --sequencer espresso, --sequencer paio or --avail-enabled",
+	}, s.problems())
+
+	s.opts.Sequencer = "paio"
+	s.Empty(s.problems())
+	s.opts.OrderingPolicy = "l1-finalized"
+	s.Empty(s.problems())
+}
+
+func (s *ValidateSuite) TestItChecksTheWorkerTimeouts() {
+	s.opts.TimeoutAnvil = 0
+	s.Equal([]string{"--timeout-anvil and --timeout-listener must be positive"}, s.problems())
+
+	s.opts.TimeoutAnvil = time.Minute
+	s.opts.TimeoutListener = -time.Second
+	s.Equal([]string{"--timeout-anvil and --timeout-listener must be positive"}, s.problems())
+}
+
+func (s *ValidateSuite) TestTheDoctorReportsEachGroup() {
+	s.opts.Sequencer = "unknown"
+	checks := s.opts.Doctor(context.Background())
+	statuses := map[string]CheckStatus{}
+	for _, check := range checks {
+		statuses[check.Name] = check.Status
+	}
+	s.Equal(CheckFail, statuses["options"])
+	s.Equal(CheckOK, statuses["addresses"])
+	s.Equal(CheckOK, statuses["ports"])
+	s.Equal(CheckOK, statuses["database"])
+	s.Equal(CheckOK, statuses["anvil"])
+	s.Contains(statuses, "environment")
+	s.True(HasFailures(checks))
+}
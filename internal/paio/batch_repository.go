@@ -0,0 +1,140 @@
+package paio
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Batch built by the local sequencer.
+type Batch struct {
+	// Keccak-256 of the batch in the Paio wire format.
+	ID             string         `json:"id"`
+	BlockNumber    uint64         `json:"blockNumber"`
+	BlockTimestamp time.Time      `json:"blockTimestamp"`
+	Transactions   []BatchedInput `json:"transactions"`
+}
+
+// Transaction of a batch and the input created for it.
+type BatchedInput struct {
+	TxID       string `json:"txId"`
+	InputIndex int    `json:"inputIndex"`
+}
+
+// Store the batches built by the local sequencer and the inputs of each one.
+type BatchRepository struct {
+	Db *sqlx.DB
+}
+
+func (r *BatchRepository) CreateTables() error {
+	schema := `CREATE TABLE IF NOT EXISTS paio_batches (
+		id              text NOT NULL PRIMARY KEY,
+		block_number    integer,
+		block_timestamp integer);
+
+	CREATE TABLE IF NOT EXISTS paio_batch_inputs (
+		batch_id    text NOT NULL,
+		position    integer NOT NULL,
+		tx_id       text NOT NULL,
+		input_index integer NOT NULL,
+		PRIMARY KEY (batch_id, position));
+
+	CREATE INDEX IF NOT EXISTS idx_paio_batch_inputs_tx_id ON paio_batch_inputs(tx_id);`
+	_, err := r.Db.Exec(schema)
+	if err == nil {
+		slog.Debug("Paio batches table created")
+	} else {
+		slog.Error("Create table error", "error", err)
+	}
+	return err
+}
+
+func (r *BatchRepository) Create(ctx context.Context, batch Batch) error {
+	tx, err := r.Db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	_, err = tx.ExecContext(ctx, `INSERT INTO paio_batches (
+		id,
+		block_number,
+		block_timestamp) VALUES ($1, $2, $3)`,
+		batch.ID,
+		batch.BlockNumber,
+		batch.BlockTimestamp.Unix(),
+	)
+	if err != nil {
+		return err
+	}
+	for position, input := range batch.Transactions {
+		_, err = tx.ExecContext(ctx, `INSERT INTO paio_batch_inputs (
+			batch_id,
+			position,
+			tx_id,
+			input_index) VALUES ($1, $2, $3, $4)`,
+			batch.ID,
+			position,
+			input.TxID,
+			input.InputIndex,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Return the batches from the oldest to the newest.
+func (r *BatchRepository) FindAll(ctx context.Context) ([]Batch, error) {
+	rows, err := r.Db.QueryxContext(ctx, `
+		SELECT b.id, b.block_number, b.block_timestamp, i.tx_id, i.input_index
+		FROM paio_batches b JOIN paio_batch_inputs i ON i.batch_id = b.id
+		ORDER BY i.input_index`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	batches := []Batch{}
+	for rows.Next() {
+		var id string
+		var blockNumber, blockTimestamp int64
+		var input BatchedInput
+		err := rows.Scan(&id, &blockNumber, &blockTimestamp, &input.TxID, &input.InputIndex)
+		if err != nil {
+			return nil, err
+		}
+		if len(batches) == 0 || batches[len(batches)-1].ID != id {
+			batches = append(batches, Batch{
+				ID:             id,
+				BlockNumber:    uint64(blockNumber),
+				BlockTimestamp: time.Unix(blockTimestamp, 0),
+			})
+		}
+		last := &batches[len(batches)-1]
+		last.Transactions = append(last.Transactions, input)
+	}
+	return batches, rows.Err()
+}
+
+// Find the id of the batch that contains the transaction.
+// Return nil if the transaction was not batched.
+func (r *BatchRepository) FindBatchIDByTxID(ctx context.Context, txID string) (*string, error) {
+	var batchID string
+	err := r.Db.QueryRowxContext(ctx,
+		`SELECT batch_id FROM paio_batch_inputs WHERE tx_id = $1 LIMIT 1`,
+		txID,
+	).Scan(&batchID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &batchID, nil
+}
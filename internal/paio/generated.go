@@ -17,11 +17,24 @@ import (
 	"github.com/oapi-codegen/runtime"
 )
 
+// Defines values for TransactionStatusStatus.
+const (
+	Pending   TransactionStatusStatus = "pending"
+	Processed TransactionStatusStatus = "processed"
+	Sequenced TransactionStatusStatus = "sequenced"
+)
+
 // Cartesi712 defines model for Cartesi712.
 type Cartesi712 struct {
+	// Address Signer of the transaction. Required for smart contract wallets,
+	// whose signatures are checked with EIP-1271 isValidSignature
 	Address   *string `json:"address,omitempty"`
 	Signature *string `json:"signature,omitempty"`
-	TypedData *struct {
+
+	// SignatureScheme eip712 (default) signs the typed data hash;
+	// eip191 signs the typed data hash with personal_sign
+	SignatureScheme *string `json:"signatureScheme,omitempty"`
+	TypedData       *struct {
 		Account *string `json:"account,omitempty"`
 		Domain  struct {
 			ChainId           *int    `json:"chainId,omitempty"`
@@ -98,6 +111,59 @@ type TransactionResponse struct {
 	Id *string `json:"id,omitempty"`
 }
 
+// TransactionSchemaResponse defines model for TransactionSchemaResponse.
+type TransactionSchemaResponse struct {
+	// Domain EIP-712 domain with the chain id of the running chain
+	Domain *map[string]interface{} `json:"domain,omitempty"`
+
+	// Payload How the message becomes the payload of the input (data, abi or json)
+	Payload     *string `json:"payload,omitempty"`
+	PrimaryType *string `json:"primaryType,omitempty"`
+
+	// Types EIP-712 types, including EIP712Domain
+	Types *map[string]interface{} `json:"types,omitempty"`
+}
+
+// TransactionStatus defines model for TransactionStatus.
+type TransactionStatus struct {
+	// BatchId Batch of the local sequencer that contains the transaction
+	BatchId *string `json:"batchId,omitempty"`
+
+	// CompletionStatus Completion status of the input
+	CompletionStatus *string `json:"completionStatus,omitempty"`
+
+	// Id Transaction id
+	Id string `json:"id"`
+
+	// InputIndex Index of the input created for the transaction
+	InputIndex *int `json:"inputIndex,omitempty"`
+
+	// Notices Number of notices emitted by the input
+	Notices *int `json:"notices,omitempty"`
+
+	// Reports Number of reports emitted by the input
+	Reports *int `json:"reports,omitempty"`
+
+	// Status pending: waiting for the next batch of the local sequencer;
+	// sequenced: stored as an input that was not processed yet;
+	// processed: the application finished processing the input
+	Status TransactionStatusStatus `json:"status"`
+
+	// Vouchers Number of vouchers emitted by the input
+	Vouchers *int `json:"vouchers,omitempty"`
+}
+
+// TransactionStatusStatus pending: waiting for the next batch of the local sequencer;
+// sequenced: stored as an input that was not processed yet;
+// processed: the application finished processing the input
+type TransactionStatusStatus string
+
+// GetTransactionSchemaParams defines parameters for GetTransactionSchema.
+type GetTransactionSchemaParams struct {
+	// App Application address; without it, the default schema is returned
+	App *string `form:"app,omitempty" json:"app,omitempty"`
+}
+
 // SendCartesiTransactionDeprecatedJSONRequestBody defines body for SendCartesiTransactionDeprecated for application/json ContentType.
 type SendCartesiTransactionDeprecatedJSONRequestBody = Cartesi712
 
@@ -317,10 +383,16 @@ type ClientInterface interface {
 
 	GetNonce(ctx context.Context, body GetNonceJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
 
+	// GetTransactionSchema request
+	GetTransactionSchema(ctx context.Context, params *GetTransactionSchemaParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
 	// SendCartesiTransactionWithBody request with any body
 	SendCartesiTransactionWithBody(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
 
 	SendCartesiTransaction(ctx context.Context, body SendCartesiTransactionJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// GetTransactionStatus request
+	GetTransactionStatus(ctx context.Context, txId string, reqEditors ...RequestEditorFn) (*http.Response, error)
 }
 
 func (c *Client) GetNonceDeprecated(ctx context.Context, reqEditors ...RequestEditorFn) (*http.Response, error) {
@@ -383,6 +455,18 @@ func (c *Client) GetNonce(ctx context.Context, body GetNonceJSONRequestBody, req
 	return c.Client.Do(req)
 }
 
+func (c *Client) GetTransactionSchema(ctx context.Context, params *GetTransactionSchemaParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetTransactionSchemaRequest(c.Server, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
 func (c *Client) SendCartesiTransactionWithBody(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
 	req, err := NewSendCartesiTransactionRequestWithBody(c.Server, contentType, body)
 	if err != nil {
@@ -407,6 +491,18 @@ func (c *Client) SendCartesiTransaction(ctx context.Context, body SendCartesiTra
 	return c.Client.Do(req)
 }
 
+func (c *Client) GetTransactionStatus(ctx context.Context, txId string, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetTransactionStatusRequest(c.Server, txId)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
 // NewGetNonceDeprecatedRequest generates requests for GetNonceDeprecated
 func NewGetNonceDeprecatedRequest(server string) (*http.Request, error) {
 	var err error
@@ -514,6 +610,55 @@ func NewGetNonceRequestWithBody(server string, contentType string, body io.Reade
 	return req, nil
 }
 
+// NewGetTransactionSchemaRequest generates requests for GetTransactionSchema
+func NewGetTransactionSchemaRequest(server string, params *GetTransactionSchemaParams) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/transaction/schema")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if params.App != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "app", runtime.ParamLocationQuery, *params.App); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
 // NewSendCartesiTransactionRequest calls the generic SendCartesiTransaction builder with application/json body
 func NewSendCartesiTransactionRequest(server string, body SendCartesiTransactionJSONRequestBody) (*http.Request, error) {
 	var bodyReader io.Reader
@@ -554,6 +699,40 @@ func NewSendCartesiTransactionRequestWithBody(server string, contentType string,
 	return req, nil
 }
 
+// NewGetTransactionStatusRequest generates requests for GetTransactionStatus
+func NewGetTransactionStatusRequest(server string, txId string) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "txId", runtime.ParamLocationPath, txId)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/transaction/%s", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
 func (c *Client) applyEditors(ctx context.Context, req *http.Request, additionalEditors []RequestEditorFn) error {
 	for _, r := range c.RequestEditors {
 		if err := r(ctx, req); err != nil {
@@ -610,10 +789,16 @@ type ClientWithResponsesInterface interface {
 
 	GetNonceWithResponse(ctx context.Context, body GetNonceJSONRequestBody, reqEditors ...RequestEditorFn) (*GetNonceResponse, error)
 
+	// GetTransactionSchemaWithResponse request
+	GetTransactionSchemaWithResponse(ctx context.Context, params *GetTransactionSchemaParams, reqEditors ...RequestEditorFn) (*GetTransactionSchemaResponse, error)
+
 	// SendCartesiTransactionWithBodyWithResponse request with any body
 	SendCartesiTransactionWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*SendCartesiTransactionResponse, error)
 
 	SendCartesiTransactionWithResponse(ctx context.Context, body SendCartesiTransactionJSONRequestBody, reqEditors ...RequestEditorFn) (*SendCartesiTransactionResponse, error)
+
+	// GetTransactionStatusWithResponse request
+	GetTransactionStatusWithResponse(ctx context.Context, txId string, reqEditors ...RequestEditorFn) (*GetTransactionStatusResponse, error)
 }
 
 type GetNonceDeprecatedResponse struct {
@@ -683,6 +868,29 @@ func (r GetNonceResponse) StatusCode() int {
 	return 0
 }
 
+type GetTransactionSchemaResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *TransactionSchemaResponse
+	JSON400      *TransactionError
+}
+
+// Status returns HTTPResponse.Status
+func (r GetTransactionSchemaResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetTransactionSchemaResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
 type SendCartesiTransactionResponse struct {
 	Body         []byte
 	HTTPResponse *http.Response
@@ -706,6 +914,29 @@ func (r SendCartesiTransactionResponse) StatusCode() int {
 	return 0
 }
 
+type GetTransactionStatusResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *TransactionStatus
+	JSON404      *TransactionError
+}
+
+// Status returns HTTPResponse.Status
+func (r GetTransactionStatusResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetTransactionStatusResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
 // GetNonceDeprecatedWithResponse request returning *GetNonceDeprecatedResponse
 func (c *ClientWithResponses) GetNonceDeprecatedWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*GetNonceDeprecatedResponse, error) {
 	rsp, err := c.GetNonceDeprecated(ctx, reqEditors...)
@@ -749,6 +980,15 @@ func (c *ClientWithResponses) GetNonceWithResponse(ctx context.Context, body Get
 	return ParseGetNonceResponse(rsp)
 }
 
+// GetTransactionSchemaWithResponse request returning *GetTransactionSchemaResponse
+func (c *ClientWithResponses) GetTransactionSchemaWithResponse(ctx context.Context, params *GetTransactionSchemaParams, reqEditors ...RequestEditorFn) (*GetTransactionSchemaResponse, error) {
+	rsp, err := c.GetTransactionSchema(ctx, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetTransactionSchemaResponse(rsp)
+}
+
 // SendCartesiTransactionWithBodyWithResponse request with arbitrary body returning *SendCartesiTransactionResponse
 func (c *ClientWithResponses) SendCartesiTransactionWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*SendCartesiTransactionResponse, error) {
 	rsp, err := c.SendCartesiTransactionWithBody(ctx, contentType, body, reqEditors...)
@@ -766,6 +1006,15 @@ func (c *ClientWithResponses) SendCartesiTransactionWithResponse(ctx context.Con
 	return ParseSendCartesiTransactionResponse(rsp)
 }
 
+// GetTransactionStatusWithResponse request returning *GetTransactionStatusResponse
+func (c *ClientWithResponses) GetTransactionStatusWithResponse(ctx context.Context, txId string, reqEditors ...RequestEditorFn) (*GetTransactionStatusResponse, error) {
+	rsp, err := c.GetTransactionStatus(ctx, txId, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetTransactionStatusResponse(rsp)
+}
+
 // ParseGetNonceDeprecatedResponse parses an HTTP response from a GetNonceDeprecatedWithResponse call
 func ParseGetNonceDeprecatedResponse(rsp *http.Response) (*GetNonceDeprecatedResponse, error) {
 	bodyBytes, err := io.ReadAll(rsp.Body)
@@ -851,6 +1100,39 @@ func ParseGetNonceResponse(rsp *http.Response) (*GetNonceResponse, error) {
 	return response, nil
 }
 
+// ParseGetTransactionSchemaResponse parses an HTTP response from a GetTransactionSchemaWithResponse call
+func ParseGetTransactionSchemaResponse(rsp *http.Response) (*GetTransactionSchemaResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetTransactionSchemaResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest TransactionSchemaResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 400:
+		var dest TransactionError
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON400 = &dest
+
+	}
+
+	return response, nil
+}
+
 // ParseSendCartesiTransactionResponse parses an HTTP response from a SendCartesiTransactionWithResponse call
 func ParseSendCartesiTransactionResponse(rsp *http.Response) (*SendCartesiTransactionResponse, error) {
 	bodyBytes, err := io.ReadAll(rsp.Body)
@@ -884,6 +1166,39 @@ func ParseSendCartesiTransactionResponse(rsp *http.Response) (*SendCartesiTransa
 	return response, nil
 }
 
+// ParseGetTransactionStatusResponse parses an HTTP response from a GetTransactionStatusWithResponse call
+func ParseGetTransactionStatusResponse(rsp *http.Response) (*GetTransactionStatusResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetTransactionStatusResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest TransactionStatus
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 404:
+		var dest TransactionError
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON404 = &dest
+
+	}
+
+	return response, nil
+}
+
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
 	// Get Nonce
@@ -895,9 +1210,15 @@ type ServerInterface interface {
 	// Get Nonce
 	// (POST /transaction/nonce)
 	GetNonce(ctx echo.Context) error
+	// Get the EIP-712 schema of the transactions of an application
+	// (GET /transaction/schema)
+	GetTransactionSchema(ctx echo.Context, params GetTransactionSchemaParams) error
 
 	// (POST /transaction/submit)
 	SendCartesiTransaction(ctx echo.Context) error
+	// Get the status of a transaction
+	// (GET /transaction/{txId})
+	GetTransactionStatus(ctx echo.Context, txId string) error
 }
 
 // ServerInterfaceWrapper converts echo contexts to parameters.
@@ -932,6 +1253,24 @@ func (w *ServerInterfaceWrapper) GetNonce(ctx echo.Context) error {
 	return err
 }
 
+// GetTransactionSchema converts echo context to params.
+func (w *ServerInterfaceWrapper) GetTransactionSchema(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetTransactionSchemaParams
+	// ------------- Optional query parameter "app" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "app", ctx.QueryParams(), &params.App)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter app: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetTransactionSchema(ctx, params)
+	return err
+}
+
 // SendCartesiTransaction converts echo context to params.
 func (w *ServerInterfaceWrapper) SendCartesiTransaction(ctx echo.Context) error {
 	var err error
@@ -941,6 +1280,22 @@ func (w *ServerInterfaceWrapper) SendCartesiTransaction(ctx echo.Context) error
 	return err
 }
 
+// GetTransactionStatus converts echo context to params.
+func (w *ServerInterfaceWrapper) GetTransactionStatus(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "txId" -------------
+	var txId string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "txId", runtime.ParamLocationPath, ctx.Param("txId"), &txId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter txId: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetTransactionStatus(ctx, txId)
+	return err
+}
+
 // This is a simple interface which specifies echo.Route addition functions which
 // are present on both echo.Echo and echo.Group, since we want to allow using
 // either of them for path registration
@@ -972,6 +1327,8 @@ func RegisterHandlersWithBaseURL(router EchoRouter, si ServerInterface, baseURL
 	router.POST(baseURL+"/nonce", wrapper.GetNonceDeprecated)
 	router.POST(baseURL+"/submit", wrapper.SendCartesiTransactionDeprecated)
 	router.POST(baseURL+"/transaction/nonce", wrapper.GetNonce)
+	router.GET(baseURL+"/transaction/schema", wrapper.GetTransactionSchema)
 	router.POST(baseURL+"/transaction/submit", wrapper.SendCartesiTransaction)
+	router.GET(baseURL+"/transaction/:txId", wrapper.GetTransactionStatus)
 
 }
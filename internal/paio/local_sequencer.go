@@ -0,0 +1,484 @@
+package paio
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/calindra/nonodo/internal/metrics"
+	"github.com/calindra/nonodo/internal/sequencers/ordering"
+	"github.com/calindra/nonodo/internal/sequencers/paiodecoder"
+	"github.com/cartesi/rollups-graphql/pkg/commons"
+	"github.com/cartesi/rollups-graphql/pkg/convenience/model"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/labstack/echo/v4"
+)
+
+// Default batching parameters of the local sequencer.
+const (
+	DefaultBatchInterval = 5 * time.Second
+	DefaultBatchSize     = 100
+)
+
+var ErrAlreadySubmitted = errors.New("paio: transaction already submitted")
+
+// Offset added to the recovery id to get the v value of the signature.
+const signatureVOffset = 27
+
+// Model used by the local sequencer to store the batched transactions as inputs.
+type SequencerModel interface {
+	AddSequencedInputs(inputs []model.AdvanceInput) ([]model.AdvanceInput, error)
+}
+
+// Source of the number of inputs already stored for each sender, used as the next nonce.
+type NonceReader interface {
+	GetNonce(ctx context.Context, appContract common.Address, msgSender common.Address) (uint64, error)
+}
+
+// Source of the InputBox inputs merged with the batches.
+type L1InputReader interface {
+	ReadInputs(ctx context.Context, start uint64, end uint64) ([]model.AdvanceInput, error)
+}
+
+// L1 client used to anchor the batches.
+type L1Reader interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	ChainID(ctx context.Context) (*big.Int, error)
+}
+
+// Sequencer that batches L2 transactions locally, like Paio does.
+//
+// The transactions are queued and sealed in a batch every Interval or once there are MaxSize of
+// them. Within a batch, the transactions are sorted by sender, nonce and id, so the order doesn't
+// depend on the arrival time. The nonce of each transaction must follow the stored inputs and the
+// queued transactions of the same sender and application. Each batch is anchored to the latest
+// L1 block when it is sealed and stored through the Orderer, like the blocks of the L2 listeners.
+// When L1Inputs is set, the InputBox inputs up to that block are merged with the batch by the
+// policy of the Orderer; otherwise, the inputter stores them as they come.
+type LocalSequencer struct {
+	Model      SequencerModel
+	Repository *BatchRepository
+	Nonces     NonceReader
+	// EIP-712 schemas of the transactions; nil means the default schema for every app.
+	Schemas  *SchemaRegistry
+	RpcUrl   string
+	Interval time.Duration
+	MaxSize  int
+	// L1 client; dialed from RpcUrl when nil.
+	L1 L1Reader
+	// Stores the batches; it stores them by the L1 block they are anchored to when nil.
+	Orderer *ordering.Orderer
+	// Optional; the InputBox inputs are read from InputBoxBlock on.
+	L1Inputs      L1InputReader
+	InputBoxBlock uint64
+
+	// guards the queue; it is also held while a batch is stored, so the nonces stay consistent
+	mutex   sync.Mutex
+	pending []queuedTransaction
+	queued  map[string]bool
+	seal    chan struct{}
+	// serializes the sealing of batches
+	sealMutex sync.Mutex
+	// next L1 block to read the InputBox inputs from, guarded by sealMutex
+	nextL1Block *uint64
+}
+
+// Transaction waiting for the next batch.
+type queuedTransaction struct {
+	id        string
+	msgSender common.Address
+	tx        paiodecoder.PaioTransaction
+}
+
+func NewLocalSequencer(
+	sequencerModel SequencerModel,
+	repository *BatchRepository,
+	nonces NonceReader,
+	rpcUrl string,
+	interval time.Duration,
+	maxSize int,
+) *LocalSequencer {
+	if interval <= 0 {
+		interval = DefaultBatchInterval
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultBatchSize
+	}
+	return &LocalSequencer{
+		Model:      sequencerModel,
+		Repository: repository,
+		Nonces:     nonces,
+		RpcUrl:     rpcUrl,
+		Interval:   interval,
+		MaxSize:    maxSize,
+		queued:     make(map[string]bool),
+		seal:       make(chan struct{}, 1),
+	}
+}
+
+func (s *LocalSequencer) String() string {
+	return "paio_sequencer"
+}
+
+func (s *LocalSequencer) Start(ctx context.Context, ready chan<- struct{}) error {
+	if s.L1 == nil {
+		client, err := ethclient.DialContext(ctx, s.RpcUrl)
+		if err != nil {
+			return fmt.Errorf("paio: dial: %w", err)
+		}
+		defer client.Close()
+		s.L1 = client
+	}
+	ready <- struct{}{}
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-s.seal:
+			// the batch may have been sealed by the ticker in the meantime
+			if !s.full() {
+				continue
+			}
+		}
+		if _, err := s.SealBatch(ctx); err != nil {
+			slog.Error("paio: failed to seal batch", "error", err)
+		}
+	}
+}
+
+func (s *LocalSequencer) full() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.pending) >= s.MaxSize
+}
+
+// Report whether the transaction is waiting for the next batch.
+func (s *LocalSequencer) IsQueued(txId string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.queued[txId]
+}
+
+// SubmitSigAndData implements Sender by queueing the transaction for the next batch.
+func (s *LocalSequencer) SubmitSigAndData(sigAndData commons.SigAndData) (string, error) {
+	jsonPayload, err := json.Marshal(sigAndData)
+	if err != nil {
+		return "", err
+	}
+	msgSender, _, signature, err := commons.ExtractSigAndData(string(jsonPayload))
+	if err != nil {
+		return "", err
+	}
+	typedDataJSON, err := base64.StdEncoding.DecodeString(sigAndData.TypedData)
+	if err != nil {
+		return "", err
+	}
+	typedData, err := decodeTypedData(typedDataJSON)
+	if err != nil {
+		return "", err
+	}
+	return s.SubmitVerified(&VerifiedSignature{Signer: msgSender, Signature: signature}, typedData)
+}
+
+// SubmitVerified implements VerifiedSender by queueing a transaction whose signature was
+// already checked by the API.
+func (s *LocalSequencer) SubmitVerified(
+	verified *VerifiedSignature,
+	typedData apitypes.TypedData,
+) (string, error) {
+	tx, err := transactionFromMessage(typedData, verified.Signature, s.Schemas)
+	if err != nil {
+		return "", err
+	}
+	msgSender := verified.Signer
+	txId := verified.TransactionId()
+	ctx := context.Background()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	batchId, err := s.Repository.FindBatchIDByTxID(ctx, txId)
+	if err != nil {
+		return "", err
+	}
+	if batchId != nil || s.queued[txId] {
+		return "", fmt.Errorf("%w: %s", ErrAlreadySubmitted, txId)
+	}
+	if s.Nonces != nil {
+		app := common.HexToAddress(tx.App)
+		expected, err := s.Nonces.GetNonce(ctx, app, msgSender)
+		if err != nil {
+			return "", err
+		}
+		for _, queued := range s.pending {
+			if queued.msgSender == msgSender && common.HexToAddress(queued.tx.App) == app {
+				expected++
+			}
+		}
+		if err := checkNonce(tx.Nonce, expected); err != nil {
+			return "", err
+		}
+	}
+	s.queued[txId] = true
+	s.pending = append(s.pending, queuedTransaction{id: txId, msgSender: msgSender, tx: tx})
+	slog.Info("paio: transaction queued", "txId", txId, "msgSender", msgSender, "pending", len(s.pending))
+	if len(s.pending) >= s.MaxSize {
+		select {
+		case s.seal <- struct{}{}:
+		default:
+		}
+	}
+	return txId, nil
+}
+
+// Seal the pending transactions in a batch and add them to the model as inputs, merged
+// with the L1 inputs read so far when L1Inputs is set.
+// Return nil if there are no pending transactions.
+// If the batch can't be sealed, its transactions stay in the queue for the next one.
+func (s *LocalSequencer) SealBatch(ctx context.Context) (*Batch, error) {
+	s.sealMutex.Lock()
+	defer s.sealMutex.Unlock()
+	s.mutex.Lock()
+	pending := slices.Clone(s.pending[:min(len(s.pending), s.MaxSize)])
+	s.mutex.Unlock()
+	if len(pending) == 0 && s.L1Inputs == nil {
+		return nil, nil
+	}
+
+	sortTransactions(pending)
+	header, err := s.L1.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("paio: read latest block: %w", err)
+	}
+	chainId, err := s.L1.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("paio: read chain id: %w", err)
+	}
+	var inputsL1 []model.AdvanceInput
+	if s.L1Inputs != nil {
+		start := s.InputBoxBlock
+		if s.nextL1Block != nil {
+			start = *s.nextL1Block
+		}
+		if start <= header.Number.Uint64() {
+			inputsL1, err = s.L1Inputs.ReadInputs(ctx, start, header.Number.Uint64())
+			if err != nil {
+				return nil, fmt.Errorf("paio: read L1 inputs: %w", err)
+			}
+		}
+	}
+
+	paioBatch := paiodecoder.PaioBatch{SequencerPaymentAddress: common.Address{}.Hex()}
+	inputs := make([]model.AdvanceInput, len(pending))
+	for i, queued := range pending {
+		paioBatch.Txs = append(paioBatch.Txs, queued.tx)
+		inputs[i] = model.AdvanceInput{
+			ID:                     queued.id,
+			MsgSender:              queued.msgSender,
+			Payload:                common.Bytes2Hex(queued.tx.Data),
+			AppContract:            common.HexToAddress(queued.tx.App),
+			BlockNumber:            header.Number.Uint64(),
+			BlockTimestamp:         time.Unix(int64(header.Time), 0),
+			PrevRandao:             header.MixDigest.Hex(),
+			ChainId:                chainId.String(),
+			EspressoBlockNumber:    -1,
+			EspressoBlockTimestamp: time.Unix(-1, 0),
+			InputBoxIndex:          -2,
+			Type:                   "L2",
+		}
+	}
+	encoded, err := paiodecoder.EncodeBatch(paioBatch)
+	if err != nil {
+		return nil, err
+	}
+	batch := Batch{
+		ID:             common.BytesToHash(crypto.Keccak256(encoded)).Hex(),
+		BlockNumber:    header.Number.Uint64(),
+		BlockTimestamp: time.Unix(int64(header.Time), 0),
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	added, held, err := s.orderer().Order(ctx, inputsL1, ordering.L2Block{
+		Source:      metrics.SourceL2,
+		Number:      header.Number.Uint64(),
+		Timestamp:   time.Unix(int64(header.Time), 0),
+		L1Finalized: header.Number.Uint64(),
+		Inputs:      inputs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("paio: add batch %s: %w", batch.ID, err)
+	}
+	if s.L1Inputs != nil {
+		// read the held inputs again with the next batch
+		next := header.Number.Uint64() + 1
+		if len(held) > 0 {
+			next = held[0].BlockNumber
+		}
+		s.nextL1Block = &next
+	}
+	for _, input := range added {
+		if input.InputBoxIndex >= 0 {
+			continue
+		}
+		batch.Transactions = append(batch.Transactions, BatchedInput{
+			TxID:       input.ID,
+			InputIndex: input.Index,
+		})
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+	if len(batch.Transactions) > 0 {
+		if err := s.Repository.Create(ctx, batch); err != nil {
+			return nil, fmt.Errorf("paio: save batch %s: %w", batch.ID, err)
+		}
+	}
+	// the transactions missing from the batch were stored by a batch that failed to be saved
+	sealed := make([]BatchedInput, len(pending))
+	for i, queued := range pending {
+		sealed[i] = BatchedInput{TxID: queued.id}
+	}
+	s.dequeue(sealed)
+	slog.Info("paio: batch sealed", "id", batch.ID, "transactions", len(batch.Transactions),
+		"blockNumber", batch.BlockNumber)
+	return &batch, nil
+}
+
+func (s *LocalSequencer) orderer() *ordering.Orderer {
+	if s.Orderer != nil {
+		return s.Orderer
+	}
+	return ordering.NewOrderer(ordering.PolicyL1Finalized, s.Model, nil)
+}
+
+// Remove the batched transactions from the queue.
+// The caller must hold the mutex.
+func (s *LocalSequencer) dequeue(batched []BatchedInput) {
+	for _, input := range batched {
+		delete(s.queued, input.TxID)
+	}
+	s.pending = slices.DeleteFunc(s.pending, func(queued queuedTransaction) bool {
+		return !s.queued[queued.id]
+	})
+	if len(s.pending) >= s.MaxSize {
+		select {
+		case s.seal <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Sort the transactions by sender, nonce and id.
+func sortTransactions(txs []queuedTransaction) {
+	sort.SliceStable(txs, func(i, j int) bool {
+		if c := bytes.Compare(txs[i].msgSender.Bytes(), txs[j].msgSender.Bytes()); c != 0 {
+			return c < 0
+		}
+		if txs[i].tx.Nonce != txs[j].tx.Nonce {
+			return txs[i].tx.Nonce < txs[j].tx.Nonce
+		}
+		return txs[i].id < txs[j].id
+	})
+}
+
+// Build the Paio transaction from the EIP-712 message and its signature.
+// The data of the transaction is the payload built with the schema of the app.
+func transactionFromMessage(
+	typedData apitypes.TypedData,
+	signature []byte,
+	schemas *SchemaRegistry,
+) (paiodecoder.PaioTransaction, error) {
+	var tx paiodecoder.PaioTransaction
+	message := typedData.Message
+	app, ok := message["app"].(string)
+	if !ok || !common.IsHexAddress(app) {
+		return tx, fmt.Errorf("%w: invalid app in message", ErrInvalidTransaction)
+	}
+	nonce, err := messageUint(message["nonce"])
+	if err != nil {
+		return tx, fmt.Errorf("%w: invalid nonce in message: %w", ErrInvalidTransaction, err)
+	}
+	maxGasPrice, err := messageUint(message["max_gas_price"])
+	if err != nil {
+		return tx, fmt.Errorf("%w: invalid max_gas_price in message: %w", ErrInvalidTransaction, err)
+	}
+	schema := schemas.SchemaOf(common.HexToAddress(app))
+	if err := schema.Validate(typedData); err != nil {
+		return tx, err
+	}
+	data, err := schema.PayloadOf(message)
+	if err != nil {
+		return tx, err
+	}
+	tx.App = common.HexToAddress(app).Hex()
+	tx.Nonce = nonce
+	tx.MaxGasPrice = maxGasPrice
+	tx.Data = data
+	const signatureSize = 65
+	if len(signature) != signatureSize {
+		// The Paio format only carries ECDSA signatures, so the signature of a contract
+		// wallet is kept as its hash.
+		tx.Signature = paiodecoder.PaioSignature{
+			R: "0x" + common.Bytes2Hex(crypto.Keccak256(signature)),
+			S: "0x0",
+			V: "0x0",
+		}
+		return tx, nil
+	}
+	tx.Signature = paiodecoder.PaioSignature{
+		R: "0x" + new(big.Int).SetBytes(signature[0:32]).Text(16),  // nolint
+		S: "0x" + new(big.Int).SetBytes(signature[32:64]).Text(16), // nolint
+		V: "0x" + strconv.FormatUint(uint64(signature[64])%signatureVOffset+signatureVOffset, 16),
+	}
+	return tx, nil
+}
+
+// Read an unsigned integer from the message, which may be a JSON number or a string.
+func messageUint(value interface{}) (uint64, error) {
+	switch v := value.(type) {
+	case float64:
+		if v < 0 || v != float64(uint64(v)) {
+			return 0, fmt.Errorf("invalid number %v", v)
+		}
+		return uint64(v), nil
+	case json.Number:
+		return strconv.ParseUint(v.String(), 10, 64) // nolint
+	case string:
+		if strings.HasPrefix(v, "0x") {
+			return strconv.ParseUint(v[2:], 16, 64) // nolint
+		}
+		return strconv.ParseUint(v, 10, 64) // nolint
+	default:
+		return 0, fmt.Errorf("invalid value %v", value)
+	}
+}
+
+// Register the API that lists the batches built by the local sequencer.
+func RegisterBatches(e *echo.Echo, repository *BatchRepository) {
+	e.GET("/nonodo/batches", func(c echo.Context) error {
+		batches, err := repository.FindAll(c.Request().Context())
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, batches)
+	})
+}
@@ -0,0 +1,386 @@
+package paio
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/calindra/nonodo/internal/sequencers/ordering"
+	"github.com/calindra/nonodo/internal/sequencers/paiodecoder"
+	"github.com/cartesi/rollups-graphql/pkg/commons"
+	"github.com/cartesi/rollups-graphql/pkg/convenience/model"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/ncruces/go-sqlite3/driver"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	"github.com/stretchr/testify/suite"
+)
+
+type LocalSequencerSuite struct {
+	suite.Suite
+	tempDir    string
+	model      *fakeSequencerModel
+	repository *BatchRepository
+	sequencer  *LocalSequencer
+	keys       []*ecdsa.PrivateKey
+	app        common.Address
+}
+
+func TestLocalSequencerSuite(t *testing.T) {
+	suite.Run(t, new(LocalSequencerSuite))
+}
+
+func (s *LocalSequencerSuite) SetupTest() {
+	tempDir, err := os.MkdirTemp("", "")
+	s.Require().NoError(err)
+	s.tempDir = tempDir
+	db := sqlx.MustConnect("sqlite3", filepath.Join(tempDir, "batches.sqlite3"))
+	s.repository = &BatchRepository{Db: db}
+	s.Require().NoError(s.repository.CreateTables())
+	s.model = &fakeSequencerModel{}
+	s.sequencer = NewLocalSequencer(s.model, s.repository, s.model, "", time.Hour, 3) // nolint
+	s.sequencer.L1 = fakeL1Reader{}
+	s.keys = nil
+	for i := 0; i < 2; i++ {
+		key, err := crypto.GenerateKey()
+		s.Require().NoError(err)
+		s.keys = append(s.keys, key)
+	}
+	s.app = common.HexToAddress("0xab7528bb862fb57e8a2bcd567a2e929a0be56a5e")
+}
+
+func (s *LocalSequencerSuite) TearDownTest() {
+	s.NoError(s.repository.Db.Close())
+	os.RemoveAll(s.tempDir)
+}
+
+func (s *LocalSequencerSuite) TestItSealsTheQueuedTransactionsInOrder() {
+	ids := make(map[string]string)
+	submissions := []struct {
+		key   int
+		nonce uint64
+	}{{1, 0}, {0, 0}, {1, 1}}
+	for _, sub := range submissions {
+		txId, err := s.sequencer.SubmitSigAndData(s.sign(s.keys[sub.key], sub.nonce, "0xdeadbeef"))
+		s.Require().NoError(err)
+		ids[fmt.Sprintf("%d-%d", sub.key, sub.nonce)] = txId
+	}
+
+	batch, err := s.sequencer.SealBatch(context.Background())
+	s.Require().NoError(err)
+	s.Require().NotNil(batch)
+	s.Equal(uint64(fakeBlockNumber), batch.BlockNumber)
+	s.Require().Len(s.model.inputs, 3)
+
+	// sorted by sender, then by nonce
+	lo, hi := 0, 1
+	if bytes.Compare(s.address(1).Bytes(), s.address(0).Bytes()) < 0 {
+		lo, hi = 1, 0
+	}
+	var expected []string
+	for _, key := range []int{lo, hi} {
+		for nonce := 0; nonce < 2; nonce++ {
+			if txId, ok := ids[fmt.Sprintf("%d-%d", key, nonce)]; ok {
+				expected = append(expected, txId)
+			}
+		}
+	}
+	for i, input := range s.model.inputs {
+		s.Equal(expected[i], input.ID)
+		s.Equal(i, input.Index)
+		s.Equal("L2", input.Type)
+		s.Equal(s.app, input.AppContract)
+		s.Equal("deadbeef", input.Payload)
+		s.Equal(uint64(fakeBlockNumber), input.BlockNumber)
+		s.Equal(int64(fakeBlockTime), input.BlockTimestamp.Unix())
+		s.Equal("31337", input.ChainId)
+		s.Equal(expected[i], batch.Transactions[i].TxID)
+	}
+
+	batches, err := s.repository.FindAll(context.Background())
+	s.Require().NoError(err)
+	s.Require().Len(batches, 1)
+	s.Equal(batch.ID, batches[0].ID)
+	s.Equal(batch.Transactions, batches[0].Transactions)
+}
+
+func (s *LocalSequencerSuite) TestTheBatchIdIsTheHashOfTheEncodedBatch() {
+	_, err := s.sequencer.SubmitSigAndData(s.sign(s.keys[0], 0, "0x01"))
+	s.Require().NoError(err)
+	batch, err := s.sequencer.SealBatch(context.Background())
+	s.Require().NoError(err)
+
+	input := s.model.inputs[0]
+	batchId, err := s.repository.FindBatchIDByTxID(context.Background(), input.ID)
+	s.Require().NoError(err)
+	s.Require().NotNil(batchId)
+	s.Equal(batch.ID, *batchId)
+	s.Len(batch.ID, 66) // nolint
+}
+
+func (s *LocalSequencerSuite) TestItRejectsDuplicatedTransactions() {
+	sigAndData := s.sign(s.keys[0], 0, "0x01")
+	_, err := s.sequencer.SubmitSigAndData(sigAndData)
+	s.Require().NoError(err)
+	_, err = s.sequencer.SubmitSigAndData(sigAndData)
+	s.ErrorIs(err, ErrAlreadySubmitted)
+
+	_, err = s.sequencer.SealBatch(context.Background())
+	s.Require().NoError(err)
+	_, err = s.sequencer.SubmitSigAndData(sigAndData)
+	s.ErrorIs(err, ErrAlreadySubmitted)
+	s.Len(s.model.inputs, 1)
+}
+
+func (s *LocalSequencerSuite) TestItAcceptsMessagesSharingAContractSignature() {
+	wallet := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	verifier := &SignatureVerifier{Client: &fakeWallet{address: wallet, signature: []byte("approved")}}
+	ids := make(map[string]bool)
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		typedData := paiodecoder.CreateTypedData(s.app, nonce, big.NewInt(10), []byte{1}, big.NewInt(31337)) // nolint
+		data, err := json.Marshal(typedData)
+		s.Require().NoError(err)
+		s.Require().NoError(json.Unmarshal(data, &typedData))
+		verified, err := verifier.Verify(context.Background(), typedData, []byte("approved"), SignatureEIP712, &wallet)
+		s.Require().NoError(err)
+		txId, err := s.sequencer.SubmitVerified(verified, typedData)
+		s.Require().NoError(err)
+		ids[txId] = true
+	}
+	s.Len(ids, 2)
+
+	_, err := s.sequencer.SealBatch(context.Background())
+	s.Require().NoError(err)
+	s.Len(s.model.inputs, 2)
+}
+
+func (s *LocalSequencerSuite) TestItRecordsTheOrderingDecisions() {
+	decisions := &ordering.DecisionRepository{Db: s.repository.Db}
+	s.Require().NoError(decisions.CreateTables())
+	s.sequencer.Orderer = ordering.NewOrderer(ordering.PolicyL1Finalized, s.model, nil)
+	s.sequencer.Orderer.Decisions = decisions
+	_, err := s.sequencer.SubmitSigAndData(s.sign(s.keys[0], 0, "0x01"))
+	s.Require().NoError(err)
+
+	_, err = s.sequencer.SealBatch(context.Background())
+	s.Require().NoError(err)
+	stored, err := decisions.FindAll(context.Background())
+	s.Require().NoError(err)
+	s.Require().Len(stored, 1)
+	s.Equal("l2", stored[0].Source)
+	s.Equal(ordering.PolicyL1Finalized, stored[0].Policy)
+	s.Equal(uint64(fakeBlockNumber), stored[0].L1Block)
+}
+
+func (s *LocalSequencerSuite) TestItMergesTheL1InputsByThePolicy() {
+	l1 := &fakeL1Inputs{inputs: []model.AdvanceInput{
+		{ID: "0", InputBoxIndex: 0, BlockNumber: fakeBlockNumber - 1, AppContract: s.app},
+		{ID: "1", InputBoxIndex: 1, BlockNumber: fakeBlockNumber, AppContract: s.app},
+		{ID: "2", InputBoxIndex: 2, BlockNumber: fakeBlockNumber + 1, AppContract: s.app},
+	}}
+	s.sequencer.L1Inputs = l1
+	s.sequencer.InputBoxBlock = 10 // nolint
+	_, err := s.sequencer.SubmitSigAndData(s.sign(s.keys[0], 0, "0x01"))
+	s.Require().NoError(err)
+
+	batch, err := s.sequencer.SealBatch(context.Background())
+	s.Require().NoError(err)
+	s.Require().NotNil(batch)
+	s.Len(batch.Transactions, 1)
+	s.Equal(2, batch.Transactions[0].InputIndex)
+	s.Require().Len(s.model.inputs, 3)
+	s.Equal("0", s.model.inputs[0].ID)
+	s.Equal("1", s.model.inputs[1].ID)
+	s.Equal([]uint64{10}, l1.starts)
+
+	// nothing is read until the next block
+	_, err = s.sequencer.SealBatch(context.Background())
+	s.Require().NoError(err)
+	s.Equal([]uint64{10}, l1.starts)
+
+	// the L1 inputs are merged even without transactions
+	s.sequencer.L1 = fakeL1Reader{number: fakeBlockNumber + 1}
+	batch, err = s.sequencer.SealBatch(context.Background())
+	s.Require().NoError(err)
+	s.Nil(batch)
+	s.Equal([]uint64{10, fakeBlockNumber + 1}, l1.starts)
+	s.Require().Len(s.model.inputs, 4)
+	s.Equal("2", s.model.inputs[3].ID)
+}
+
+func (s *LocalSequencerSuite) TestItChecksTheNonces() {
+	_, err := s.sequencer.SubmitSigAndData(s.sign(s.keys[0], 1, "0x01"))
+	s.ErrorIs(err, ErrInvalidTransaction)
+	s.ErrorContains(err, "nonce 1 is ahead of the expected nonce 0")
+
+	_, err = s.sequencer.SubmitSigAndData(s.sign(s.keys[0], 0, "0x01"))
+	s.Require().NoError(err)
+	_, err = s.sequencer.SubmitSigAndData(s.sign(s.keys[1], 0, "0x01"))
+	s.Require().NoError(err)
+	// a queued transaction takes its nonce
+	_, err = s.sequencer.SubmitSigAndData(s.sign(s.keys[0], 0, "0x02"))
+	s.ErrorContains(err, "nonce 0 was already used, expected 1")
+	_, err = s.sequencer.SubmitSigAndData(s.sign(s.keys[0], 1, "0x02"))
+	s.Require().NoError(err)
+
+	// and so does a sealed one
+	_, err = s.sequencer.SealBatch(context.Background())
+	s.Require().NoError(err)
+	_, err = s.sequencer.SubmitSigAndData(s.sign(s.keys[0], 1, "0x03"))
+	s.ErrorContains(err, "nonce 1 was already used, expected 2")
+	_, err = s.sequencer.SubmitSigAndData(s.sign(s.keys[0], 2, "0x03"))
+	s.NoError(err)
+}
+
+func (s *LocalSequencerSuite) TestItSealsWhenTheBatchIsFull() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ready := make(chan struct{}, 1)
+	result := make(chan error)
+	go func() {
+		result <- s.sequencer.Start(ctx, ready)
+	}()
+	<-ready
+	for nonce := uint64(0); nonce < 4; nonce++ {
+		_, err := s.sequencer.SubmitSigAndData(s.sign(s.keys[0], nonce, "0x01"))
+		s.Require().NoError(err)
+	}
+	s.Eventually(func() bool {
+		return s.model.count() == 3 // nolint
+	}, time.Second, 10*time.Millisecond) // nolint
+	cancel()
+	s.ErrorIs(<-result, context.Canceled)
+	s.Equal(3, s.model.count()) // nolint
+}
+
+func (s *LocalSequencerSuite) TestItDoesNothingWithoutTransactions() {
+	batch, err := s.sequencer.SealBatch(context.Background())
+	s.NoError(err)
+	s.Nil(batch)
+	s.Empty(s.model.inputs)
+}
+
+func (s *LocalSequencerSuite) address(key int) common.Address {
+	return crypto.PubkeyToAddress(s.keys[key].PublicKey)
+}
+
+func (s *LocalSequencerSuite) sign(key *ecdsa.PrivateKey, nonce uint64, data string) commons.SigAndData {
+	sigAndData, err := signTransaction(key, s.app, nonce, data)
+	s.Require().NoError(err)
+	return sigAndData
+}
+
+// Sign a transaction like the clients of the Paio API do.
+func signTransaction(key *ecdsa.PrivateKey, app common.Address, nonce uint64, data string) (commons.SigAndData, error) {
+	typedData := paiodecoder.CreateTypedData(
+		app,
+		nonce,
+		big.NewInt(10), // nolint
+		common.FromHex(data),
+		big.NewInt(31337), // nolint
+	)
+	// hash the typed data as the clients send it
+	typedDataJSON, err := json.Marshal(typedData)
+	if err != nil {
+		return commons.SigAndData{}, err
+	}
+	if err := json.Unmarshal(typedDataJSON, &typedData); err != nil {
+		return commons.SigAndData{}, err
+	}
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return commons.SigAndData{}, err
+	}
+	signature, err := crypto.Sign(hash, key)
+	if err != nil {
+		return commons.SigAndData{}, err
+	}
+	signature[64] += 27
+	return commons.SigAndData{
+		Signature: fmt.Sprintf("0x%s", common.Bytes2Hex(signature)),
+		TypedData: base64.StdEncoding.EncodeToString(typedDataJSON),
+	}, nil
+}
+
+type fakeSequencerModel struct {
+	mutex  sync.Mutex
+	inputs []model.AdvanceInput
+}
+
+func (m *fakeSequencerModel) AddSequencedInputs(inputs []model.AdvanceInput) ([]model.AdvanceInput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for i := range inputs {
+		inputs[i].Index = len(m.inputs)
+		m.inputs = append(m.inputs, inputs[i])
+	}
+	return inputs, nil
+}
+
+func (m *fakeSequencerModel) GetNonce(ctx context.Context, appContract common.Address, msgSender common.Address) (uint64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var nonce uint64
+	for _, input := range m.inputs {
+		if input.AppContract == appContract && input.MsgSender == msgSender {
+			nonce++
+		}
+	}
+	return nonce, nil
+}
+
+func (m *fakeSequencerModel) count() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return len(m.inputs)
+}
+
+// InputBox with inputs in some blocks; it returns the inputs up to the end block.
+type fakeL1Inputs struct {
+	inputs []model.AdvanceInput
+	starts []uint64
+}
+
+func (f *fakeL1Inputs) ReadInputs(ctx context.Context, start uint64, end uint64) ([]model.AdvanceInput, error) {
+	f.starts = append(f.starts, start)
+	var inputs []model.AdvanceInput
+	for _, input := range f.inputs {
+		if input.BlockNumber >= start && input.BlockNumber <= end {
+			inputs = append(inputs, input)
+		}
+	}
+	return inputs, nil
+}
+
+const (
+	fakeBlockNumber = 42
+	fakeBlockTime   = 1700000000
+)
+
+// L1 at fakeBlockNumber, unless number is set.
+type fakeL1Reader struct {
+	number int64
+}
+
+func (r fakeL1Reader) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if r.number == 0 {
+		return &types.Header{Number: big.NewInt(fakeBlockNumber), Time: fakeBlockTime}, nil
+	}
+	return &types.Header{Number: big.NewInt(r.number), Time: fakeBlockTime}, nil
+}
+
+func (fakeL1Reader) ChainID(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(31337), nil // nolint
+}
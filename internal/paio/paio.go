@@ -6,18 +6,24 @@ import (
 	_ "embed"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"math/big"
 	"net/http"
+	"sync"
 	"time"
 
+	"github.com/calindra/nonodo/internal/metrics"
 	"github.com/calindra/nonodo/internal/sequencers/avail"
+	"github.com/calindra/nonodo/internal/tracing"
 	"github.com/cartesi/rollups-graphql/pkg/commons"
 	"github.com/cartesi/rollups-graphql/pkg/convenience/model"
 	"github.com/cartesi/rollups-graphql/pkg/convenience/repository"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 	"github.com/labstack/echo/v4"
@@ -40,6 +46,20 @@ type PaioAPI struct {
 	chainID         *big.Int
 	ClientSender    Sender
 	paioNonceUrl    string
+	// Expected verifyingContract of the EIP-712 domain of local transactions.
+	VerifyingContract common.Address
+	// Minimum max_gas_price accepted for local transactions.
+	MinGasPrice uint64
+	// EIP-712 schemas of the transactions; nil means the default schema for every app.
+	Schemas *SchemaRegistry
+	// Optional; answers the transaction status requests.
+	Tracker *TransactionTracker
+	// Checks the signatures of the transactions.
+	Verifier *SignatureVerifier
+	// Optional; stores the transactions that aren't batched, so their input_added event is published.
+	Model SequencerModel
+	// serializes the nonce check and the creation of local transactions
+	mutex sync.Mutex
 }
 
 // GetNonceDeprecated implements ServerInterface.
@@ -54,17 +74,51 @@ func (p *PaioAPI) SendCartesiTransactionDeprecated(ctx echo.Context) error {
 	return p.SendCartesiTransaction(ctx)
 }
 
-func (p *PaioAPI) getBlockNumber(ctx context.Context) (uint64, error) {
+// Return the latest block and the chain id of the running chain.
+func (p *PaioAPI) getChainInfo(ctx context.Context) (*types.Header, *big.Int, error) {
 	client, err := ethclient.DialContext(ctx, p.EvmRpcUrl)
 	if err != nil {
-		return 0, fmt.Errorf("ethclient dial error: %w", err)
+		return nil, nil, fmt.Errorf("ethclient dial error: %w", err)
 	}
 	defer client.Close()
-	blockNumber, err := client.BlockNumber(ctx)
+	header, err := client.HeaderByNumber(ctx, nil)
 	if err != nil {
-		return 0, fmt.Errorf("ethclient block_number error: %w", err)
+		return nil, nil, fmt.Errorf("ethclient header error: %w", err)
 	}
-	return blockNumber, nil
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ethclient chain_id error: %w", err)
+	}
+	return header, chainID, nil
+}
+
+// Store the input of a transaction that isn't batched, after the inputs already stored.
+func (p *PaioAPI) saveInput(ctx context.Context, input model.AdvanceInput) error {
+	if p.Model != nil {
+		_, err := p.Model.AddSequencedInputs([]model.AdvanceInput{input})
+		return err
+	}
+	inputCount, err := p.inputRepository.Count(ctx, nil)
+	if err != nil {
+		return err
+	}
+	input.Index = int(inputCount)
+	_, err = p.inputRepository.Create(ctx, input)
+	return err
+}
+
+// Local transactions are checked by nonodo; the remote sequencers check their own.
+func (p *PaioAPI) sequencesLocally() bool {
+	if p.ClientSender == nil {
+		return true
+	}
+	_, ok := p.ClientSender.(*LocalSequencer)
+	return ok
+}
+
+func transactionError(ctx echo.Context, err error) error {
+	errorMessage := err.Error()
+	return ctx.JSON(http.StatusBadRequest, TransactionError{Message: &errorMessage})
 }
 
 func (p *PaioAPI) getNonceFromPaio(user common.Address, app common.Address) (*NonceResponse, error) {
@@ -143,79 +197,170 @@ func (p *PaioAPI) SendCartesiTransaction(ctx echo.Context) error {
 	var request SendCartesiTransactionJSONRequestBody
 	stdCtx, cancel := context.WithCancel(ctx.Request().Context())
 	defer cancel()
-	if err := ctx.Bind(&request); err != nil {
+	stdCtx, span := tracing.Start(stdCtx, "paio.SendCartesiTransaction")
+	defer span.End()
+	body, err := io.ReadAll(ctx.Request().Body)
+	if err != nil {
 		return err
 	}
-	typeJSON, err := json.Marshal(request.TypedData)
-	if err != nil {
-		return fmt.Errorf("error marshalling typed data: %w", err)
+	// the typed data is kept as sent, because its message depends on the schema of the app
+	var rawRequest struct {
+		TypedData json.RawMessage `json:"typedData"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := json.Unmarshal(body, &rawRequest); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if request.Signature == nil || len(rawRequest.TypedData) == 0 {
+		return transactionError(ctx, fmt.Errorf("%w: signature and typedData are required", ErrInvalidTransaction))
 	}
 	sigAndData := commons.SigAndData{
 		Signature: *request.Signature,
-		TypedData: base64.StdEncoding.EncodeToString(typeJSON),
+		TypedData: base64.StdEncoding.EncodeToString(rawRequest.TypedData),
 	}
-	jsonPayload, err := json.Marshal(sigAndData)
+	slog.Debug("/submit", "signature", sigAndData.Signature, "typedData", string(rawRequest.TypedData))
+	typedData, err := decodeTypedData(rawRequest.TypedData)
 	if err != nil {
-		slog.Error("Error json.Marshal message:", "err", err)
-		return err
+		return transactionError(ctx, fmt.Errorf("%w: typed data: %w", ErrInvalidTransaction, err))
 	}
-	slog.Debug("/submit", "jsonPayload", string(jsonPayload))
-	msgSender, _, signature, err := commons.ExtractSigAndData(string(jsonPayload))
+	rawSignature, err := hexutil.Decode(*request.Signature)
 	if err != nil {
-		slog.Error("Error ExtractSigAndData message:", "err", err)
+		return transactionError(ctx, fmt.Errorf("%w: %w", ErrInvalidSignature, err))
+	}
+	scheme := SignatureEIP712
+	if request.SignatureScheme != nil {
+		scheme = *request.SignatureScheme
+	}
+	var signer *common.Address
+	if request.Address != nil {
+		address := common.HexToAddress(*request.Address)
+		signer = &address
+	}
+	verified, err := p.Verifier.Verify(stdCtx, typedData, rawSignature, scheme, signer)
+	if errors.Is(err, ErrInvalidTransaction) || errors.Is(err, ErrInvalidSignature) {
+		return transactionError(ctx, err)
+	}
+	if err != nil {
+		slog.Error("Error verifying the signature:", "err", err)
 		return err
 	}
-	if request.Address != nil && common.HexToAddress(*request.Address) != msgSender {
-		errorMessage := "wrong signature"
-		return ctx.JSON(http.StatusBadRequest, TransactionError{Message: &errorMessage})
+	msgSender := verified.Signer
+	verifiedSender, acceptsVerified := p.ClientSender.(VerifiedSender)
+	if p.ClientSender != nil && !acceptsVerified && (scheme != SignatureEIP712 || verified.Contract) {
+		return transactionError(ctx, fmt.Errorf(
+			"%w: %s and contract signatures require local sequencing", ErrInvalidTransaction, SignatureEIP191))
+	}
+	app, ok := typedData.Message["app"].(string)
+	if !ok || !common.IsHexAddress(app) {
+		return transactionError(ctx, fmt.Errorf("%w: invalid app in message", ErrInvalidTransaction))
 	}
-	appContract := common.HexToAddress(request.TypedData.Message.App[2:])
+	appContract := common.HexToAddress(app)
 	slog.Debug("SaveTransaction",
 		"msgSender", msgSender,
 		"appContract", appContract.Hex(),
-		"message", request.TypedData.Message,
+		"message", typedData.Message,
 	)
-	txId := fmt.Sprintf("0x%s", common.Bytes2Hex(crypto.Keccak256(signature)))
+	schema := p.Schemas.SchemaOf(appContract)
+	if err := schema.Validate(typedData); err != nil {
+		return transactionError(ctx, err)
+	}
+	payloadBytes, err := schema.PayloadOf(typedData.Message)
+	if err != nil {
+		return transactionError(ctx, err)
+	}
+	txId := verified.TransactionId()
+	span.SetAttributes(tracing.AppContractKey.String(appContract.Hex()), tracing.InputIdKey.String(txId))
+	var header *types.Header
+	var chainID *big.Int
+	if p.sequencesLocally() {
+		header, chainID, err = p.getChainInfo(stdCtx)
+		if err != nil {
+			slog.Error("Error reading the chain:", "err", err)
+			return err
+		}
+		rules := transactionRules{
+			ChainID:           chainID,
+			VerifyingContract: p.VerifyingContract,
+			MinGasPrice:       p.MinGasPrice,
+		}
+		if err := rules.validate(typedData); err != nil {
+			return transactionError(ctx, err)
+		}
+	}
+	if !p.sequencesLocally() && schema.Payload != PayloadData {
+		return transactionError(ctx, fmt.Errorf(
+			"%w: the %s payload of the %s schema requires local sequencing", ErrInvalidTransaction, schema.Payload, schema.PrimaryType))
+	}
 	if p.ClientSender != nil {
-		seqTxId, err := p.ClientSender.SubmitSigAndData(sigAndData)
+		var seqTxId string
+		if acceptsVerified {
+			seqTxId, err = verifiedSender.SubmitVerified(verified, typedData)
+		} else {
+			seqTxId, err = p.ClientSender.SubmitSigAndData(sigAndData)
+		}
+		if errors.Is(err, ErrAlreadySubmitted) {
+			errorMessage := err.Error()
+			return ctx.JSON(http.StatusConflict, TransactionError{Message: &errorMessage})
+		}
+		if errors.Is(err, ErrInvalidTransaction) {
+			return transactionError(ctx, err)
+		}
 		if err != nil {
 			return err
 		}
-		slog.Info("Transaction sent to the sequencer", "txId", seqTxId)
+		slog.Info("Transaction sent to the sequencer", "txId", txId, "sequencerTxId", seqTxId)
+		// the listeners store the input under the transaction id, not the id of the sequencer
+		tracing.Inputs.Add(stdCtx, appContract.Hex(), txId)
 		response := TransactionResponse{
 			Id: &txId,
 		}
 		return ctx.JSON(http.StatusCreated, response)
 	}
-	blockNumber, err := p.getBlockNumber(stdCtx)
+	nonce, err := messageUint(typedData.Message["nonce"])
+	if err != nil {
+		return transactionError(ctx, fmt.Errorf("%w: nonce: %w", ErrInvalidTransaction, err))
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	expectedNonce, err := p.inputRepository.GetNonce(stdCtx, appContract, msgSender)
 	if err != nil {
-		slog.Error("Error reading current block number:", "err", err)
+		slog.Error("Error reading nonce:", "err", err)
 		return err
 	}
-	inputCount, err := p.inputRepository.Count(stdCtx, nil)
+	if err := checkNonce(nonce, expectedNonce); err != nil {
+		return transactionError(ctx, err)
+	}
+	existing, err := p.inputRepository.FindByIDAndAppContract(stdCtx, txId, &appContract)
 	if err != nil {
-		slog.Error("Error counting inputs:", "err", err)
+		slog.Error("Error reading input:", "err", err)
 		return err
 	}
-	payload := request.TypedData.Message.Data[2:]
-	_, err = p.inputRepository.Create(stdCtx, model.AdvanceInput{
+	if existing != nil {
+		errorMessage := fmt.Sprintf("%v: %s", ErrAlreadySubmitted, txId)
+		return ctx.JSON(http.StatusConflict, TransactionError{Message: &errorMessage})
+	}
+	payload := common.Bytes2Hex(payloadBytes)
+	input := model.AdvanceInput{
 		ID:             txId,
-		Index:          int(inputCount),
 		MsgSender:      msgSender,
 		Payload:        payload,
 		AppContract:    appContract,
-		BlockNumber:    blockNumber,
-		BlockTimestamp: time.Now(),
+		BlockNumber:    header.Number.Uint64(),
+		BlockTimestamp: time.Unix(int64(header.Time), 0),
 		InputBoxIndex:  -2,
 		Type:           "L2",
-		ChainId:        "31337",
-		PrevRandao:     "0xaabb",
-	})
-	if err != nil {
+		ChainId:        chainID.String(),
+		PrevRandao:     header.MixDigest.Hex(),
+	}
+	if err := p.saveInput(stdCtx, input); err != nil {
 		slog.Error("Error saving input:", "err", err)
 		return err
 	}
-	msg, _ := json.Marshal(request.TypedData.Message)
+	metrics.Inputs.WithLabelValues(metrics.SourceL2).Inc()
+	tracing.Inputs.Add(stdCtx, appContract.Hex(), txId)
+	msg, _ := json.Marshal(typedData.Message)
 	slog.Info("transaction saved",
 		"txId", txId,
 		"msgSender", msgSender,
@@ -229,6 +374,43 @@ func (p *PaioAPI) SendCartesiTransaction(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, response)
 }
 
+// GetTransactionSchema implements ServerInterface.
+func (p *PaioAPI) GetTransactionSchema(ctx echo.Context, params GetTransactionSchemaParams) error {
+	var app common.Address
+	if params.App != nil {
+		if !common.IsHexAddress(*params.App) {
+			errorMessage := "invalid app address"
+			return ctx.JSON(http.StatusBadRequest, TransactionError{Message: &errorMessage})
+		}
+		app = common.HexToAddress(*params.App)
+	}
+	_, chainID, err := p.getChainInfo(ctx.Request().Context())
+	if err != nil {
+		slog.Error("Error reading the chain:", "err", err)
+		return err
+	}
+	schema := p.Schemas.SchemaOf(app)
+	return ctx.JSON(http.StatusOK, schema.SigningSchema(chainID, p.VerifyingContract))
+}
+
+// GetTransactionStatus implements ServerInterface.
+func (p *PaioAPI) GetTransactionStatus(ctx echo.Context, txId string) error {
+	var status *TransactionStatus
+	if p.Tracker != nil {
+		var err error
+		status, err = p.Tracker.Status(ctx.Request().Context(), txId)
+		if err != nil {
+			slog.Error("Error reading transaction status:", "err", err)
+			return err
+		}
+	}
+	if status == nil {
+		errorMessage := fmt.Sprintf("transaction %s not found", txId)
+		return ctx.JSON(http.StatusNotFound, TransactionError{Message: &errorMessage})
+	}
+	return ctx.JSON(http.StatusOK, status)
+}
+
 // Register the Paio API to echo
 func Register(e *echo.Echo, paioServerAPI ServerInterface) {
 	RegisterHandlers(e, paioServerAPI)
@@ -15,6 +15,14 @@ type PaioBuilder struct {
 	EspressoUrl     string
 	PaioServerUrl   string
 	Namespace       uint64
+	LocalSequencer  *LocalSequencer
+	MinGasPrice     uint64
+	Tracker         *TransactionTracker
+	Schemas         *SchemaRegistry
+	// Sender of the transactions; overrides the one chosen by the urls.
+	ClientSender Sender
+	// Model of the transactions stored without a sender.
+	Model SequencerModel
 }
 
 func NewPaioBuilder() *PaioBuilder {
@@ -58,6 +66,36 @@ func (pb *PaioBuilder) WithPaioServerUrl(paioServerUrl string) *PaioBuilder {
 	return pb
 }
 
+func (pb *PaioBuilder) WithLocalSequencer(localSequencer *LocalSequencer) *PaioBuilder {
+	pb.LocalSequencer = localSequencer
+	return pb
+}
+
+func (pb *PaioBuilder) WithMinGasPrice(minGasPrice uint64) *PaioBuilder {
+	pb.MinGasPrice = minGasPrice
+	return pb
+}
+
+func (pb *PaioBuilder) WithTransactionTracker(tracker *TransactionTracker) *PaioBuilder {
+	pb.Tracker = tracker
+	return pb
+}
+
+func (pb *PaioBuilder) WithSchemas(schemas *SchemaRegistry) *PaioBuilder {
+	pb.Schemas = schemas
+	return pb
+}
+
+func (pb *PaioBuilder) WithClientSender(clientSender Sender) *PaioBuilder {
+	pb.ClientSender = clientSender
+	return pb
+}
+
+func (pb *PaioBuilder) WithModel(model SequencerModel) *PaioBuilder {
+	pb.Model = model
+	return pb
+}
+
 func (pb *PaioBuilder) Build() *PaioAPI {
 	var clientSender Sender
 
@@ -65,6 +103,10 @@ func (pb *PaioBuilder) Build() *PaioAPI {
 		clientSender = NewEspressoSender(pb.EspressoUrl, pb.Namespace)
 	}
 
+	if pb.LocalSequencer != nil {
+		clientSender = pb.LocalSequencer
+	}
+
 	paioNonceUrl := ""
 	if pb.PaioServerUrl != "" {
 		slog.Info("Using Paio's server", "url", pb.PaioServerUrl)
@@ -72,6 +114,10 @@ func (pb *PaioBuilder) Build() *PaioAPI {
 		paioNonceUrl = fmt.Sprintf("%s/nonce", pb.PaioServerUrl)
 	}
 
+	if pb.ClientSender != nil {
+		clientSender = pb.ClientSender
+	}
+
 	return &PaioAPI{
 		availClient:     pb.AvalClient,
 		inputRepository: pb.InputRepository,
@@ -79,5 +125,10 @@ func (pb *PaioBuilder) Build() *PaioAPI {
 		ClientSender:    clientSender,
 		chainID:         nil,
 		paioNonceUrl:    paioNonceUrl,
+		MinGasPrice:     pb.MinGasPrice,
+		Tracker:         pb.Tracker,
+		Schemas:         pb.Schemas,
+		Verifier:        &SignatureVerifier{RpcUrl: pb.RpcUrl},
+		Model:           pb.Model,
 	}
 }
@@ -1,16 +1,26 @@
 package paio
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"log/slog"
 	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"github.com/calindra/nonodo/internal/tracing"
 	"github.com/cartesi/rollups-graphql/pkg/commons"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type PaioSuite struct {
@@ -86,3 +96,52 @@ func (p *PaioSuite) TestPaioDecoder() {
 func TestPaioSuite(t *testing.T) {
 	suite.Run(t, new(PaioSuite))
 }
+
+// Sender that identifies the transactions by its own ids, as Espresso and Avail do.
+type fakeRemoteSender struct{}
+
+func (fakeRemoteSender) SubmitSigAndData(sigAndData commons.SigAndData) (string, error) {
+	return "0xsequencer", nil
+}
+
+func (p *PaioSuite) TestTheRemoteTransactionsJoinTheTraceOfTheirInput() {
+	key, err := crypto.GenerateKey()
+	p.Require().NoError(err)
+	app := common.HexToAddress("0xab7528bb862fb57e8a2bcd567a2e929a0be56a5e")
+	sigAndData, err := signTransaction(key, app, 0, "0xdeadbeef")
+	p.Require().NoError(err)
+	typedDataJSON, err := base64.StdEncoding.DecodeString(sigAndData.TypedData)
+	p.Require().NoError(err)
+	var typedData map[string]any
+	p.Require().NoError(json.Unmarshal(typedDataJSON, &typedData))
+	// the clients send the chain id as a number
+	typedData["domain"].(map[string]any)["chainId"] = 31337
+	body, err := json.Marshal(map[string]any{"signature": sigAndData.Signature, "typedData": typedData})
+	p.Require().NoError(err)
+
+	api := NewPaioBuilder().WithClientSender(fakeRemoteSender{}).Build()
+	e := echo.New()
+	Register(e, api)
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+	})
+	request := httptest.NewRequest(http.MethodPost, "/transaction/submit", bytes.NewReader(body))
+	request = request.WithContext(trace.ContextWithSpanContext(request.Context(), spanContext))
+	request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, request)
+	p.Require().Equal(http.StatusCreated, recorder.Code, recorder.Body.String())
+	var response TransactionResponse
+	p.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	defer tracing.Inputs.Remove(app.Hex(), *response.Id)
+
+	signature := common.FromHex(sigAndData.Signature)
+	signature[64] -= 27
+	p.Equal(hexutil.Encode(crypto.Keccak256(signature)), *response.Id)
+	ctx := tracing.Inputs.Context(context.Background(), app.Hex(), *response.Id)
+	p.Equal(spanContext.TraceID(), trace.SpanContextFromContext(ctx).TraceID())
+	ctx = tracing.Inputs.Context(context.Background(), app.Hex(), "0xsequencer")
+	p.False(trace.SpanContextFromContext(ctx).IsValid())
+}
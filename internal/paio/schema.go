@@ -0,0 +1,359 @@
+package paio
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	gomath "math"
+	"math/big"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// How the signed message becomes the payload of the input.
+const (
+	// The payload is the data field of the message.
+	PayloadData = "data"
+	// The payload is the ABI encoding of the application fields, in the order they are declared.
+	PayloadABI = "abi"
+	// The payload is a JSON object with the application fields.
+	PayloadJSON = "json"
+)
+
+// Fields every schema must declare, because the sequencer reads them.
+var sequencerFields = []apitypes.Type{
+	{Name: "app", Type: "address"},
+	{Name: "nonce", Type: "uint64"},
+	{Name: "max_gas_price", Type: "uint128"},
+}
+
+// Standard EIP712Domain type of the transactions.
+var domainType = []apitypes.Type{
+	{Name: "name", Type: "string"},
+	{Name: "version", Type: "string"},
+	{Name: "chainId", Type: "uint256"},
+	{Name: "verifyingContract", Type: "address"},
+}
+
+// EIP-712 domain name and version of a schema.
+type SchemaDomain struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// EIP-712 typed data accepted for the transactions of an application.
+type TransactionSchema struct {
+	// Application that uses the schema; nil for the default schema.
+	App         *common.Address `json:"app,omitempty"`
+	Domain      SchemaDomain    `json:"domain"`
+	PrimaryType string          `json:"primaryType"`
+	// Types of the message, without EIP712Domain.
+	Types apitypes.Types `json:"types"`
+	// One of data, abi or json.
+	Payload string `json:"payload"`
+}
+
+// The CartesiMessage schema, used by the applications that don't declare one.
+func DefaultTransactionSchema() TransactionSchema {
+	return TransactionSchema{
+		Domain:      SchemaDomain{Name: "Cartesi", Version: "0.1.0"},
+		PrimaryType: "CartesiMessage",
+		Types: apitypes.Types{
+			"CartesiMessage": append(slices.Clone(sequencerFields), apitypes.Type{Name: "data", Type: "bytes"}),
+		},
+		Payload: PayloadData,
+	}
+}
+
+// Whether the schema is the CartesiMessage schema, the only one the Paio batches can rebuild.
+func (s TransactionSchema) isDefault() bool {
+	return reflect.DeepEqual(s, DefaultTransactionSchema())
+}
+
+// Check that the schema is complete and can build the payload.
+func (s TransactionSchema) validate() error {
+	fields, ok := s.Types[s.PrimaryType]
+	if !ok {
+		return fmt.Errorf("primary type %q is not declared", s.PrimaryType)
+	}
+	if _, ok := s.Types["EIP712Domain"]; ok {
+		return fmt.Errorf("EIP712Domain is implicit and must not be declared")
+	}
+	for _, required := range sequencerFields {
+		if !slices.Contains(fields, required) {
+			return fmt.Errorf("primary type %s must have the field %s %s", s.PrimaryType, required.Type, required.Name)
+		}
+	}
+	switch s.Payload {
+	case PayloadData:
+		if !slices.Contains(fields, apitypes.Type{Name: "data", Type: "bytes"}) {
+			return fmt.Errorf("payload data requires the field bytes data")
+		}
+	case PayloadABI:
+		for _, field := range s.applicationFields() {
+			if _, err := abi.NewType(field.Type, "", nil); err != nil || s.Types[field.Type] != nil ||
+				strings.HasSuffix(field.Type, "]") {
+				return fmt.Errorf("payload abi doesn't support the field %s %s", field.Type, field.Name)
+			}
+		}
+	case PayloadJSON:
+	default:
+		return fmt.Errorf("unknown payload %q, expected data, abi or json", s.Payload)
+	}
+	return nil
+}
+
+// Fields of the primary type that belong to the application.
+func (s TransactionSchema) applicationFields() []apitypes.Type {
+	var fields []apitypes.Type
+	for _, field := range s.Types[s.PrimaryType] {
+		if !slices.ContainsFunc(sequencerFields, func(t apitypes.Type) bool { return t.Name == field.Name }) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// Check that the typed data was built with this schema.
+func (s TransactionSchema) Validate(typedData apitypes.TypedData) error {
+	if typedData.Domain.Name != s.Domain.Name || typedData.Domain.Version != s.Domain.Version {
+		return fmt.Errorf("%w: domain %s %s should be %s %s", ErrInvalidTransaction,
+			typedData.Domain.Name, typedData.Domain.Version, s.Domain.Name, s.Domain.Version)
+	}
+	if typedData.PrimaryType != s.PrimaryType {
+		return fmt.Errorf("%w: primary type %s should be %s", ErrInvalidTransaction,
+			typedData.PrimaryType, s.PrimaryType)
+	}
+	for name, fields := range s.Types {
+		if !reflect.DeepEqual(typedData.Types[name], fields) {
+			return fmt.Errorf("%w: type %s doesn't match the schema", ErrInvalidTransaction, name)
+		}
+	}
+	return nil
+}
+
+// Build the input payload from the signed message.
+func (s TransactionSchema) PayloadOf(message apitypes.TypedDataMessage) ([]byte, error) {
+	switch s.Payload {
+	case PayloadData:
+		data, ok := message["data"].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: invalid data in message", ErrInvalidTransaction)
+		}
+		return common.FromHex(data), nil
+	case PayloadABI:
+		var arguments abi.Arguments
+		var values []any
+		for _, field := range s.applicationFields() {
+			abiType, err := abi.NewType(field.Type, "", nil)
+			if err != nil {
+				return nil, err
+			}
+			value, err := abiValue(abiType, message[field.Name])
+			if err != nil {
+				return nil, fmt.Errorf("%w: field %s: %w", ErrInvalidTransaction, field.Name, err)
+			}
+			arguments = append(arguments, abi.Argument{Name: field.Name, Type: abiType})
+			values = append(values, value)
+		}
+		return arguments.Pack(values...)
+	default:
+		fields := make(map[string]any)
+		for _, field := range s.applicationFields() {
+			fields[field.Name] = message[field.Name]
+		}
+		return json.Marshal(fields)
+	}
+}
+
+// Decode the typed data of a request, keeping the numbers of the message as json.Number so
+// the large integers keep their precision.
+func decodeTypedData(data []byte) (apitypes.TypedData, error) {
+	var typedData apitypes.TypedData
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&typedData)
+	return typedData, err
+}
+
+// Convert a JSON value of the message to the Go value expected by the ABI encoder.
+func abiValue(abiType abi.Type, value any) (any, error) {
+	switch abiType.T {
+	case abi.IntTy, abi.UintTy:
+		var n *big.Int
+		switch v := value.(type) {
+		case json.Number:
+			var ok bool
+			n, ok = new(big.Int).SetString(v.String(), 10) // nolint
+			if !ok {
+				return nil, fmt.Errorf("invalid integer %s", v)
+			}
+		case float64:
+			if v != gomath.Trunc(v) {
+				return nil, fmt.Errorf("invalid integer %v", v)
+			}
+			n, _ = big.NewFloat(v).Int(nil)
+		case string:
+			var ok bool
+			n, ok = new(big.Int).SetString(v, 0)
+			if !ok {
+				return nil, fmt.Errorf("invalid number %q", v)
+			}
+		default:
+			return nil, fmt.Errorf("invalid number %v", value)
+		}
+		if !inRange(n, abiType) {
+			return nil, fmt.Errorf("number %s out of range of %s", n, abiType)
+		}
+		goType := abiType.GetType()
+		if goType.Kind() == reflect.Ptr {
+			return n, nil
+		}
+		if abiType.T == abi.UintTy {
+			return reflect.ValueOf(n.Uint64()).Convert(goType).Interface(), nil
+		}
+		return reflect.ValueOf(n.Int64()).Convert(goType).Interface(), nil
+	case abi.BoolTy:
+		v, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("invalid bool %v", value)
+		}
+		return v, nil
+	case abi.StringTy:
+		v, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid string %v", value)
+		}
+		return v, nil
+	case abi.AddressTy:
+		v, ok := value.(string)
+		if !ok || !common.IsHexAddress(v) {
+			return nil, fmt.Errorf("invalid address %v", value)
+		}
+		return common.HexToAddress(v), nil
+	case abi.BytesTy:
+		v, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid bytes %v", value)
+		}
+		return common.FromHex(v), nil
+	case abi.FixedBytesTy:
+		v, ok := value.(string)
+		if !ok || len(common.FromHex(v)) != abiType.Size {
+			return nil, fmt.Errorf("invalid bytes%d %v", abiType.Size, value)
+		}
+		array := reflect.New(abiType.GetType()).Elem()
+		reflect.Copy(array, reflect.ValueOf(common.FromHex(v)))
+		return array.Interface(), nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", abiType)
+	}
+}
+
+// Check that n fits the integer type: [0, 2^size) for uint and [-2^(size-1), 2^(size-1)) for int.
+func inRange(n *big.Int, abiType abi.Type) bool {
+	if abiType.T == abi.UintTy {
+		return n.Sign() >= 0 && n.BitLen() <= abiType.Size
+	}
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(abiType.Size-1))
+	return n.Cmp(limit) < 0 && n.Cmp(new(big.Int).Neg(limit)) >= 0
+}
+
+// Schema with the domain and the types ready to be signed, as served to the frontends.
+type SigningSchema struct {
+	Domain      apitypes.TypedDataDomain `json:"domain"`
+	Types       apitypes.Types           `json:"types"`
+	PrimaryType string                   `json:"primaryType"`
+	Payload     string                   `json:"payload"`
+}
+
+func (s TransactionSchema) SigningSchema(chainId *big.Int, verifyingContract common.Address) SigningSchema {
+	types := apitypes.Types{"EIP712Domain": domainType}
+	for name, fields := range s.Types {
+		types[name] = fields
+	}
+	return SigningSchema{
+		Domain: apitypes.TypedDataDomain{
+			Name:              s.Domain.Name,
+			Version:           s.Domain.Version,
+			ChainId:           (*math.HexOrDecimal256)(chainId),
+			VerifyingContract: verifyingContract.Hex(),
+		},
+		Types:       types,
+		PrimaryType: s.PrimaryType,
+		Payload:     s.Payload,
+	}
+}
+
+// Schemas of the transactions of each application.
+type SchemaRegistry struct {
+	mutex         sync.RWMutex
+	defaultSchema TransactionSchema
+	apps          map[common.Address]TransactionSchema
+}
+
+// Create a registry in which every application uses the default schema.
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{
+		defaultSchema: DefaultTransactionSchema(),
+		apps:          make(map[common.Address]TransactionSchema),
+	}
+}
+
+// Load the schemas from a JSON file with a list of schemas.
+// A schema without app replaces the default schema.
+func LoadSchemaRegistry(path string) (*SchemaRegistry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("paio: read schemas: %w", err)
+	}
+	var file struct {
+		Schemas []TransactionSchema `json:"schemas"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("paio: parse schemas %s: %w", path, err)
+	}
+	registry := NewSchemaRegistry()
+	for _, schema := range file.Schemas {
+		if err := registry.Register(schema); err != nil {
+			return nil, fmt.Errorf("paio: schemas %s: %w", path, err)
+		}
+	}
+	return registry, nil
+}
+
+// Add the schema to the registry, replacing the previous one of the application.
+func (r *SchemaRegistry) Register(schema TransactionSchema) error {
+	if err := schema.validate(); err != nil {
+		return fmt.Errorf("schema %s: %w", schema.PrimaryType, err)
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if schema.App == nil {
+		r.defaultSchema = schema
+	} else {
+		r.apps[*schema.App] = schema
+	}
+	return nil
+}
+
+// Return the schema of the application.
+func (r *SchemaRegistry) SchemaOf(app common.Address) TransactionSchema {
+	if r == nil {
+		return DefaultTransactionSchema()
+	}
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if schema, ok := r.apps[app]; ok {
+		return schema
+	}
+	return r.defaultSchema
+}
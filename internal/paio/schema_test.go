@@ -0,0 +1,186 @@
+package paio
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/calindra/nonodo/internal/sequencers/paiodecoder"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/stretchr/testify/suite"
+)
+
+type SchemaSuite struct {
+	suite.Suite
+	app common.Address
+}
+
+func TestSchemaSuite(t *testing.T) {
+	suite.Run(t, new(SchemaSuite))
+}
+
+func (s *SchemaSuite) SetupTest() {
+	s.app = common.HexToAddress("0xab7528bb862fb57e8a2bcd567a2e929a0be56a5e")
+}
+
+// Schema of an order, signed as a structured message.
+func (s *SchemaSuite) orderSchema(payload string) TransactionSchema {
+	return TransactionSchema{
+		App:         &s.app,
+		Domain:      SchemaDomain{Name: "Exchange", Version: "1"},
+		PrimaryType: "Order",
+		Types: apitypes.Types{
+			"Order": {
+				{Name: "app", Type: "address"},
+				{Name: "nonce", Type: "uint64"},
+				{Name: "max_gas_price", Type: "uint128"},
+				{Name: "side", Type: "string"},
+				{Name: "amount", Type: "uint256"},
+				{Name: "market", Type: "bytes4"},
+			},
+		},
+		Payload: payload,
+	}
+}
+
+// Typed data of an order as it arrives in a request.
+func (s *SchemaSuite) order() apitypes.TypedData {
+	typedData := apitypes.TypedData{
+		Types:       s.orderSchema(PayloadJSON).SigningSchema(big.NewInt(31337), common.Address{}).Types, // nolint
+		PrimaryType: "Order",
+		Domain:      apitypes.TypedDataDomain{Name: "Exchange", Version: "1"},
+		Message: apitypes.TypedDataMessage{
+			"app":           s.app.Hex(),
+			"nonce":         0,
+			"max_gas_price": "10",
+			"side":          "buy",
+			"amount":        "1000000000000000000000",
+			"market":        "0x01020304",
+		},
+	}
+	data, err := json.Marshal(typedData)
+	s.Require().NoError(err)
+	var decoded apitypes.TypedData
+	s.Require().NoError(json.Unmarshal(data, &decoded))
+	return decoded
+}
+
+func (s *SchemaSuite) TestTheDefaultSchemaAcceptsCartesiMessages() {
+	registry := NewSchemaRegistry()
+	typedData := paiodecoder.CreateTypedData(s.app, 0, big.NewInt(10), []byte{1, 2}, big.NewInt(31337)) // nolint
+	schema := registry.SchemaOf(s.app)
+	s.NoError(schema.Validate(typedData))
+	payload, err := schema.PayloadOf(typedData.Message)
+	s.NoError(err)
+	s.Equal([]byte{1, 2}, payload)
+}
+
+func (s *SchemaSuite) TestItRejectsMessagesOfAnotherSchema() {
+	registry := NewSchemaRegistry()
+	s.Require().NoError(registry.Register(s.orderSchema(PayloadJSON)))
+	typedData := paiodecoder.CreateTypedData(s.app, 0, big.NewInt(10), []byte{1, 2}, big.NewInt(31337)) // nolint
+	err := registry.SchemaOf(s.app).Validate(typedData)
+	s.ErrorIs(err, ErrInvalidTransaction)
+
+	// other apps keep the default schema
+	s.NoError(registry.SchemaOf(common.Address{}).Validate(typedData))
+	s.ErrorIs(registry.SchemaOf(common.Address{}).Validate(s.order()), ErrInvalidTransaction)
+}
+
+func (s *SchemaSuite) TestItBuildsAJSONPayload() {
+	schema := s.orderSchema(PayloadJSON)
+	s.Require().NoError(schema.Validate(s.order()))
+	payload, err := schema.PayloadOf(s.order().Message)
+	s.Require().NoError(err)
+	s.JSONEq(`{"side":"buy","amount":"1000000000000000000000","market":"0x01020304"}`, string(payload))
+}
+
+func (s *SchemaSuite) TestItBuildsAnABIPayload() {
+	schema := s.orderSchema(PayloadABI)
+	payload, err := schema.PayloadOf(s.order().Message)
+	s.Require().NoError(err)
+
+	amount, _ := new(big.Int).SetString("1000000000000000000000", 10) // nolint
+	expected, err := s.orderArguments().Pack("buy", amount, [4]byte{1, 2, 3, 4})
+	s.Require().NoError(err)
+	s.Equal(expected, payload)
+}
+
+// Arguments of the ABI payload of an order.
+func (s *SchemaSuite) orderArguments() abi.Arguments {
+	stringType, _ := abi.NewType("string", "", nil)
+	uintType, _ := abi.NewType("uint256", "", nil)
+	bytesType, _ := abi.NewType("bytes4", "", nil)
+	return abi.Arguments{{Type: stringType}, {Type: uintType}, {Type: bytesType}}
+}
+
+func (s *SchemaSuite) TestItKeepsThePrecisionOfTheNumbers() {
+	order, err := json.Marshal(s.order())
+	s.Require().NoError(err)
+	// nolint
+	order = bytes.Replace(order, []byte(`"amount":"1000000000000000000000"`), []byte(`"amount":1000000000000000000001`), 1)
+	typedData, err := decodeTypedData(order)
+	s.Require().NoError(err)
+	payload, err := s.orderSchema(PayloadABI).PayloadOf(typedData.Message)
+	s.Require().NoError(err)
+	values, err := s.orderArguments().Unpack(payload)
+	s.Require().NoError(err)
+	amount, _ := new(big.Int).SetString("1000000000000000000001", 10) // nolint
+	s.Equal(amount, values[1])
+
+	typedData.Message["amount"] = json.Number("1.5")
+	_, err = s.orderSchema(PayloadABI).PayloadOf(typedData.Message)
+	s.ErrorIs(err, ErrInvalidTransaction)
+	typedData.Message["amount"] = 1.5
+	_, err = s.orderSchema(PayloadABI).PayloadOf(typedData.Message)
+	s.ErrorIs(err, ErrInvalidTransaction)
+}
+
+func (s *SchemaSuite) TestItChecksTheRangeOfTheIntegers() {
+	int8Type, _ := abi.NewType("int8", "", nil)
+	uint8Type, _ := abi.NewType("uint8", "", nil)
+	for value, valid := range map[string]bool{"-129": false, "-128": true, "127": true, "128": false} {
+		_, err := abiValue(int8Type, json.Number(value))
+		s.Equal(valid, err == nil, value)
+	}
+	for value, valid := range map[string]bool{"-1": false, "0": true, "255": true, "256": false} {
+		_, err := abiValue(uint8Type, json.Number(value))
+		s.Equal(valid, err == nil, value)
+	}
+	value, err := abiValue(int8Type, json.Number("-128"))
+	s.Require().NoError(err)
+	s.Equal(int8(-128), value)
+}
+
+func (s *SchemaSuite) TestItRejectsInvalidSchemas() {
+	registry := NewSchemaRegistry()
+	schema := s.orderSchema(PayloadData)
+	s.ErrorContains(registry.Register(schema), "payload data requires the field bytes data")
+
+	schema = s.orderSchema(PayloadJSON)
+	schema.Types["Order"] = schema.Types["Order"][1:]
+	s.ErrorContains(registry.Register(schema), "must have the field address app")
+
+	schema = s.orderSchema("xml")
+	s.ErrorContains(registry.Register(schema), `unknown payload "xml"`)
+}
+
+func (s *SchemaSuite) TestItLoadsTheSchemasFromAFile() {
+	file := filepath.Join(s.T().TempDir(), "schemas.json")
+	data, err := json.Marshal(map[string]any{"schemas": []TransactionSchema{s.orderSchema(PayloadABI)}})
+	s.Require().NoError(err)
+	s.Require().NoError(os.WriteFile(file, data, 0600)) // nolint
+	registry, err := LoadSchemaRegistry(file)
+	s.Require().NoError(err)
+	s.Equal("Order", registry.SchemaOf(s.app).PrimaryType)
+	s.Equal("CartesiMessage", registry.SchemaOf(common.Address{}).PrimaryType)
+
+	signing := registry.SchemaOf(s.app).SigningSchema(big.NewInt(31337), common.Address{}) // nolint
+	s.Equal(domainType, signing.Types["EIP712Domain"])
+	s.Equal("31337", (*big.Int)(signing.Domain.ChainId).String())
+}
@@ -1,14 +1,28 @@
 package paio
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/calindra/nonodo/internal/sequencers/avail"
 	"github.com/calindra/nonodo/internal/sequencers/espresso"
+	"github.com/calindra/nonodo/internal/sequencers/paiodecoder"
 	"github.com/cartesi/rollups-graphql/pkg/commons"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 )
 
 type Sender interface {
 	SubmitSigAndData(sigAndData commons.SigAndData) (string, error)
 }
 
+// Sender that accepts transactions whose signature was already checked, so it also
+// takes EIP-191 and EIP-1271 signatures, which SubmitSigAndData can't recover.
+type VerifiedSender interface {
+	SubmitVerified(verified *VerifiedSignature, typedData apitypes.TypedData) (string, error)
+}
+
 type EspressoSender struct {
 	Namespace uint64
 	Client    espresso.EspressoClient
@@ -27,3 +41,50 @@ func NewEspressoSender(url string, namespace uint64) Sender {
 		},
 	}
 }
+
+// Sender that publishes each transaction to Avail as a Paio batch with a single transaction,
+// the format read by the Avail listener. It stands in for the Paio server on local chains.
+type AvailSender struct {
+	Client  *avail.AvailClient
+	Schemas *SchemaRegistry
+}
+
+// SubmitSigAndData implements Sender.
+func (as AvailSender) SubmitSigAndData(sigAndData commons.SigAndData) (string, error) {
+	raw, err := json.Marshal(sigAndData)
+	if err != nil {
+		return "", err
+	}
+	_, typedData, signature, err := commons.ExtractSigAndData(string(raw))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidTransaction, err)
+	}
+	tx, err := transactionFromMessage(typedData, signature, as.Schemas)
+	if err != nil {
+		return "", err
+	}
+	// the listener recovers the signer from a CartesiMessage rebuilt from the batch
+	if schema := as.Schemas.SchemaOf(common.HexToAddress(tx.App)); !schema.isDefault() {
+		return "", fmt.Errorf("%w: the %s schema is not supported by Avail, which only carries CartesiMessage",
+			ErrInvalidTransaction, schema.PrimaryType)
+	}
+	batch, err := paiodecoder.EncodeBatch(paiodecoder.PaioBatch{
+		SequencerPaymentAddress: common.Address{}.Hex(),
+		Txs:                     []paiodecoder.PaioTransaction{tx},
+	})
+	if err != nil {
+		return "", err
+	}
+	hash, err := as.Client.DefaultSubmit(context.Background(), string(batch))
+	if err != nil {
+		return "", fmt.Errorf("avail submit: %w", err)
+	}
+	return hash.Hex(), nil
+}
+
+func NewAvailSender(client *avail.AvailClient, schemas *SchemaRegistry) Sender {
+	return AvailSender{
+		Client:  client,
+		Schemas: schemas,
+	}
+}
@@ -0,0 +1,157 @@
+package paio
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calindra/nonodo/internal/sequencers/avail"
+	"github.com/calindra/nonodo/internal/sequencers/paiodecoder"
+	"github.com/cartesi/rollups-graphql/pkg/commons"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type AvailSenderSuite struct {
+	suite.Suite
+	emulator *avail.AvailEmulator
+	server   *httptest.Server
+	url      string
+	sender   Sender
+}
+
+func TestAvailSenderSuite(t *testing.T) {
+	suite.Run(t, new(AvailSenderSuite))
+}
+
+func (s *AvailSenderSuite) SetupTest() {
+	s.emulator = avail.NewAvailEmulator(time.Hour)
+	s.Require().NoError(s.emulator.ProduceBlock())
+	s.server = httptest.NewServer(s.emulator)
+	s.url = "ws" + strings.TrimPrefix(s.server.URL, "http")
+	client, err := avail.NewAvailClient("", avail.DEFAULT_CHAINID_HARDHAT, avail.DEFAULT_APP_ID)
+	s.Require().NoError(err)
+	s.sender = NewAvailSender(client.WithRpcUrl(s.url), nil)
+}
+
+func (s *AvailSenderSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *AvailSenderSuite) TestItPublishesTheTransactionsAsPaioBatches() {
+	ctx := context.Background()
+	key, err := crypto.GenerateKey()
+	s.Require().NoError(err)
+	app := common.HexToAddress("0xab7528bb862fb57e8a2bcd567a2e929a0be56a5e")
+	sigAndData, err := signTransaction(key, app, 0, "0xdeadbeef")
+	s.Require().NoError(err)
+	hash, err := s.sender.SubmitSigAndData(sigAndData)
+	s.Require().NoError(err)
+	s.True(strings.HasPrefix(hash, "0x"))
+	s.Require().NoError(s.emulator.ProduceBlock())
+
+	api, err := avail.NewSubstrateAPICtx(ctx, s.url)
+	s.Require().NoError(err)
+	defer api.Client.Close()
+	blockHash, err := api.RPC.Chain.GetBlockHash(1)
+	s.Require().NoError(err)
+	block, err := api.RPC.Chain.GetBlock(blockHash)
+	s.Require().NoError(err)
+	s.Require().Len(block.Block.Extrinsics, 2)
+	ext := block.Block.Extrinsics[1]
+	s.Equal(int64(avail.DEFAULT_APP_ID), ext.Signature.AppID.Int64())
+
+	// the listener reads the same transaction from the batch
+	batch, err := paiodecoder.NativeDecoder{}.DecodePaioBatch(ctx, ext.Method.Args)
+	s.Require().NoError(err)
+	inputs, err := paiodecoder.ParsePaioBatchToInputs(batch, big.NewInt(avail.DEFAULT_CHAINID_HARDHAT))
+	s.Require().NoError(err)
+	s.Require().Len(inputs, 1)
+	s.Equal(crypto.PubkeyToAddress(key.PublicKey), inputs[0].MsgSender)
+	s.Equal(app, inputs[0].AppContract)
+	s.Equal(common.FromHex("0xdeadbeef"), common.FromHex(inputs[0].Payload))
+}
+
+func (s *AvailSenderSuite) TestItRejectsInvalidTransactions() {
+	_, err := s.sender.SubmitSigAndData(commons.SigAndData{Signature: "0x01", TypedData: "e30="})
+	s.ErrorIs(err, ErrInvalidTransaction)
+}
+
+func (s *AvailSenderSuite) TestItOnlyPublishesTheDefaultSchema() {
+	ctx := context.Background()
+	key, err := crypto.GenerateKey()
+	s.Require().NoError(err)
+	app := common.HexToAddress("0xab7528bb862fb57e8a2bcd567a2e929a0be56a5e")
+	other := common.HexToAddress("0x70ac08179605af2d9e75782b8decdd3c22aa4d0c")
+	schemas := NewSchemaRegistry()
+	s.Require().NoError(schemas.Register(TransactionSchema{
+		App:         &app,
+		Domain:      SchemaDomain{Name: "Wallet", Version: "1"},
+		PrimaryType: "Transfer",
+		Types: apitypes.Types{
+			"Transfer": append(slices.Clone(sequencerFields), apitypes.Type{Name: "data", Type: "bytes"}),
+		},
+		Payload: PayloadData,
+	}))
+	s.sender = NewAvailSender(s.sender.(AvailSender).Client, schemas)
+
+	// the listener would recover another signer from the CartesiMessage of the batch
+	typedData := paiodecoder.CreateTypedData(app, 0, big.NewInt(10), common.FromHex("0xdeadbeef"), big.NewInt(31337)) // nolint
+	typedData.Domain.Name = "Wallet"
+	typedData.Domain.Version = "1"
+	typedData.Types["Transfer"] = typedData.Types["CartesiMessage"]
+	delete(typedData.Types, "CartesiMessage")
+	typedData.PrimaryType = "Transfer"
+	sigAndData := signTypedData(s.T(), key, typedData)
+	_, err = s.sender.SubmitSigAndData(sigAndData)
+	s.ErrorIs(err, ErrInvalidTransaction)
+
+	// the apps of the default schema still get their signer back from the batch
+	sigAndData, err = signTransaction(key, other, 0, "0xdeadbeef")
+	s.Require().NoError(err)
+	_, err = s.sender.SubmitSigAndData(sigAndData)
+	s.Require().NoError(err)
+	s.Require().NoError(s.emulator.ProduceBlock())
+	api, err := avail.NewSubstrateAPICtx(ctx, s.url)
+	s.Require().NoError(err)
+	defer api.Client.Close()
+	blockHash, err := api.RPC.Chain.GetBlockHash(1)
+	s.Require().NoError(err)
+	block, err := api.RPC.Chain.GetBlock(blockHash)
+	s.Require().NoError(err)
+	s.Require().Len(block.Block.Extrinsics, 2)
+	batch, err := paiodecoder.NativeDecoder{}.DecodePaioBatch(ctx, block.Block.Extrinsics[1].Method.Args)
+	s.Require().NoError(err)
+	inputs, err := paiodecoder.ParsePaioBatchToInputs(batch, big.NewInt(avail.DEFAULT_CHAINID_HARDHAT))
+	s.Require().NoError(err)
+	s.Require().Len(inputs, 1)
+	s.Equal(crypto.PubkeyToAddress(key.PublicKey), inputs[0].MsgSender)
+	s.Equal(other, inputs[0].AppContract)
+}
+
+// Sign the typed data as the clients do.
+func signTypedData(t *testing.T, key *ecdsa.PrivateKey, typedData apitypes.TypedData) commons.SigAndData {
+	typedDataJSON, err := json.Marshal(typedData)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(typedDataJSON, &typedData))
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	require.NoError(t, err)
+	signature, err := crypto.Sign(hash, key)
+	require.NoError(t, err)
+	signature[64] += 27
+	return commons.SigAndData{
+		Signature: hexutil.Encode(signature),
+		TypedData: base64.StdEncoding.EncodeToString(typedDataJSON),
+	}
+}
@@ -0,0 +1,197 @@
+package paio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Signing schemes of the L2 transactions.
+const (
+	// The signature is over the EIP-712 hash of the typed data.
+	SignatureEIP712 = "eip712"
+	// The signature is over the EIP-712 hash wrapped by EIP-191 personal_sign.
+	SignatureEIP191 = "eip191"
+)
+
+var ErrInvalidSignature = errors.New("wrong signature")
+
+// Value returned by EIP-1271 isValidSignature when the signature is valid.
+var eip1271MagicValue = [4]byte{0x16, 0x26, 0xba, 0x7e}
+
+const eip1271ABI = `[{"type":"function","name":"isValidSignature","stateMutability":"view",
+	"inputs":[{"name":"hash","type":"bytes32"},{"name":"signature","type":"bytes"}],
+	"outputs":[{"name":"magicValue","type":"bytes4"}]}]`
+
+// Part of the L1 client used to check contract signatures.
+type ContractCaller interface {
+	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
+	CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+// Signature checked by the verifier.
+type VerifiedSignature struct {
+	Signer common.Address
+	// For ECDSA signatures, the recovery id is 0 or 1.
+	Signature []byte
+	// Set when the signer is a contract that validated the signature with EIP-1271.
+	Contract bool
+	// Digest signed: the EIP-712 hash of the typed data, wrapped by EIP-191 for personal_sign.
+	Hash []byte
+}
+
+// Id of the transaction: the hash of its ECDSA signature or, as a contract may reuse its
+// signature across messages, the hash of the signed digest and the signer.
+func (v *VerifiedSignature) TransactionId() string {
+	if v.Contract {
+		return hexutil.Encode(crypto.Keccak256(v.Hash, v.Signer.Bytes()))
+	}
+	return hexutil.Encode(crypto.Keccak256(v.Signature))
+}
+
+// Check the signatures of the L2 transactions.
+// Signatures from externally owned accounts are recovered with ECDSA; signatures of smart
+// contract wallets are checked by calling isValidSignature on the signer.
+type SignatureVerifier struct {
+	// L1 client; dialed from RpcUrl when nil.
+	Client ContractCaller
+	RpcUrl string
+}
+
+// Verify the signature of the typed data with the given scheme.
+// The signer is optional for ECDSA signatures and required for contract signatures.
+func (v *SignatureVerifier) Verify(
+	ctx context.Context,
+	typedData apitypes.TypedData,
+	signature []byte,
+	scheme string,
+	signer *common.Address,
+) (*VerifiedSignature, error) {
+	hashable := typedData
+	hashable.Message = numbersAsStrings(typedData.Message).(apitypes.TypedDataMessage)
+	hash, _, err := apitypes.TypedDataAndHash(hashable)
+	if err != nil {
+		return nil, fmt.Errorf("%w: typed data hash: %w", ErrInvalidTransaction, err)
+	}
+	switch strings.ToLower(scheme) {
+	case "", SignatureEIP712:
+	case SignatureEIP191:
+		hash = accounts.TextHash(hash)
+	default:
+		return nil, fmt.Errorf("%w: unknown signature scheme %q, expected eip712 or eip191", ErrInvalidTransaction, scheme)
+	}
+
+	if signer != nil {
+		client, closeClient, err := v.client(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer closeClient()
+		code, err := client.CodeAt(ctx, *signer, nil)
+		if err != nil {
+			return nil, fmt.Errorf("paio: read code of %s: %w", signer.Hex(), err)
+		}
+		if len(code) > 0 {
+			if err := isValidSignature(ctx, client, *signer, hash, signature); err != nil {
+				return nil, err
+			}
+			return &VerifiedSignature{Signer: *signer, Signature: signature, Contract: true, Hash: hash}, nil
+		}
+	}
+
+	const signatureSize = 65
+	if len(signature) != signatureSize {
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrInvalidSignature, signatureSize, len(signature))
+	}
+	normalized := bytes.Clone(signature)
+	if normalized[64] >= signatureVOffset {
+		normalized[64] -= signatureVOffset
+	}
+	pubkey, err := crypto.SigToPub(hash, normalized)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+	recovered := crypto.PubkeyToAddress(*pubkey)
+	if signer != nil && recovered != *signer {
+		return nil, fmt.Errorf("%w: signed by %s instead of %s", ErrInvalidSignature, recovered.Hex(), signer.Hex())
+	}
+	return &VerifiedSignature{Signer: recovered, Signature: normalized, Hash: hash}, nil
+}
+
+func (v *SignatureVerifier) client(ctx context.Context) (ContractCaller, func(), error) {
+	if v.Client != nil {
+		return v.Client, func() {}, nil
+	}
+	client, err := ethclient.DialContext(ctx, v.RpcUrl)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ethclient dial error: %w", err)
+	}
+	return client, client.Close, nil
+}
+
+// Ask the contract whether the signature of the hash is valid, as defined by EIP-1271.
+func isValidSignature(
+	ctx context.Context,
+	client ContractCaller,
+	contract common.Address,
+	hash []byte,
+	signature []byte,
+) error {
+	parsed, err := abi.JSON(strings.NewReader(eip1271ABI))
+	if err != nil {
+		return err
+	}
+	input, err := parsed.Pack("isValidSignature", common.BytesToHash(hash), signature)
+	if err != nil {
+		return err
+	}
+	output, err := client.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: input}, nil)
+	if err != nil {
+		return fmt.Errorf("%w: isValidSignature of %s: %w", ErrInvalidSignature, contract.Hex(), err)
+	}
+	values, err := parsed.Unpack("isValidSignature", output)
+	if err != nil || len(values) != 1 {
+		return fmt.Errorf("%w: invalid isValidSignature result of %s", ErrInvalidSignature, contract.Hex())
+	}
+	magicValue, ok := values[0].([4]byte)
+	if !ok || magicValue != eip1271MagicValue {
+		return fmt.Errorf("%w: rejected by the contract %s", ErrInvalidSignature, contract.Hex())
+	}
+	return nil
+}
+
+// Replace the json.Number values by their text, which apitypes reads as integers without
+// losing precision.
+func numbersAsStrings(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		return v.String()
+	case map[string]any:
+		converted := make(map[string]any, len(v))
+		for key, child := range v {
+			converted[key] = numbersAsStrings(child)
+		}
+		return converted
+	case []any:
+		converted := make([]any, len(v))
+		for i, child := range v {
+			converted[i] = numbersAsStrings(child)
+		}
+		return converted
+	default:
+		return value
+	}
+}
@@ -0,0 +1,168 @@
+package paio
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/calindra/nonodo/internal/sequencers/paiodecoder"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/stretchr/testify/suite"
+)
+
+type SignatureSuite struct {
+	suite.Suite
+	key       *ecdsa.PrivateKey
+	wallet    common.Address
+	typedData apitypes.TypedData
+	hash      []byte
+	verifier  *SignatureVerifier
+}
+
+func TestSignatureSuite(t *testing.T) {
+	suite.Run(t, new(SignatureSuite))
+}
+
+func (s *SignatureSuite) SetupTest() {
+	key, err := crypto.GenerateKey()
+	s.Require().NoError(err)
+	s.key = key
+	s.wallet = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	app := common.HexToAddress("0xab7528bb862fb57e8a2bcd567a2e929a0be56a5e")
+	typedData := paiodecoder.CreateTypedData(app, 0, big.NewInt(10), []byte{1}, big.NewInt(31337)) // nolint
+	data, err := json.Marshal(typedData)
+	s.Require().NoError(err)
+	s.Require().NoError(json.Unmarshal(data, &s.typedData))
+	s.hash, _, err = apitypes.TypedDataAndHash(s.typedData)
+	s.Require().NoError(err)
+	s.verifier = &SignatureVerifier{Client: &fakeWallet{address: s.wallet, signature: []byte("approved")}}
+}
+
+func (s *SignatureSuite) sign(hash []byte) []byte {
+	signature, err := crypto.Sign(hash, s.key)
+	s.Require().NoError(err)
+	signature[64] += signatureVOffset
+	return signature
+}
+
+func (s *SignatureSuite) TestItRecoversTheSignerOfEIP712Signatures() {
+	signer := crypto.PubkeyToAddress(s.key.PublicKey)
+	verified, err := s.verifier.Verify(context.Background(), s.typedData, s.sign(s.hash), SignatureEIP712, nil)
+	s.Require().NoError(err)
+	s.Equal(signer, verified.Signer)
+	s.False(verified.Contract)
+	s.Less(verified.Signature[64], byte(signatureVOffset))
+
+	verified, err = s.verifier.Verify(context.Background(), s.typedData, s.sign(s.hash), SignatureEIP712, &signer)
+	s.Require().NoError(err)
+	s.Equal(signer, verified.Signer)
+}
+
+func (s *SignatureSuite) TestItRecoversTheSignerOfEIP191Signatures() {
+	signature := s.sign(accounts.TextHash(s.hash))
+	verified, err := s.verifier.Verify(context.Background(), s.typedData, signature, SignatureEIP191, nil)
+	s.Require().NoError(err)
+	s.Equal(crypto.PubkeyToAddress(s.key.PublicKey), verified.Signer)
+
+	// the same signature doesn't sign the typed data hash
+	verified, err = s.verifier.Verify(context.Background(), s.typedData, signature, SignatureEIP712, nil)
+	s.Require().NoError(err)
+	s.NotEqual(crypto.PubkeyToAddress(s.key.PublicKey), verified.Signer)
+}
+
+func (s *SignatureSuite) TestItRejectsTheSignatureOfAnotherSigner() {
+	other := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	_, err := s.verifier.Verify(context.Background(), s.typedData, s.sign(s.hash), SignatureEIP712, &other)
+	s.ErrorIs(err, ErrInvalidSignature)
+	s.ErrorContains(err, "wrong signature")
+}
+
+func (s *SignatureSuite) TestItChecksContractSignaturesWithEIP1271() {
+	verified, err := s.verifier.Verify(context.Background(), s.typedData, []byte("approved"), SignatureEIP712, &s.wallet)
+	s.Require().NoError(err)
+	s.Equal(s.wallet, verified.Signer)
+	s.True(verified.Contract)
+	s.Equal([]byte("approved"), verified.Signature)
+
+	_, err = s.verifier.Verify(context.Background(), s.typedData, []byte("rejected"), SignatureEIP712, &s.wallet)
+	s.ErrorIs(err, ErrInvalidSignature)
+}
+
+func (s *SignatureSuite) TestContractSignaturesHaveOneIdPerMessage() {
+	app := common.HexToAddress("0xab7528bb862fb57e8a2bcd567a2e929a0be56a5e")
+	other := paiodecoder.CreateTypedData(app, 1, big.NewInt(10), []byte{1}, big.NewInt(31337)) // nolint
+	data, err := json.Marshal(other)
+	s.Require().NoError(err)
+	var typedData apitypes.TypedData
+	s.Require().NoError(json.Unmarshal(data, &typedData))
+
+	first, err := s.verifier.Verify(context.Background(), s.typedData, []byte("approved"), SignatureEIP712, &s.wallet)
+	s.Require().NoError(err)
+	second, err := s.verifier.Verify(context.Background(), typedData, []byte("approved"), SignatureEIP712, &s.wallet)
+	s.Require().NoError(err)
+	s.Equal(first.Signature, second.Signature)
+	s.NotEqual(first.TransactionId(), second.TransactionId())
+
+	// the same message signed through personal_sign is another transaction
+	personal, err := s.verifier.Verify(context.Background(), s.typedData, []byte("approved"), SignatureEIP191, &s.wallet)
+	s.Require().NoError(err)
+	s.NotEqual(first.TransactionId(), personal.TransactionId())
+}
+
+func (s *SignatureSuite) TestECDSASignaturesKeepTheirHashAsId() {
+	signature := s.sign(s.hash)
+	verified, err := s.verifier.Verify(context.Background(), s.typedData, signature, SignatureEIP712, nil)
+	s.Require().NoError(err)
+	s.Equal(hexutil.Encode(crypto.Keccak256(verified.Signature)), verified.TransactionId())
+}
+
+func (s *SignatureSuite) TestItRejectsUnknownSchemes() {
+	_, err := s.verifier.Verify(context.Background(), s.typedData, s.sign(s.hash), "eip1559", nil)
+	s.ErrorIs(err, ErrInvalidTransaction)
+}
+
+func (s *SignatureSuite) TestTheSequencerKeepsContractSignaturesAsTheirHash() {
+	tx, err := transactionFromMessage(s.typedData, []byte("approved"), nil)
+	s.Require().NoError(err)
+	s.Equal("0x"+common.Bytes2Hex(crypto.Keccak256([]byte("approved"))), tx.Signature.R)
+	s.Equal("0x0", tx.Signature.V)
+}
+
+// Contract wallet that approves a single signature.
+type fakeWallet struct {
+	address   common.Address
+	signature []byte
+}
+
+func (w *fakeWallet) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	if account == w.address {
+		return []byte{0x60, 0x80}, nil // nolint
+	}
+	return nil, nil
+}
+
+func (w *fakeWallet) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	parsed, err := abi.JSON(strings.NewReader(eip1271ABI))
+	if err != nil {
+		return nil, err
+	}
+	args, err := parsed.Methods["isValidSignature"].Inputs.Unpack(call.Data[4:])
+	if err != nil {
+		return nil, err
+	}
+	magicValue := [4]byte{}
+	if bytes.Equal(args[1].([]byte), w.signature) {
+		magicValue = eip1271MagicValue
+	}
+	return parsed.Methods["isValidSignature"].Outputs.Pack(magicValue)
+}
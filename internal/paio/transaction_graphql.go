@@ -0,0 +1,304 @@
+package paio
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/formatter"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// Query field of the GraphQL API with the status of a transaction:
+//
+//	transaction(id: String!): TransactionStatus
+//
+// TransactionStatus has the fields of GET /transaction/{txId}.
+const GraphQLTransactionField = "transaction"
+
+// Type of the transaction field.
+const graphQLTransactionType = "TransactionStatus"
+
+// Middleware that serves the transaction field of the GraphQL API.
+// The reader schema comes from rollups-graphql, so the field is resolved here and the other
+// fields of the query are sent to the reader; the data of both is merged in the response.
+func GraphQLTransactions(tracker *TransactionTracker) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			request := c.Request()
+			if request.Method != http.MethodPost || !strings.HasPrefix(c.Path(), "/graphql") {
+				return next(c)
+			}
+			body, err := io.ReadAll(request.Body)
+			if err != nil {
+				return err
+			}
+			request.Body = io.NopCloser(bytes.NewReader(body))
+			var params map[string]any
+			if err := json.Unmarshal(body, &params); err != nil {
+				return next(c)
+			}
+			query, _ := params["query"].(string)
+			operationName, _ := params["operationName"].(string)
+			variables, _ := params["variables"].(map[string]any)
+			doc, err := parser.ParseQuery(&ast.Source{Input: query})
+			if err != nil {
+				return next(c)
+			}
+			operation := findOperation(doc, operationName)
+			if operation == nil || operation.Operation != ast.Query {
+				return next(c)
+			}
+			var fields []*ast.Field
+			var others ast.SelectionSet
+			for _, selection := range operation.SelectionSet {
+				if field, ok := selection.(*ast.Field); ok && field.Name == GraphQLTransactionField {
+					fields = append(fields, field)
+					continue
+				}
+				others = append(others, selection)
+			}
+			if len(fields) == 0 {
+				return next(c)
+			}
+
+			data := make(map[string]any)
+			var errors []any
+			for _, field := range fields {
+				key := responseKey(field)
+				value, err := resolveTransaction(c, tracker, doc, field, variables)
+				if err != nil {
+					errors = append(errors, map[string]any{"message": err.Error(), "path": []string{key}})
+				}
+				data[key] = value
+			}
+			if len(others) == 0 {
+				response := map[string]any{"data": data}
+				if len(errors) > 0 {
+					response["errors"] = errors
+				}
+				return c.JSON(http.StatusOK, response)
+			}
+
+			// send the other fields to the reader
+			operation.SelectionSet = others
+			removeUnused(doc, operation)
+			var formatted bytes.Buffer
+			formatter.NewFormatter(&formatted).FormatQueryDocument(doc)
+			params["query"] = formatted.String()
+			forwarded, err := json.Marshal(params)
+			if err != nil {
+				return err
+			}
+			request.Body = io.NopCloser(bytes.NewReader(forwarded))
+			request.ContentLength = int64(len(forwarded))
+			writer := c.Response().Writer
+			buffered := &bufferedWriter{ResponseWriter: writer, status: http.StatusOK}
+			c.Response().Writer = buffered
+			err = next(c)
+			c.Response().Writer = writer
+			if err != nil {
+				return err
+			}
+			output := buffered.body.Bytes()
+			if merged, err := mergeData(output, data, errors); err == nil {
+				output = merged
+			}
+			writer.Header().Del(echo.HeaderContentLength)
+			writer.WriteHeader(buffered.status)
+			_, err = writer.Write(output)
+			return err
+		}
+	}
+}
+
+func findOperation(doc *ast.QueryDocument, name string) *ast.OperationDefinition {
+	if name != "" {
+		return doc.Operations.ForName(name)
+	}
+	if len(doc.Operations) != 1 {
+		return nil
+	}
+	return doc.Operations[0]
+}
+
+func responseKey(field *ast.Field) string {
+	if field.Alias != "" {
+		return field.Alias
+	}
+	return field.Name
+}
+
+// Return the selected fields of the status of the transaction, or nil if it is unknown.
+func resolveTransaction(
+	c echo.Context,
+	tracker *TransactionTracker,
+	doc *ast.QueryDocument,
+	field *ast.Field,
+	variables map[string]any,
+) (any, error) {
+	argument := field.Arguments.ForName("id")
+	if argument == nil {
+		return nil, fmt.Errorf("%s: argument id is required", GraphQLTransactionField)
+	}
+	value, err := argument.Value.Value(variables)
+	if err != nil {
+		return nil, err
+	}
+	txId, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s: argument id must be a string", GraphQLTransactionField)
+	}
+	status, err := tracker.Status(c.Request().Context(), txId)
+	if err != nil || status == nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(status)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, err
+	}
+	selected := make(map[string]any)
+	selectFields(doc, field.SelectionSet, fields, selected)
+	return selected, nil
+}
+
+// Copy the selected fields to the result, expanding the fragments.
+func selectFields(doc *ast.QueryDocument, selections ast.SelectionSet, fields map[string]any, result map[string]any) {
+	for _, selection := range selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if selection.Name == "__typename" {
+				result[responseKey(selection)] = graphQLTransactionType
+				continue
+			}
+			result[responseKey(selection)] = fields[selection.Name]
+		case *ast.InlineFragment:
+			selectFields(doc, selection.SelectionSet, fields, result)
+		case *ast.FragmentSpread:
+			if fragment := doc.Fragments.ForName(selection.Name); fragment != nil {
+				selectFields(doc, fragment.SelectionSet, fields, result)
+			}
+		}
+	}
+}
+
+// Remove the fragments on the transaction type and the variables no longer used, which the
+// reader would reject.
+func removeUnused(doc *ast.QueryDocument, operation *ast.OperationDefinition) {
+	var fragments ast.FragmentDefinitionList
+	for _, fragment := range doc.Fragments {
+		if fragment.TypeCondition != graphQLTransactionType {
+			fragments = append(fragments, fragment)
+		}
+	}
+	doc.Fragments = fragments
+	var definitions ast.VariableDefinitionList
+	for _, definition := range operation.VariableDefinitions {
+		used := usesVariable(operation.SelectionSet, definition.Variable)
+		for _, fragment := range doc.Fragments {
+			used = used || usesVariable(fragment.SelectionSet, definition.Variable)
+		}
+		if used {
+			definitions = append(definitions, definition)
+		}
+	}
+	operation.VariableDefinitions = definitions
+}
+
+func usesVariable(selections ast.SelectionSet, name string) bool {
+	for _, selection := range selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			for _, argument := range selection.Arguments {
+				if valueUses(argument.Value, name) {
+					return true
+				}
+			}
+			if directivesUse(selection.Directives, name) || usesVariable(selection.SelectionSet, name) {
+				return true
+			}
+		case *ast.InlineFragment:
+			if directivesUse(selection.Directives, name) || usesVariable(selection.SelectionSet, name) {
+				return true
+			}
+		case *ast.FragmentSpread:
+			if directivesUse(selection.Directives, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func directivesUse(directives ast.DirectiveList, name string) bool {
+	for _, directive := range directives {
+		for _, argument := range directive.Arguments {
+			if valueUses(argument.Value, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func valueUses(value *ast.Value, name string) bool {
+	if value == nil {
+		return false
+	}
+	if value.Kind == ast.Variable && value.Raw == name {
+		return true
+	}
+	for _, child := range value.Children {
+		if valueUses(child.Value, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Add the data and the errors of the transaction fields to the response of the reader.
+func mergeData(output []byte, data map[string]any, errors []any) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	decoder.UseNumber()
+	var response map[string]any
+	if err := decoder.Decode(&response); err != nil {
+		return nil, err
+	}
+	merged, ok := response["data"].(map[string]any)
+	if !ok {
+		merged = make(map[string]any)
+	}
+	for key, value := range data {
+		merged[key] = value
+	}
+	response["data"] = merged
+	if len(errors) > 0 {
+		previous, _ := response["errors"].([]any)
+		response["errors"] = append(previous, errors...)
+	}
+	return json.Marshal(response)
+}
+
+// Response writer that keeps the response of the reader to merge it.
+type bufferedWriter struct {
+	http.ResponseWriter
+	body   bytes.Buffer
+	status int
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
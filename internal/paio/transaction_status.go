@@ -0,0 +1,86 @@
+package paio
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/calindra/nonodo/internal/events"
+	"github.com/cartesi/rollups-graphql/pkg/convenience/model"
+	"github.com/cartesi/rollups-graphql/pkg/convenience/repository"
+)
+
+// Find out what happened to a submitted transaction from the input created for it.
+// The input id is the transaction id.
+// It backs GET /transaction/{txId} and the transaction field of the GraphQL API.
+type TransactionTracker struct {
+	InputRepository   *repository.InputRepository
+	VoucherRepository *repository.VoucherRepository
+	NoticeRepository  *repository.NoticeRepository
+	ReportRepository  *repository.ReportRepository
+	// Optional; set when the local sequencer is running.
+	LocalSequencer *LocalSequencer
+}
+
+// Return the status of the transaction or nil if it is unknown.
+func (t *TransactionTracker) Status(ctx context.Context, txId string) (*TransactionStatus, error) {
+	status := TransactionStatus{Id: txId}
+	if t.LocalSequencer != nil && t.LocalSequencer.IsQueued(txId) {
+		status.Status = Pending
+		return &status, nil
+	}
+	input, err := t.InputRepository.FindByIDAndAppContract(ctx, txId, nil)
+	if err != nil {
+		return nil, err
+	}
+	if input == nil {
+		return nil, nil
+	}
+	status.InputIndex = &input.Index
+	completionStatus, ok := events.StatusName(input.Status)
+	if ok {
+		status.CompletionStatus = &completionStatus
+	}
+	if t.LocalSequencer != nil {
+		status.BatchId, err = t.LocalSequencer.Repository.FindBatchIDByTxID(ctx, txId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if input.Status == model.CompletionStatusUnprocessed {
+		status.Status = Sequenced
+		return &status, nil
+	}
+	status.Status = Processed
+
+	inputIndexField := model.INPUT_INDEX
+	appContractField := model.APP_CONTRACT
+	inputIndex := strconv.Itoa(input.Index)
+	appContract := input.AppContract.Hex()
+	filter := []*model.ConvenienceFilter{
+		{Field: &inputIndexField, Eq: &inputIndex},
+		{Field: &appContractField, Eq: &appContract},
+	}
+	vouchers, err := t.VoucherRepository.Count(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	delegateCallVouchers, err := t.VoucherRepository.CountDelegateCall(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	notices, err := t.NoticeRepository.Count(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	reports, err := t.ReportRepository.Count(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	voucherCount := int(vouchers + delegateCallVouchers)
+	noticeCount := int(notices)
+	reportCount := int(reports)
+	status.Vouchers = &voucherCount
+	status.Notices = &noticeCount
+	status.Reports = &reportCount
+	return &status, nil
+}
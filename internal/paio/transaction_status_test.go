@@ -0,0 +1,238 @@
+package paio
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/calindra/nonodo/internal/events"
+	"github.com/calindra/nonodo/internal/model"
+	"github.com/cartesi/rollups-graphql/pkg/convenience"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/jmoiron/sqlx"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+)
+
+type TransactionStatusSuite struct {
+	suite.Suite
+	tempDir   string
+	db        *sqlx.DB
+	model     *model.NonodoModel
+	sequencer *LocalSequencer
+	tracker   *TransactionTracker
+	app       common.Address
+}
+
+func TestTransactionStatusSuite(t *testing.T) {
+	suite.Run(t, new(TransactionStatusSuite))
+}
+
+func (s *TransactionStatusSuite) SetupTest() {
+	tempDir, err := os.MkdirTemp("", "")
+	s.Require().NoError(err)
+	s.tempDir = tempDir
+	s.db = sqlx.MustConnect("sqlite3", filepath.Join(tempDir, "status.sqlite3"))
+	container := convenience.NewContainer(*s.db, false)
+	s.model = model.NewNonodoModel(
+		container.GetOutputDecoder(),
+		container.GetReportRepository(),
+		container.GetInputRepository(),
+		container.GetVoucherRepository(),
+		container.GetNoticeRepository(),
+	)
+	repository := &BatchRepository{Db: s.db}
+	s.Require().NoError(repository.CreateTables())
+	s.sequencer = NewLocalSequencer(s.model, repository, container.GetInputRepository(), "", time.Hour, 10) // nolint
+	s.sequencer.L1 = fakeL1Reader{}
+	s.tracker = &TransactionTracker{
+		InputRepository:   container.GetInputRepository(),
+		VoucherRepository: container.GetVoucherRepository(),
+		NoticeRepository:  container.GetNoticeRepository(),
+		ReportRepository:  container.GetReportRepository(),
+		LocalSequencer:    s.sequencer,
+	}
+	s.app = common.HexToAddress("0xab7528bb862fb57e8a2bcd567a2e929a0be56a5e")
+}
+
+func (s *TransactionStatusSuite) TearDownTest() {
+	s.NoError(s.db.Close())
+	os.RemoveAll(s.tempDir)
+}
+
+func (s *TransactionStatusSuite) TestItFollowsTheTransaction() {
+	ctx := context.Background()
+	key, err := crypto.GenerateKey()
+	s.Require().NoError(err)
+	sigAndData, err := signTransaction(key, s.app, 0, "0xdeadbeef")
+	s.Require().NoError(err)
+	txId, err := s.sequencer.SubmitSigAndData(sigAndData)
+	s.Require().NoError(err)
+
+	status, err := s.tracker.Status(ctx, txId)
+	s.Require().NoError(err)
+	s.Equal(Pending, status.Status)
+	s.Nil(status.InputIndex)
+
+	batch, err := s.sequencer.SealBatch(ctx)
+	s.Require().NoError(err)
+	status, err = s.tracker.Status(ctx, txId)
+	s.Require().NoError(err)
+	s.Equal(Sequenced, status.Status)
+	s.Equal(0, *status.InputIndex)
+	s.Equal(batch.ID, *status.BatchId)
+	s.Equal("UNPROCESSED", *status.CompletionStatus)
+	s.Nil(status.Notices)
+
+	_, err = s.model.FinishAndGetNext(true)
+	s.Require().NoError(err)
+	_, err = s.model.AddNotice([]byte("notice"), s.app)
+	s.Require().NoError(err)
+	s.Require().NoError(s.model.AddReport(s.app, []byte("report")))
+	s.Require().NoError(s.model.AddReport(s.app, []byte("report")))
+	_, err = s.model.FinishAndGetNext(true)
+	s.Require().NoError(err)
+
+	status, err = s.tracker.Status(ctx, txId)
+	s.Require().NoError(err)
+	s.Equal(Processed, status.Status)
+	s.Equal("ACCEPTED", *status.CompletionStatus)
+	s.Equal(0, *status.Vouchers)
+	s.Equal(1, *status.Notices)
+	s.Equal(2, *status.Reports) // nolint
+}
+
+func (s *TransactionStatusSuite) TestItDoesNotKnowOtherTransactions() {
+	status, err := s.tracker.Status(context.Background(), "0x01")
+	s.NoError(err)
+	s.Nil(status)
+}
+
+func (s *TransactionStatusSuite) TestItServesTheStatusInGraphQL() {
+	key, err := crypto.GenerateKey()
+	s.Require().NoError(err)
+	sigAndData, err := signTransaction(key, s.app, 0, "0xdeadbeef")
+	s.Require().NoError(err)
+	txId, err := s.sequencer.SubmitSigAndData(sigAndData)
+	s.Require().NoError(err)
+
+	var forwarded string
+	e := echo.New()
+	e.Use(GraphQLTransactions(s.tracker))
+	e.POST("/graphql", func(c echo.Context) error {
+		var params struct {
+			Query string `json:"query"`
+		}
+		if err := c.Bind(&params); err != nil {
+			return err
+		}
+		forwarded = params.Query
+		return c.JSON(http.StatusOK, map[string]any{"data": map[string]any{"inputs": map[string]any{"totalCount": 0}}})
+	})
+
+	response := s.postGraphQL(e, `query ($id: String!) { tx: transaction(id: $id) { id status ...batch } }
+		fragment batch on TransactionStatus { batchId __typename }`, txId)
+	s.Empty(forwarded)
+	s.JSONEq(`{"data": {"tx": {"id": "`+txId+`", "status": "pending", "batchId": null, "__typename": "TransactionStatus"}}}`, response)
+
+	response = s.postGraphQL(e, `query ($id: String!) { transaction(id: $id) { status } inputs { totalCount } }`, txId)
+	s.NotContains(forwarded, "transaction")
+	s.NotContains(forwarded, "$id")
+	s.Contains(forwarded, "inputs")
+	s.JSONEq(`{"data": {"transaction": {"status": "pending"}, "inputs": {"totalCount": 0}}}`, response)
+
+	response = s.postGraphQL(e, `query ($id: String!) { transaction(id: $id) { status } }`, "0x01")
+	s.JSONEq(`{"data": {"transaction": null}}`, response)
+}
+
+func (s *TransactionStatusSuite) postGraphQL(e *echo.Echo, query string, txId string) string {
+	body, err := json.Marshal(map[string]any{"query": query, "variables": map[string]any{"id": txId}})
+	s.Require().NoError(err)
+	request := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, request)
+	s.Require().Equal(http.StatusOK, recorder.Code)
+	return recorder.Body.String()
+}
+
+func (s *TransactionStatusSuite) TestItFollowsTheTransactionsStoredWithoutASequencer() {
+	rpcServer := rpc.NewServer()
+	defer rpcServer.Stop()
+	s.Require().NoError(rpcServer.RegisterName("eth", fakeEth{}))
+	server := httptest.NewServer(rpcServer)
+	defer server.Close()
+	published := &publishedEvents{}
+	s.model.SetPublisher(published)
+	api := NewPaioBuilder().
+		WithInputRepository(s.model.GetInputRepository()).
+		WithRpcUrl(server.URL).
+		WithModel(s.model).
+		Build()
+	e := echo.New()
+	Register(e, api)
+
+	key, err := crypto.GenerateKey()
+	s.Require().NoError(err)
+	sigAndData, err := signTransaction(key, s.app, 0, "0xdeadbeef")
+	s.Require().NoError(err)
+	typedDataJSON, err := base64.StdEncoding.DecodeString(sigAndData.TypedData)
+	s.Require().NoError(err)
+	var typedData map[string]any
+	s.Require().NoError(json.Unmarshal(typedDataJSON, &typedData))
+	typedData["domain"].(map[string]any)["chainId"] = 31337
+	body, err := json.Marshal(map[string]any{"signature": sigAndData.Signature, "typedData": typedData})
+	s.Require().NoError(err)
+	request := httptest.NewRequest(http.MethodPost, "/transaction/submit", bytes.NewReader(body))
+	request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, request)
+	s.Require().Equal(http.StatusOK, recorder.Code, recorder.Body.String())
+	var response TransactionResponse
+	s.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+
+	status, err := s.tracker.Status(context.Background(), *response.Id)
+	s.Require().NoError(err)
+	s.Equal(Sequenced, status.Status)
+	s.Equal(0, *status.InputIndex)
+	s.Require().Len(published.events, 1)
+	s.Equal(events.InputAdded, published.events[0].Type)
+	s.Equal(s.app.Hex(), published.events[0].AppContract)
+	s.Equal(0, published.events[0].InputIndex)
+}
+
+type publishedEvents struct {
+	events []events.Event
+}
+
+func (p *publishedEvents) Publish(event events.Event) {
+	p.events = append(p.events, event)
+}
+
+// L1 node of the chain 31337 at block 100.
+type fakeEth struct{}
+
+func (fakeEth) GetBlockByNumber(number string, full bool) (*types.Header, error) {
+	return &types.Header{
+		Number:     big.NewInt(100), // nolint
+		Time:       uint64(time.Now().Unix()),
+		Difficulty: big.NewInt(0),
+		Extra:      []byte{},
+	}, nil
+}
+
+func (fakeEth) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(31337)) // nolint
+}
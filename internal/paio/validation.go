@@ -0,0 +1,58 @@
+package paio
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Error returned when a transaction sequenced locally breaks one of the rules below.
+var ErrInvalidTransaction = errors.New("invalid transaction")
+
+// Rules checked before a transaction is sequenced locally.
+type transactionRules struct {
+	ChainID           *big.Int
+	VerifyingContract common.Address
+	MinGasPrice       uint64
+}
+
+// Check the EIP-712 domain against the running chain and the max_gas_price of the message.
+func (r transactionRules) validate(typedData apitypes.TypedData) error {
+	domain := typedData.Domain
+	if domain.ChainId == nil {
+		return fmt.Errorf("%w: domain chainId is missing", ErrInvalidTransaction)
+	}
+	chainId := (*big.Int)(domain.ChainId)
+	if r.ChainID != nil && chainId.Cmp(r.ChainID) != 0 {
+		return fmt.Errorf("%w: domain chainId %s does not match the chain id %s",
+			ErrInvalidTransaction, chainId, r.ChainID)
+	}
+	if !common.IsHexAddress(domain.VerifyingContract) ||
+		common.HexToAddress(domain.VerifyingContract) != r.VerifyingContract {
+		return fmt.Errorf("%w: domain verifyingContract %q should be %s",
+			ErrInvalidTransaction, domain.VerifyingContract, r.VerifyingContract.Hex())
+	}
+	maxGasPrice, err := messageUint(typedData.Message["max_gas_price"])
+	if err != nil {
+		return fmt.Errorf("%w: max_gas_price: %w", ErrInvalidTransaction, err)
+	}
+	if maxGasPrice < r.MinGasPrice {
+		return fmt.Errorf("%w: max_gas_price %d is lower than the minimum gas price %d",
+			ErrInvalidTransaction, maxGasPrice, r.MinGasPrice)
+	}
+	return nil
+}
+
+// Check that the nonce is the next one expected for the sender.
+func checkNonce(nonce uint64, expected uint64) error {
+	if nonce < expected {
+		return fmt.Errorf("%w: nonce %d was already used, expected %d", ErrInvalidTransaction, nonce, expected)
+	}
+	if nonce > expected {
+		return fmt.Errorf("%w: nonce %d is ahead of the expected nonce %d", ErrInvalidTransaction, nonce, expected)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package paio
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/calindra/nonodo/internal/sequencers/paiodecoder"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/suite"
+)
+
+type ValidationSuite struct {
+	suite.Suite
+	rules transactionRules
+}
+
+func TestValidationSuite(t *testing.T) {
+	suite.Run(t, new(ValidationSuite))
+}
+
+func (s *ValidationSuite) SetupTest() {
+	s.rules = transactionRules{
+		ChainID:           big.NewInt(31337), // nolint
+		VerifyingContract: common.Address{},
+		MinGasPrice:       10, // nolint
+	}
+}
+
+func (s *ValidationSuite) TestItAcceptsAValidTransaction() {
+	typedData := paiodecoder.CreateTypedData(common.Address{}, 0, big.NewInt(10), nil, big.NewInt(31337)) // nolint
+	s.NoError(s.rules.validate(typedData))
+}
+
+func (s *ValidationSuite) TestItRejectsAnotherChain() {
+	typedData := paiodecoder.CreateTypedData(common.Address{}, 0, big.NewInt(10), nil, big.NewInt(1)) // nolint
+	err := s.rules.validate(typedData)
+	s.ErrorIs(err, ErrInvalidTransaction)
+	s.ErrorContains(err, "domain chainId 1 does not match the chain id 31337")
+}
+
+func (s *ValidationSuite) TestItRejectsAnotherVerifyingContract() {
+	typedData := paiodecoder.CreateTypedData(common.Address{}, 0, big.NewInt(10), nil, big.NewInt(31337)) // nolint
+	typedData.Domain.VerifyingContract = "0x0000000000000000000000000000000000000001"
+	err := s.rules.validate(typedData)
+	s.ErrorIs(err, ErrInvalidTransaction)
+	s.ErrorContains(err, "verifyingContract")
+}
+
+func (s *ValidationSuite) TestItRejectsALowGasPrice() {
+	typedData := paiodecoder.CreateTypedData(common.Address{}, 0, big.NewInt(9), nil, big.NewInt(31337)) // nolint
+	err := s.rules.validate(typedData)
+	s.ErrorIs(err, ErrInvalidTransaction)
+	s.ErrorContains(err, "max_gas_price 9 is lower than the minimum gas price 10")
+}
+
+func (s *ValidationSuite) TestItChecksTheNonce() {
+	s.NoError(checkNonce(3, 3))                                                   // nolint
+	s.ErrorContains(checkNonce(2, 3), "nonce 2 was already used, expected 3")     // nolint
+	s.ErrorContains(checkNonce(4, 3), "nonce 4 is ahead of the expected nonce 3") // nolint
+}
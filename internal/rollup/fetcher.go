@@ -2,8 +2,10 @@ package rollup
 
 import (
 	"net/http"
+	"time"
 
 	DA "github.com/calindra/nonodo/internal/dataavailability"
+	"github.com/calindra/nonodo/internal/metrics"
 	"github.com/labstack/echo/v4"
 )
 
@@ -16,8 +18,10 @@ func (r *RollupAPI) Fetcher(ctx echo.Context, request GioJSONRequestBody) (*GioR
 		its_ok   uint16 = 42
 	)
 
+	start := time.Now()
 	switch request.Domain {
 	case espresso:
+		defer observeFetch("espresso", start)
 		espressoFetcher := DA.NewEspressoFetcher(r.model.GetInputRepository())
 		data, err := espressoFetcher.Fetch(ctx, request.Id)
 
@@ -27,6 +31,7 @@ func (r *RollupAPI) Fetcher(ctx echo.Context, request GioJSONRequestBody) (*GioR
 
 		return &GioResponseRollup{Data: *data, Code: its_ok}, nil
 	case syscoin:
+		defer observeFetch("syscoin", start)
 		syscoinFetcher := DA.NewSyscoinClient()
 		data, err := syscoinFetcher.Fetch(ctx, request.Id)
 
@@ -36,6 +41,7 @@ func (r *RollupAPI) Fetcher(ctx echo.Context, request GioJSONRequestBody) (*GioR
 
 		return &GioResponseRollup{Data: *data, Code: its_ok}, nil
 	case celestia:
+		defer observeFetch("celestia", start)
 		celestiaFetcher := DA.NewCelestiaClient()
 		data, err := celestiaFetcher.Fetch(ctx, request.Id)
 
@@ -45,6 +51,7 @@ func (r *RollupAPI) Fetcher(ctx echo.Context, request GioJSONRequestBody) (*GioR
 
 		return &GioResponseRollup{Data: *data, Code: its_ok}, nil
 	case avail:
+		defer observeFetch("avail", start)
 		availFetcher := DA.NewAvailFetcher()
 		data, err := availFetcher.Fetch(ctx, request.Id)
 
@@ -58,3 +65,7 @@ func (r *RollupAPI) Fetcher(ctx echo.Context, request GioJSONRequestBody) (*GioR
 		return nil, DA.NewHttpCustomError(http.StatusBadRequest, &unsupported)
 	}
 }
+
+func observeFetch(domain string, start time.Time) {
+	metrics.FetchDuration.WithLabelValues(domain).Observe(time.Since(start).Seconds())
+}
@@ -14,23 +14,33 @@ import (
 	"net/http"
 
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/calindra/nonodo/internal/contracts"
 	mdl "github.com/calindra/nonodo/internal/model"
+	"github.com/calindra/nonodo/internal/tracing"
 	cModel "github.com/cartesi/rollups-graphql/pkg/convenience/model"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const FinishRetries = 50
 const FinishPollInterval = time.Millisecond * 100
 
-// Register the rollup API to echo
-func Register(e *echo.Echo, model *mdl.NonodoModel, sequencer Sequencer, applicationAddress common.Address) {
-	var rollupAPI ServerInterface = &RollupAPI{model, sequencer, applicationAddress}
+// Time after the last call to /finish in which the application is still considered attached.
+// The application polls again at most after FinishRetries * FinishPollInterval.
+const BackendTimeout = 2 * FinishRetries * FinishPollInterval
+
+// Register the rollup API to echo.
+// Return the tracker of the calls of the application.
+func Register(e *echo.Echo, model *mdl.NonodoModel, sequencer Sequencer, applicationAddress common.Address) *Backend {
+	backend := &Backend{}
+	var rollupAPI ServerInterface = &RollupAPI{model, sequencer, applicationAddress, backend}
 	RegisterHandlers(e, rollupAPI)
+	return backend
 }
 
 // Shared struct for request handlers.
@@ -38,6 +48,58 @@ type RollupAPI struct {
 	model              *mdl.NonodoModel
 	sequencer          Sequencer
 	ApplicationAddress common.Address
+	backend            *Backend
+}
+
+// Track the calls of the application to /finish, to tell whether it is attached.
+type Backend struct {
+	mutex      sync.Mutex
+	polling    int
+	processing bool
+	lastCall   time.Time
+}
+
+// Return whether the application is polling /finish, processing an input or called /finish
+// less than BackendTimeout ago, and the time of its last call.
+func (b *Backend) Attached() (bool, time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	attached := b.polling > 0 || b.processing ||
+		(!b.lastCall.IsZero() && time.Since(b.lastCall) < BackendTimeout)
+	return attached, b.lastCall
+}
+
+// Start the span of a call of the application, in the trace of its traceparent header or
+// else of the advance being processed.
+func (r *RollupAPI) startSpan(c echo.Context, name string) trace.Span {
+	ctx := tracing.Extract(c.Request().Context(), c.Request().Header)
+	if !trace.SpanContextFromContext(ctx).IsValid() && r.model != nil {
+		ctx = r.model.TraceContext(ctx)
+	}
+	_, span := tracing.Start(ctx, name)
+	return span
+}
+
+func (b *Backend) begin() {
+	if b == nil {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.polling++
+	b.processing = false
+	b.lastCall = time.Now()
+}
+
+func (b *Backend) end(processing bool) {
+	if b == nil {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.polling--
+	b.processing = processing
+	b.lastCall = time.Now()
 }
 
 type Sequencer interface {
@@ -46,6 +108,8 @@ type Sequencer interface {
 
 // AddDelegateCallVoucher implements ServerInterface.
 func (r *RollupAPI) AddDelegateCallVoucher(ctx echo.Context) error {
+	span := r.startSpan(ctx, "rollup.AddDelegateCallVoucher")
+	defer span.End()
 	if !checkContentType(ctx) {
 		return ctx.String(http.StatusUnsupportedMediaType, "invalid content type")
 	}
@@ -98,6 +162,8 @@ func (r *RollupAPI) AddDelegateCallVoucher(ctx echo.Context) error {
 
 // Gio implements ServerInterface.
 func (r *RollupAPI) Gio(ctx echo.Context) error {
+	span := r.startSpan(ctx, "rollup.Gio")
+	defer span.End()
 
 	if !checkContentType(ctx) {
 		return ctx.String(http.StatusUnsupportedMediaType, "invalid content type")
@@ -152,6 +218,9 @@ func (r *RollupAPI) Finish(c echo.Context) error {
 	if r.sequencer == nil {
 		return c.String(http.StatusInternalServerError, "sequencer not available")
 	}
+	r.backend.begin()
+	processing := false
+	defer func() { r.backend.end(processing) }()
 	for i := 0; i < FinishRetries; i++ {
 		input, err := r.sequencer.FinishAndGetNext(accepted)
 
@@ -161,12 +230,16 @@ func (r *RollupAPI) Finish(c echo.Context) error {
 		}
 
 		if input != nil {
+			processing = true
 			resp, err := convertInput(input)
 
 			if err != nil {
 				slog.Error("/finish convert input", "error", err)
 				return err
 			}
+			if r.model != nil {
+				tracing.Inject(r.model.TraceContext(c.Request().Context()), c.Response().Header())
+			}
 
 			return c.JSON(http.StatusOK, &resp)
 		}
@@ -182,6 +255,8 @@ func (r *RollupAPI) Finish(c echo.Context) error {
 
 // Handle requests to /voucher.
 func (r *RollupAPI) AddVoucher(c echo.Context) error {
+	span := r.startSpan(c, "rollup.AddVoucher")
+	defer span.End()
 	if !checkContentType(c) {
 		return c.String(http.StatusUnsupportedMediaType, "invalid content type")
 	}
@@ -244,6 +319,8 @@ func (r *RollupAPI) AddVoucher(c echo.Context) error {
 
 // Handle requests to /notice.
 func (r *RollupAPI) AddNotice(c echo.Context) error {
+	span := r.startSpan(c, "rollup.AddNotice")
+	defer span.End()
 	if !checkContentType(c) {
 		slog.Error("invalid notice content type")
 		return c.String(http.StatusUnsupportedMediaType, "invalid content type")
@@ -290,6 +367,8 @@ func (r *RollupAPI) AddNotice(c echo.Context) error {
 
 // Handle requests to /report.
 func (r *RollupAPI) AddReport(c echo.Context) error {
+	span := r.startSpan(c, "rollup.AddReport")
+	defer span.End()
 	if !checkContentType(c) {
 		return c.String(http.StatusUnsupportedMediaType, "invalid content type")
 	}
@@ -318,6 +397,8 @@ func (r *RollupAPI) AddReport(c echo.Context) error {
 
 // Handle requests to /exception.
 func (r *RollupAPI) RegisterException(c echo.Context) error {
+	span := r.startSpan(c, "rollup.RegisterException")
+	defer span.End()
 	if !checkContentType(c) {
 		return c.String(http.StatusUnsupportedMediaType, "invalid content type")
 	}
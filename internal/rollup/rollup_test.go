@@ -27,6 +27,11 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 const TestTimeout = 5 * time.Second
@@ -222,6 +227,40 @@ func (s *RollupSuite) TestEncodeNotice() {
 	s.Equal(fmt.Sprintf("0x%s", common.Bytes2Hex(deb)), noticesResp.Rows[0].Payload)
 }
 
+func (s *RollupSuite) TestItTracksTheBackend() {
+	backend := &Backend{}
+	s.rollupsAPI.(*RollupAPI).backend = backend
+	attached, lastCall := backend.Attached()
+	s.False(attached)
+	s.True(lastCall.IsZero())
+
+	s.addNewAdvanceInput(1)
+	s.hitFinish()
+	attached, lastCall = backend.Attached()
+	s.True(attached)
+	s.False(lastCall.IsZero())
+
+	// the application is processing the input, however long it takes
+	backend.lastCall = time.Now().Add(-BackendTimeout)
+	attached, _ = backend.Attached()
+	s.True(attached)
+	backend.processing = false
+	attached, _ = backend.Attached()
+	s.False(attached)
+}
+
+func (s *RollupSuite) TestItSendsTheTraceOfTheInput() {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider())
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+	s.addNewAdvanceInput(1)
+	rec := s.hitFinish()
+	traceparent := rec.Header().Get("traceparent")
+	s.NotEmpty(traceparent)
+	advance := trace.SpanContextFromContext(s.model.TraceContext(context.Background()))
+	s.Contains(traceparent, advance.TraceID().String())
+}
+
 func (s *RollupSuite) addNewAdvanceInput(inputBoxIndex int) {
 	destination := common.HexToAddress("0xab7528bb862fb57e8a2bcd567a2e929a0be56a5e")
 	payloadHex := "0xdeadbeef"
@@ -233,7 +272,7 @@ func (s *RollupSuite) addNewAdvanceInput(inputBoxIndex int) {
 	s.NoError(err)
 }
 
-func (s *RollupSuite) hitFinish() {
+func (s *RollupSuite) hitFinish() *httptest.ResponseRecorder {
 	finishReq := FinishJSONRequestBody{
 		Status: Accept,
 	}
@@ -248,4 +287,5 @@ func (s *RollupSuite) hitFinish() {
 	res1 := s.rollupsAPI.Finish(c1)
 	s.NoError(res1, "Finish should not return an error")
 	s.Assert().Equal(http.StatusOK, rec1.Result().StatusCode)
+	return rec1
 }
@@ -39,10 +39,7 @@ type AvailClient struct {
 }
 
 func NewAvailClient(graphQLUrl string, chainId int, appId int) (*AvailClient, error) {
-	apiURL, hasEnv := os.LookupEnv("AVAIL_RPC_URL")
-	if !hasEnv {
-		apiURL = DEFAULT_AVAIL_RPC_URL
-	}
+	apiURL := availRpcUrl()
 	if graphQLUrl == "" {
 		graphQLUrl = DEFAULT_GRAPHQL_URL
 	}
@@ -50,6 +47,20 @@ func NewAvailClient(graphQLUrl string, chainId int, appId int) (*AvailClient, er
 	return &client, nil
 }
 
+// Use the Avail node of the url instead of AVAIL_RPC_URL.
+func (av *AvailClient) WithRpcUrl(rpcUrl string) *AvailClient {
+	av.apiURL = rpcUrl
+	return av
+}
+
+// Url of the Avail node from AVAIL_RPC_URL, or the default url.
+func availRpcUrl() string {
+	if rpcUrl, ok := os.LookupEnv("AVAIL_RPC_URL"); ok {
+		return rpcUrl
+	}
+	return DEFAULT_AVAIL_RPC_URL
+}
+
 func (av *AvailClient) Submit712(ctx context.Context, payload string, dappAddress string, maxGasPrice uint64) (*types.Hash, error) {
 	nonce, err := fetchNonce(DEFAULT_USER_ADDRESS, av.GraphQLUrl)
 
@@ -0,0 +1,524 @@
+package avail
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/hash"
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types/codec"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gorilla/websocket"
+)
+
+// Default time between the blocks of the Avail emulator.
+const DefaultEmulatorBlockTime = 2 * time.Second
+
+// Default port of the Avail emulator, the same of the Substrate nodes.
+const DefaultEmulatorPort = 9944
+
+// Indices of the pallets of the emulator, the same of the Avail runtime.
+const (
+	systemPalletIndex           = 0
+	timestampPalletIndex        = TIMESTAMP_SECTION_INDEX
+	dataAvailabilityPalletIndex = 29
+)
+
+const (
+	emulatorSpecVersion        = 1
+	emulatorTransactionVersion = 1
+)
+
+var errUnknownBlock = errors.New("unknown block")
+
+// Local stand-in for an Avail node, with the subset of the JSON-RPC API used by nonodo.
+//
+// It produces a block every BlockTime with a timestamp extrinsic and the extrinsics submitted
+// since the previous one, and notifies the new heads to the subscribers. The signatures of the
+// extrinsics are not checked; the emulator only keeps the nonce of each signer.
+// The API is served over websocket, and over HTTP for the methods without subscriptions.
+type AvailEmulator struct {
+	BlockTime time.Duration
+	// Clock of the block timestamps; time.Now when nil.
+	Clock func() time.Time
+
+	mutex         sync.Mutex
+	metadata      *types.Metadata
+	blocks        []emulatorBlock
+	hashes        map[types.Hash]int
+	pending       []types.Extrinsic
+	nonces        map[types.AccountID]uint32
+	subscriptions map[string]*emulatorConn
+	lastID        uint64
+	upgrader      websocket.Upgrader
+}
+
+type emulatorBlock struct {
+	hash       types.Hash
+	header     types.Header
+	extrinsics []types.Extrinsic
+}
+
+func NewAvailEmulator(blockTime time.Duration) *AvailEmulator {
+	return &AvailEmulator{
+		BlockTime:     blockTime,
+		metadata:      newEmulatorMetadata(),
+		hashes:        map[types.Hash]int{},
+		nonces:        map[types.AccountID]uint32{},
+		subscriptions: map[string]*emulatorConn{},
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+func (a *AvailEmulator) String() string {
+	return "avail_emulator"
+}
+
+func (a *AvailEmulator) Start(ctx context.Context, ready chan<- struct{}) error {
+	// the genesis block, whose hash signs the extrinsics
+	if err := a.ProduceBlock(); err != nil {
+		return err
+	}
+	slog.Info("avail emulator started", "blockTime", a.BlockTime)
+	ready <- struct{}{}
+	ticker := time.NewTicker(a.BlockTime)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := a.ProduceBlock(); err != nil {
+				slog.Error("avail emulator: failed to produce block", "error", err)
+			}
+		}
+	}
+}
+
+// Produce a block with the timestamp and the pending extrinsics, and notify the subscribers.
+func (a *AvailEmulator) ProduceBlock() error {
+	now := time.Now
+	if a.Clock != nil {
+		now = a.Clock
+	}
+	args, err := codec.Encode(types.NewUCompactFromUInt(uint64(now().UnixMilli())))
+	if err != nil {
+		return fmt.Errorf("avail emulator: encode timestamp: %w", err)
+	}
+	timestamp := types.Extrinsic{
+		Version: types.ExtrinsicVersion4,
+		Method: types.Call{
+			CallIndex: types.CallIndex{SectionIndex: timestampPalletIndex, MethodIndex: 0},
+			Args:      args,
+		},
+	}
+
+	a.mutex.Lock()
+	number := len(a.blocks)
+	block := emulatorBlock{
+		header:     types.Header{Number: types.BlockNumber(number)},
+		extrinsics: append([]types.Extrinsic{timestamp}, a.pending...),
+	}
+	if number > 0 {
+		block.header.ParentHash = a.blocks[number-1].hash
+	}
+	block.header.ExtrinsicsRoot, err = blake2b256(block.extrinsics)
+	if err == nil {
+		block.hash, err = blake2b256(block.header)
+	}
+	if err != nil {
+		a.mutex.Unlock()
+		return fmt.Errorf("avail emulator: hash block: %w", err)
+	}
+	a.blocks = append(a.blocks, block)
+	a.hashes[block.hash] = number
+	a.pending = nil
+	subscriptions := maps.Clone(a.subscriptions)
+	a.mutex.Unlock()
+
+	if len(block.extrinsics) > 1 {
+		slog.Info("avail emulator: block produced", "number", number, "extrinsics", len(block.extrinsics)-1)
+	}
+	for id, conn := range subscriptions {
+		conn.notify("chain_newHead", id, block.header)
+	}
+	return nil
+}
+
+// Queue the extrinsic for the next block and return its hash.
+func (a *AvailEmulator) Submit(ext types.Extrinsic) (types.Hash, error) {
+	extHash, err := blake2b256(ext)
+	if err != nil {
+		return types.Hash{}, fmt.Errorf("avail emulator: hash extrinsic: %w", err)
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.pending = append(a.pending, ext)
+	if ext.IsSigned() && ext.Signature.Signer.IsID {
+		a.nonces[ext.Signature.Signer.AsID]++
+	}
+	slog.Debug("avail emulator: extrinsic submitted", "hash", extHash.Hex(),
+		"appID", ext.Signature.AppID.Int64())
+	return extHash, nil
+}
+
+// Number of the latest block; zero before the first block is produced.
+func (a *AvailEmulator) Height() uint64 {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if len(a.blocks) == 0 {
+		return 0
+	}
+	return uint64(len(a.blocks) - 1)
+}
+
+func blake2b256(value any) (types.Hash, error) {
+	encoded, err := codec.Encode(value)
+	if err != nil {
+		return types.Hash{}, err
+	}
+	hasher, err := hash.NewBlake2b256(nil)
+	if err != nil {
+		return types.Hash{}, err
+	}
+	hasher.Write(encoded)
+	return types.NewHash(hasher.Sum(nil)), nil
+}
+
+// Metadata with the storage and calls used by the Avail client.
+func newEmulatorMetadata() *types.Metadata {
+	metadata := types.NewMetadataV13()
+	metadata.MagicNumber = types.MagicNumber
+	metadata.AsMetadataV13.Modules = []types.ModuleMetadataV13{
+		{
+			Name:       "System",
+			HasStorage: true,
+			Storage: types.StorageMetadataV13{
+				Prefix: "System",
+				Items: []types.StorageFunctionMetadataV13{{
+					Name:     "Account",
+					Modifier: types.StorageFunctionModifierV0{IsDefault: true},
+					Type: types.StorageFunctionTypeV13{
+						IsMap: true,
+						AsMap: types.MapTypeV10{
+							Hasher: types.StorageHasherV10{IsBlake2_128Concat: true},
+							Key:    "AccountId",
+							Value:  "AccountInfo",
+						},
+					},
+				}},
+			},
+			Index: systemPalletIndex,
+		},
+		{
+			Name:     "Timestamp",
+			HasCalls: true,
+			Calls: []types.FunctionMetadataV4{{
+				Name: "set",
+				Args: []types.FunctionArgumentMetadata{{Name: "now", Type: "Compact<Moment>"}},
+			}},
+			Index: timestampPalletIndex,
+		},
+		{
+			Name:     "DataAvailability",
+			HasCalls: true,
+			Calls: []types.FunctionMetadataV4{
+				{
+					Name: "create_application_key",
+					Args: []types.FunctionArgumentMetadata{{Name: "key", Type: "Vec<u8>"}},
+				},
+				{
+					Name: "submit_data",
+					Args: []types.FunctionArgumentMetadata{{Name: "data", Type: "Vec<u8>"}},
+				},
+			},
+			Index: dataAvailabilityPalletIndex,
+		},
+	}
+	metadata.AsMetadataV13.Extrinsic = types.ExtrinsicV11{Version: types.ExtrinsicVersion4}
+	return metadata
+}
+
+// JSON-RPC
+
+type emulatorRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type emulatorResponse struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *emulatorError  `json:"error,omitempty"`
+}
+
+type emulatorError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type emulatorNotification struct {
+	Version string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  struct {
+		Subscription string `json:"subscription"`
+		Result       any    `json:"result"`
+	} `json:"params"`
+}
+
+// Websocket connection of a client.
+type emulatorConn struct {
+	ws    *websocket.Conn
+	mutex sync.Mutex
+}
+
+func (c *emulatorConn) write(message any) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if err := c.ws.WriteJSON(message); err != nil {
+		slog.Debug("avail emulator: write error", "error", err)
+	}
+}
+
+func (c *emulatorConn) notify(method string, subscription string, result any) {
+	notification := emulatorNotification{Version: "2.0", Method: method}
+	notification.Params.Subscription = subscription
+	notification.Params.Result = result
+	c.write(notification)
+}
+
+// Serve the JSON-RPC API; websocket connections may subscribe to the new heads.
+func (a *AvailEmulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		ws, err := a.upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			slog.Error("avail emulator: websocket upgrade", "error", err)
+			return
+		}
+		a.serveConn(&emulatorConn{ws: ws})
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "expected a JSON-RPC request", http.StatusMethodNotAllowed)
+		return
+	}
+	var request emulatorRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(a.handle(nil, request)); err != nil {
+		slog.Debug("avail emulator: write error", "error", err)
+	}
+}
+
+func (a *AvailEmulator) serveConn(conn *emulatorConn) {
+	defer func() {
+		a.mutex.Lock()
+		maps.DeleteFunc(a.subscriptions, func(_ string, c *emulatorConn) bool { return c == conn })
+		a.mutex.Unlock()
+		conn.ws.Close()
+	}()
+	for {
+		var request emulatorRequest
+		if err := conn.ws.ReadJSON(&request); err != nil {
+			return
+		}
+		conn.write(a.handle(conn, request))
+	}
+}
+
+func (a *AvailEmulator) handle(conn *emulatorConn, request emulatorRequest) emulatorResponse {
+	response := emulatorResponse{Version: "2.0", ID: request.ID}
+	result, err := a.call(conn, request.Method, request.Params)
+	if err == nil {
+		response.Result, err = json.Marshal(result)
+	}
+	if err != nil {
+		const invalidParams = -32602
+		response.Result = nil
+		response.Error = &emulatorError{Code: invalidParams, Message: err.Error()}
+	}
+	return response
+}
+
+func (a *AvailEmulator) call(conn *emulatorConn, method string, params []json.RawMessage) (any, error) {
+	switch method {
+	case "chain_getHeader":
+		block, err := a.blockByHash(params, 0)
+		if err != nil {
+			return nil, err
+		}
+		return block.header, nil
+	case "chain_getBlock":
+		block, err := a.blockByHash(params, 0)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{
+			"block": map[string]any{
+				"header":     block.header,
+				"extrinsics": block.extrinsics,
+			},
+			"justification": nil,
+		}, nil
+	case "chain_getBlockHash":
+		return a.blockHash(params)
+	case "chain_getFinalizedHead":
+		block, err := a.blockByHash(nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		return block.hash, nil
+	case "chain_subscribeNewHead", "chain_subscribeNewHeads":
+		if conn == nil {
+			return nil, fmt.Errorf("subscriptions require a websocket connection")
+		}
+		a.mutex.Lock()
+		defer a.mutex.Unlock()
+		a.lastID++
+		id := strconv.FormatUint(a.lastID, 10)
+		a.subscriptions[id] = conn
+		return id, nil
+	case "chain_unsubscribeNewHead", "chain_unsubscribeNewHeads":
+		var id string
+		if err := param(params, 0, &id); err != nil {
+			return nil, err
+		}
+		a.mutex.Lock()
+		defer a.mutex.Unlock()
+		_, ok := a.subscriptions[id]
+		delete(a.subscriptions, id)
+		return ok, nil
+	case "state_getMetadata":
+		return codec.EncodeToHex(a.metadata)
+	case "state_getRuntimeVersion":
+		return map[string]any{
+			"specName":           "avail-emulator",
+			"implName":           "nonodo",
+			"authoringVersion":   1,
+			"specVersion":        emulatorSpecVersion,
+			"implVersion":        0,
+			"transactionVersion": emulatorTransactionVersion,
+			"apis":               []any{},
+		}, nil
+	case "state_getStorage":
+		return a.storage(params)
+	case "author_submitExtrinsic":
+		var encoded string
+		if err := param(params, 0, &encoded); err != nil {
+			return nil, err
+		}
+		var ext types.Extrinsic
+		if err := codec.DecodeFromHex(encoded, &ext); err != nil {
+			return nil, fmt.Errorf("invalid extrinsic: %w", err)
+		}
+		return a.Submit(ext)
+	case "system_chain":
+		return "Avail Emulator", nil
+	case "system_name":
+		return "nonodo", nil
+	case "system_health":
+		return map[string]any{"peers": 0, "isSyncing": false, "shouldHavePeers": false}, nil
+	default:
+		return nil, fmt.Errorf("method %s not supported by the avail emulator", method)
+	}
+}
+
+// Decode the optional parameter at the index; missing and null parameters are left untouched.
+func param(params []json.RawMessage, index int, value any) error {
+	if index >= len(params) || string(params[index]) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(params[index], value); err != nil {
+		return fmt.Errorf("invalid param %d: %w", index, err)
+	}
+	return nil
+}
+
+// Block of the hash parameter at the index, or the latest block.
+func (a *AvailEmulator) blockByHash(params []json.RawMessage, index int) (*emulatorBlock, error) {
+	var blockHash *types.Hash
+	if err := param(params, index, &blockHash); err != nil {
+		return nil, err
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	number := len(a.blocks) - 1
+	if blockHash != nil {
+		n, ok := a.hashes[*blockHash]
+		if !ok {
+			return nil, fmt.Errorf("%w %s", errUnknownBlock, blockHash.Hex())
+		}
+		number = n
+	}
+	if number < 0 {
+		return nil, errUnknownBlock
+	}
+	return &a.blocks[number], nil
+}
+
+// Hash of the block number parameter, or of the latest block; null for future blocks.
+func (a *AvailEmulator) blockHash(params []json.RawMessage) (*types.Hash, error) {
+	var number *hexutil.Uint64
+	if len(params) > 0 && len(params[0]) > 0 && params[0][0] != '"' {
+		// the Go client sends the number as a JSON number, other clients as hex
+		var n uint64
+		if err := param(params, 0, &n); err != nil {
+			return nil, err
+		}
+		number = (*hexutil.Uint64)(&n)
+	} else if err := param(params, 0, &number); err != nil {
+		return nil, err
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if number == nil {
+		if len(a.blocks) == 0 {
+			return nil, nil
+		}
+		return &a.blocks[len(a.blocks)-1].hash, nil
+	}
+	if uint64(*number) >= uint64(len(a.blocks)) {
+		return nil, nil
+	}
+	return &a.blocks[*number].hash, nil
+}
+
+// Read the storage key parameter; only the accounts are stored.
+func (a *AvailEmulator) storage(params []json.RawMessage) (any, error) {
+	var key hexutil.Bytes
+	if err := param(params, 0, &key); err != nil {
+		return nil, err
+	}
+	// the key of an account ends with the account, as the hasher is blake2_128_concat
+	if len(key) < types.AccountIDLen {
+		return nil, nil
+	}
+	account := types.AccountID(key[len(key)-types.AccountIDLen:])
+	accountKey, err := types.CreateStorageKey(a.metadata, "System", "Account", account[:])
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(key, accountKey) {
+		return nil, nil
+	}
+	a.mutex.Lock()
+	info := types.AccountInfo{Nonce: types.U32(a.nonces[account]), Providers: 1}
+	a.mutex.Unlock()
+	return codec.EncodeToHex(info)
+}
@@ -0,0 +1,129 @@
+package avail
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calindra/nonodo/internal/sequencers/paiodecoder"
+	gsrpc "github.com/centrifuge/go-substrate-rpc-client/v4"
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/suite"
+)
+
+type AvailEmulatorSuite struct {
+	suite.Suite
+	ctx      context.Context
+	cancel   context.CancelFunc
+	emulator *AvailEmulator
+	server   *httptest.Server
+	url      string
+	api      *gsrpc.SubstrateAPI
+	now      time.Time
+}
+
+func TestAvailEmulatorSuite(t *testing.T) {
+	suite.Run(t, new(AvailEmulatorSuite))
+}
+
+func (s *AvailEmulatorSuite) SetupTest() {
+	const testTimeout = 10 * time.Second
+	s.ctx, s.cancel = context.WithTimeout(context.Background(), testTimeout)
+	s.now = time.UnixMilli(1727357780000) // nolint
+	s.emulator = NewAvailEmulator(time.Hour)
+	s.emulator.Clock = func() time.Time { return s.now }
+	s.Require().NoError(s.emulator.ProduceBlock())
+	s.server = httptest.NewServer(s.emulator)
+	s.url = "ws" + strings.TrimPrefix(s.server.URL, "http")
+	api, err := NewSubstrateAPICtx(s.ctx, s.url)
+	s.Require().NoError(err)
+	s.api = api
+}
+
+func (s *AvailEmulatorSuite) TearDownTest() {
+	s.api.Client.Close()
+	s.server.Close()
+	s.cancel()
+}
+
+func (s *AvailEmulatorSuite) block(number uint64) *types.SignedBlock {
+	hash, err := s.api.RPC.Chain.GetBlockHash(number)
+	s.Require().NoError(err)
+	block, err := s.api.RPC.Chain.GetBlock(hash)
+	s.Require().NoError(err)
+	return block
+}
+
+func (s *AvailEmulatorSuite) TestItProducesBlocksWithTimestamps() {
+	header, err := s.api.RPC.Chain.GetHeaderLatest()
+	s.Require().NoError(err)
+	s.Equal(types.BlockNumber(0), header.Number)
+
+	s.now = s.now.Add(20 * time.Second) // nolint
+	s.Require().NoError(s.emulator.ProduceBlock())
+	block := s.block(1)
+	s.Equal(types.BlockNumber(1), block.Block.Header.Number)
+	genesis, err := s.api.RPC.Chain.GetBlockHash(0)
+	s.Require().NoError(err)
+	s.Equal(genesis, block.Block.Header.ParentHash)
+	timestamp, err := ReadTimestampFromBlock(block)
+	s.Require().NoError(err)
+	s.Equal(uint64(1727357800000), timestamp)
+	s.Equal(uint64(1), s.emulator.Height())
+}
+
+func (s *AvailEmulatorSuite) TestTheHeightStartsAtZero() {
+	s.Equal(uint64(0), NewAvailEmulator(time.Hour).Height())
+	s.Equal(uint64(0), s.emulator.Height())
+}
+
+func (s *AvailEmulatorSuite) TestItNotifiesTheNewHeads() {
+	subscription, err := s.api.RPC.Chain.SubscribeNewHeads()
+	s.Require().NoError(err)
+	defer subscription.Unsubscribe()
+	s.Require().NoError(s.emulator.ProduceBlock())
+	select {
+	case header := <-subscription.Chan():
+		s.Equal(types.BlockNumber(1), header.Number)
+	case err := <-subscription.Err():
+		s.Fail("subscription error", err)
+	case <-s.ctx.Done():
+		s.Fail("no new head")
+	}
+}
+
+func (s *AvailEmulatorSuite) TestItSequencesTheSubmittedData() {
+	client, err := NewAvailClient("", DEFAULT_CHAINID_HARDHAT, DEFAULT_APP_ID)
+	s.Require().NoError(err)
+	client.WithRpcUrl(s.url)
+	// nolint
+	batch := "0x1463f9725f107358c9115bc9d86c72dd5823e9b1e60114ab7528bb862fb57e8a2bcd567a2e929a0be56a5e000a0d48656c6c6f2c20576f726c643f2076a270f52ade97cd95ef7be45e08ea956bfdaf14b7fc4f8816207fa9eb3a5c17207ccdd94ac1bd86a749b66526fff6579e2b6bf1698e831955332ad9d5ed44da7208000000000000001c"
+	data := string(common.FromHex(batch))
+	for range 2 {
+		_, err = client.SubmitData(s.ctx, data, s.url, DEFAULT_EVM_MNEMONIC, DEFAULT_APP_ID)
+		s.Require().NoError(err)
+	}
+	s.Require().NoError(s.emulator.ProduceBlock())
+
+	block := s.block(1)
+	s.Require().Len(block.Block.Extrinsics, 3) // nolint
+	for i, ext := range block.Block.Extrinsics[1:] {
+		s.True(ext.IsSigned())
+		s.Equal(int64(DEFAULT_APP_ID), ext.Signature.AppID.Int64())
+		s.Equal(int64(i), ext.Signature.Nonce.Int64())
+		decoded, err := paiodecoder.NativeDecoder{}.DecodePaioBatch(s.ctx, ext.Method.Args)
+		s.Require().NoError(err)
+		s.Contains(decoded, `"nonce":0`)
+	}
+	s.Empty(s.block(0).Block.Extrinsics[1:])
+}
+
+func (s *AvailEmulatorSuite) TestItRejectsUnknownBlocks() {
+	_, err := s.api.RPC.Chain.GetBlock(types.NewHash(make([]byte, 32))) // nolint
+	s.ErrorContains(err, "unknown block")
+	_, err = s.api.RPC.Chain.GetBlockHash(5) // nolint
+	s.Error(err)
+}
@@ -9,9 +9,10 @@ import (
 	"time"
 
 	"github.com/calindra/nonodo/internal/contracts"
+	"github.com/calindra/nonodo/internal/metrics"
 	"github.com/calindra/nonodo/internal/sequencers/inputter"
+	"github.com/calindra/nonodo/internal/sequencers/ordering"
 	"github.com/calindra/nonodo/internal/sequencers/paiodecoder"
-	"github.com/calindra/nonodo/internal/supervisor"
 	"github.com/cartesi/rollups-graphql/pkg/commons"
 	cModel "github.com/cartesi/rollups-graphql/pkg/convenience/model"
 	cRepos "github.com/cartesi/rollups-graphql/pkg/convenience/repository"
@@ -38,6 +39,10 @@ type AvailListener struct {
 	L1CurrentBlock     uint64
 	ApplicationAddress common.Address
 	L1ReadDelay        int
+	// Avail node; AVAIL_RPC_URL or the default url when empty.
+	RpcUrl string
+	// Merge the L1 inputs with the Avail blocks; by timestamp minus L1ReadDelay when nil.
+	Orderer *ordering.Orderer
 }
 
 type PaioDecoder interface {
@@ -45,12 +50,11 @@ type PaioDecoder interface {
 }
 
 func NewAvailListener(availFromBlock uint64, repository *cRepos.InputRepository,
-	w *inputter.InputterWorker, fromBlock uint64, binaryDecoderPathLocation string,
+	w *inputter.InputterWorker, fromBlock uint64, paioDecoder PaioDecoder,
 	applicationAddress string,
-) supervisor.Worker {
-	var paioDecoder PaioDecoder = paiodecoder.ZzzzHuiDecoder{}
-	if binaryDecoderPathLocation != "" {
-		paioDecoder = paiodecoder.NewPaioDecoder(binaryDecoderPathLocation)
+) AvailListener {
+	if paioDecoder == nil {
+		paioDecoder = paiodecoder.NativeDecoder{}
 	}
 	l1ReadDelay := FIVE_MINUTES
 	l1ReadDelayStr, ok := os.LookupEnv("L1_READ_DELAY_IN_SECONDS")
@@ -88,9 +92,9 @@ func (a AvailListener) Start(ctx context.Context, ready chan<- struct{}) error {
 }
 
 func (a AvailListener) connect(ctx context.Context) (*gsrpc.SubstrateAPI, error) {
-	rpcURL, haveURL := os.LookupEnv("AVAIL_RPC_URL")
-	if !haveURL {
-		rpcURL = DEFAULT_AVAIL_RPC_URL
+	rpcURL := a.RpcUrl
+	if rpcURL == "" {
+		rpcURL = availRpcUrl()
 	}
 
 	errCh := make(chan error)
@@ -106,6 +110,7 @@ func (a AvailListener) connect(ctx context.Context) (*gsrpc.SubstrateAPI, error)
 				if err != nil {
 					slog.Error("Avail", "Error connecting to Avail client", err)
 					slog.Info("Avail reconnecting client", "retryInterval", retryInterval)
+					metrics.Reconnects.WithLabelValues("avail").Inc()
 					time.Sleep(retryInterval)
 				} else {
 					clientCh <- client
@@ -146,6 +151,7 @@ func (a AvailListener) watchNewTransactions(ctx context.Context, client *gsrpc.S
 			if err != nil {
 				slog.Error("Avail", "Error getting latest block hash", err)
 				slog.Info("Avail reconnecting", "retryInterval", retryInterval)
+				metrics.Reconnects.WithLabelValues("avail").Inc()
 				time.Sleep(retryInterval)
 				continue
 			}
@@ -158,6 +164,7 @@ func (a AvailListener) watchNewTransactions(ctx context.Context, client *gsrpc.S
 		if err != nil {
 			slog.Error("Avail", "Error subscribing to new heads", err)
 			slog.Info("Avail reconnecting", "retryInterval", retryInterval)
+			metrics.Reconnects.WithLabelValues("avail").Inc()
 			time.Sleep(retryInterval)
 			continue
 		}
@@ -224,6 +231,7 @@ func (a AvailListener) watchNewTransactions(ctx context.Context, client *gsrpc.S
 		if err != nil {
 			slog.Error("Avail", "Error", err)
 			slog.Info("Avail reconnecting", "retryInterval", retryInterval)
+			metrics.Reconnects.WithLabelValues("avail").Inc()
 			time.Sleep(retryInterval)
 		} else {
 			return nil
@@ -249,9 +257,8 @@ func (a AvailListener) TableTennis(ctx context.Context,
 	} else {
 		availBlockTimestamp = uint64(availInputs[0].BlockTimestamp.Unix())
 	}
-	inputsL1, err := a.InputterWorker.FindAllInputsByBlockAndTimestampLT(ctx,
-		ethClient, inputBox, startBlockNumber,
-		(availBlockTimestamp/ONE_SECOND_IN_MS)-uint64(a.L1ReadDelay),
+	inputsL1, err := a.InputterWorker.FindAllInputsByBlockRange(ctx,
+		ethClient, inputBox, startBlockNumber, nil,
 	)
 	if err != nil {
 		return nil, err
@@ -259,40 +266,57 @@ func (a AvailListener) TableTennis(ctx context.Context,
 	if len(inputsL1) > 0 {
 		currentL1Block = inputsL1[len(inputsL1)-1].BlockNumber + 1
 	}
-	inputs := append(inputsL1, availInputs...)
-	if len(inputs) > 0 {
-		inputCount, err := a.InputRepository.Count(ctx, nil)
-		if err != nil {
-			return nil, err
-		}
-		for i := range inputs {
-			inputs[i].Index = i + int(inputCount)
-			if inputs[i].AppContract != a.ApplicationAddress {
-				slog.Warn("Skipping input",
-					"appContract", inputs[i].AppContract.Hex(),
-					"expected", a.ApplicationAddress.Hex(),
-					"msgSender", inputs[i].MsgSender.Hex(),
-					"payload", inputs[i].Payload,
-				)
-				continue
-			}
-			// The chainId information does not come in Paio's batch.
-			_, err = a.InputRepository.Create(ctx, inputs[i])
-			if err != nil {
-				return nil, err
-			}
-			slog.Info("Input saved",
-				"ID", inputs[i].ID,
-				"index", inputs[i].Index,
-				"appContract", inputs[i].AppContract.Hex(),
-				"msgSender", inputs[i].MsgSender.Hex(),
-				"payload", inputs[i].Payload,
+	availAppInputs := make([]cModel.AdvanceInput, 0, len(availInputs))
+	for _, input := range availInputs {
+		if input.AppContract != a.ApplicationAddress {
+			slog.Warn("Skipping input",
+				"appContract", input.AppContract.Hex(),
+				"expected", a.ApplicationAddress.Hex(),
+				"msgSender", input.MsgSender.Hex(),
+				"payload", input.Payload,
 			)
+			continue
 		}
+		availAppInputs = append(availAppInputs, input)
+	}
+	// The chainId information does not come in Paio's batch.
+	inputs, held, err := a.orderer().Order(ctx, inputsL1, ordering.L2Block{
+		Source:    "avail",
+		Number:    uint64(block.Block.Header.Number),
+		Timestamp: time.UnixMilli(int64(availBlockTimestamp)),
+		Inputs:    availAppInputs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(held) > 0 {
+		// read the held inputs again with the next block
+		currentL1Block = held[0].BlockNumber
+	}
+	for _, input := range inputs {
+		slog.Info("Input saved",
+			"ID", input.ID,
+			"index", input.Index,
+			"appContract", input.AppContract.Hex(),
+			"msgSender", input.MsgSender.Hex(),
+			"payload", input.Payload,
+		)
 	}
 	return &currentL1Block, nil
 }
 
+func (a AvailListener) orderer() *ordering.Orderer {
+	if a.Orderer != nil {
+		return a.Orderer
+	}
+	orderer := ordering.NewOrderer(ordering.PolicyTimestamp,
+		&ordering.RepositoryWriter{Repository: a.InputRepository},
+		a.InputRepository,
+	)
+	orderer.ReadDelay = time.Duration(a.L1ReadDelay) * time.Second
+	return orderer
+}
+
 func (av *AvailListener) ReadInputsFromPaioBlock(ctx context.Context, block *types.SignedBlock) ([]cModel.AdvanceInput, error) {
 	inputs := []cModel.AdvanceInput{}
 	timestamp, err := ReadTimestampFromBlock(block)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"math/big"
+	"net/http/httptest"
 	"os/exec"
 	"strings"
 	"testing"
@@ -176,6 +177,76 @@ func (s *AvailListenerSuite) TestTableTennis() {
 	s.Equal("Hello, World?", string(common.Hex2Bytes(hexPayloadWithoutPrefix)))
 }
 
+func (s *AvailListenerSuite) TestTheListenerInterleavesTheEmulatorBlocksWithTheL1() {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	appAddress := common.HexToAddress(devnet.ApplicationAddress)
+	err := devnet.AddInput(ctx, s.rpcUrl, common.Hex2Bytes("deadbeef11"))
+	s.Require().NoError(err)
+
+	// the Avail blocks are ahead of the L1 by more than the read delay
+	const l1ReadDelay = 300
+	emulator := NewAvailEmulator(time.Hour)
+	emulator.Clock = func() time.Time { return time.Now().Add((l1ReadDelay + 50) * time.Second) }
+	s.Require().NoError(emulator.ProduceBlock())
+	server := httptest.NewServer(emulator)
+	defer server.Close()
+	availUrl := "ws" + strings.TrimPrefix(server.URL, "http")
+	client, err := NewAvailClient("", DEFAULT_CHAINID_HARDHAT, DEFAULT_APP_ID)
+	s.Require().NoError(err)
+	_, err = client.WithRpcUrl(availUrl).SubmitData(ctx, "batch", availUrl, DEFAULT_EVM_MNEMONIC, DEFAULT_APP_ID)
+	s.Require().NoError(err)
+	s.Require().NoError(emulator.ProduceBlock())
+
+	s.DbFactory = commons.NewDbFactory()
+	db := s.DbFactory.CreateDb("input.sqlite3")
+	inputRepository := &repository.InputRepository{Db: *db}
+	s.Require().NoError(inputRepository.CreateTables())
+	listener := AvailListener{
+		PaioDecoder:     &FakeDecoder{},
+		InputRepository: inputRepository,
+		InputterWorker: &inputter.InputterWorker{
+			Provider:           s.rpcUrl,
+			InputBoxAddress:    common.HexToAddress(devnet.InputBoxAddress),
+			InputBoxBlock:      1,
+			ApplicationAddress: appAddress,
+		},
+		AvailFromBlock:     1,
+		ApplicationAddress: appAddress,
+		L1ReadDelay:        l1ReadDelay,
+		RpcUrl:             availUrl,
+	}
+	result := make(chan error, 1)
+	go func() {
+		result <- listener.Start(ctx, make(chan struct{}, 1))
+	}()
+
+	// the listener reads the blocks when it is notified of a new head
+	ticker := time.NewTicker(100 * time.Millisecond) // nolint
+	defer ticker.Stop()
+	for {
+		inputs, err := inputRepository.FindAll(ctx, nil, nil, nil, nil, nil)
+		s.Require().NoError(err)
+		if inputs.Total == 2 { // nolint
+			s.Equal("0xdeadbeef11", inputs.Rows[0].Payload)
+			s.Equal(0, inputs.Rows[0].Index)
+			s.Equal("Hello, World?", string(common.FromHex(inputs.Rows[1].Payload)))
+			s.Equal(1, inputs.Rows[1].Index)
+			break
+		}
+		select {
+		case err := <-result:
+			s.FailNow("listener exited", err)
+		case <-ctx.Done():
+			s.FailNow("inputs not read", ctx.Err())
+		case <-ticker.C:
+			s.Require().NoError(emulator.ProduceBlock())
+		}
+	}
+	cancel()
+	s.ErrorIs(<-result, context.Canceled)
+}
+
 type FakeDecoder struct {
 }
 
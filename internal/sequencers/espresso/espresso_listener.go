@@ -13,13 +13,17 @@ import (
 	"strings"
 	"time"
 
+	espressoTypes "github.com/EspressoSystems/espresso-sequencer-go/types"
 	"github.com/calindra/nonodo/internal/contracts"
 	"github.com/calindra/nonodo/internal/dataavailability"
+	"github.com/calindra/nonodo/internal/metrics"
 	"github.com/calindra/nonodo/internal/sequencers/inputter"
+	"github.com/calindra/nonodo/internal/sequencers/ordering"
 	"github.com/cartesi/rollups-graphql/pkg/commons"
 	cModel "github.com/cartesi/rollups-graphql/pkg/convenience/model"
 	cRepos "github.com/cartesi/rollups-graphql/pkg/convenience/repository"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/tidwall/gjson"
@@ -33,6 +37,8 @@ type EspressoListener struct {
 	fromBlock       uint64
 	InputterWorker  *inputter.InputterWorker
 	fromBlockL1     *uint64
+	// Merge the L1 inputs with the Espresso blocks; by the finalized L1 block when nil.
+	Orderer *ordering.Orderer
 }
 
 func (e EspressoListener) String() string {
@@ -57,6 +63,16 @@ func NewEspressoListener(
 	}
 }
 
+func (e EspressoListener) orderer() *ordering.Orderer {
+	if e.Orderer != nil {
+		return e.Orderer
+	}
+	return ordering.NewOrderer(ordering.PolicyL1Finalized,
+		&ordering.RepositoryWriter{Repository: e.InputRepository},
+		e.InputRepository,
+	)
+}
+
 func (e EspressoListener) getBaseUrl() string {
 	return e.espressoUrl
 }
@@ -80,14 +96,13 @@ func (e EspressoListener) watchNewTransactions(ctx context.Context) error {
 		currentBlockHeight = lastEspressoBlockHeight
 		slog.Info("Espresso: starting from latest block height", "lastEspressoBlockHeight", lastEspressoBlockHeight)
 	}
-	previousBlockHeight := currentBlockHeight
-	var l1FinalizedPrevHeight uint64
+	var l1NextBlock uint64
 	if e.fromBlockL1 != nil {
-		l1FinalizedPrevHeight = *e.fromBlockL1
+		l1NextBlock = *e.fromBlockL1
 	} else {
-		l1FinalizedPrevHeight = e.getL1FinalizedHeight(previousBlockHeight)
+		l1NextBlock = e.getL1FinalizedHeight(currentBlockHeight)
 	}
-	slog.Info("Espresso: starting l1 block from", "blockNumber", l1FinalizedPrevHeight)
+	slog.Info("Espresso: starting l1 block from", "blockNumber", l1NextBlock)
 
 	var delay time.Duration = 800
 
@@ -101,6 +116,7 @@ func (e EspressoListener) watchNewTransactions(ctx context.Context) error {
 				return err
 			}
 			slog.Warn("Espresso: error fetching the latest block height. Retrying...", "error", err)
+			metrics.Reconnects.WithLabelValues("espresso").Inc()
 			time.Sleep(delay * time.Millisecond)
 			continue
 		}
@@ -119,141 +135,177 @@ func (e EspressoListener) watchNewTransactions(ctx context.Context) error {
 					return err
 				}
 				slog.Warn("Espresso: error fetching transactions. Retrying...", "blockHeight", currentBlockHeight, "namespace", e.namespace, "error", err)
+				metrics.Reconnects.WithLabelValues("espresso").Inc()
 				time.Sleep(delay * time.Millisecond)
 				continue
 			}
 			tot := len(transactions.Transactions)
 
-			// read inputbox
 			l1FinalizedCurrentHeight := e.getL1FinalizedHeight(currentBlockHeight)
-			// read L1 if there might be update
-			if l1FinalizedCurrentHeight > l1FinalizedPrevHeight || currentBlockHeight == e.fromBlock {
-				slog.Debug("L1 finalized", "from", l1FinalizedPrevHeight, "to", l1FinalizedCurrentHeight)
-				slog.Debug("Fetching InputBox between Espresso blocks", "from", previousBlockHeight, "to", currentBlockHeight)
-				err = readInputBox(ctx, l1FinalizedPrevHeight, l1FinalizedCurrentHeight, e.InputterWorker)
+			orderer := e.orderer()
+			// read the InputBox up to the finalized L1 block or, by the other policies, up to the latest one
+			var l1EndBlock *uint64
+			if orderer.Policy == ordering.PolicyL1Finalized {
+				l1EndBlock = &l1FinalizedCurrentHeight
+			}
+			inputsL1 := []cModel.AdvanceInput{}
+			if l1EndBlock == nil || *l1EndBlock >= l1NextBlock {
+				slog.Debug("Fetching InputBox for the Espresso block", "from", l1NextBlock, "to", l1EndBlock,
+					"espressoBlock", currentBlockHeight)
+				inputsL1, err = readInputBox(ctx, l1NextBlock, l1EndBlock, e.InputterWorker)
 				if err != nil {
 					return err
 				}
-				l1FinalizedPrevHeight = l1FinalizedCurrentHeight
 			}
 
 			slog.Debug("Espresso:", "transactionsLen", tot)
-			for i := 0; i < tot; i++ {
-				transaction := transactions.Transactions[i]
-				slog.Debug("Espresso:", "currentBlockHeight", currentBlockHeight)
-
-				ctx := context.Background()
-				// transform and add to InputRepository
-				index, err := e.InputRepository.Count(ctx, nil)
-				if err != nil {
-					return err
+			inputs, err := e.readInputsFromTransactions(ctx, currentBlockHeight, l1FinalizedCurrentHeight, transactions.Transactions)
+			if err != nil {
+				return err
+			}
+			added, held, err := orderer.Order(ctx, inputsL1, ordering.L2Block{
+				Source:      "espresso",
+				Number:      currentBlockHeight,
+				Timestamp:   e.getEspressoTimestamp(currentBlockHeight),
+				L1Finalized: l1FinalizedCurrentHeight,
+				Inputs:      inputs,
+			})
+			if err != nil {
+				return err
+			}
+			for _, input := range added {
+				if input.Type == "Espresso" {
+					slog.Info("Espresso: input added", "index", input.Index, "payload", input.Payload)
 				}
+			}
+			if len(held) > 0 {
+				l1NextBlock = held[0].BlockNumber
+			} else if l1EndBlock != nil && *l1EndBlock >= l1NextBlock {
+				l1NextBlock = *l1EndBlock + 1
+			} else if len(inputsL1) > 0 {
+				l1NextBlock = inputsL1[len(inputsL1)-1].BlockNumber + 1
+			}
+		}
+	}
+}
 
-				// assume the following encoding
-				// transaction = JSON.stringify({
-				//		 	signature,
-				//		 	typedData: btoa(JSON.stringify(typedData)),
-				//		 })
-				msgSender, typedData, signature, err := commons.ExtractSigAndData(string(transaction))
-				if err != nil {
-					slog.Warn("Ignoring transaction", "blockHeight", currentBlockHeight, "transactionIndex", i, "error", err)
-					continue
-				}
-				// type EspressoMessage struct {
-				// 	nonce   uint32 `json:"nonce"`
-				// 	payload string `json:"payload"`
-				// }
-				nonceField := typedData.Message["nonce"]
-
-				var nonce int64
-				// Request tx is coming from nonodo, the nonce at this point returns as string always
-				if _, ok := nonceField.(string); ok {
-					strNonce := nonceField.(string)
-					parsedNonce, err := strconv.ParseInt(strNonce, 10, 64)
-					if err != nil {
-						return fmt.Errorf("error converting nonce from string to int64: %v", err)
-					}
-					nonce = parsedNonce
-				} else if _, ok := nonceField.(float64); ok {
-					// When coming from other sources, the nonce at this point is a float
-					nonce = int64(nonceField.(float64))
-				} else {
-					//
-					return fmt.Errorf("error converting nonce: %T", nonceField)
-				}
+// Read the inputs of the application from the transactions of an Espresso block.
+// The nonce of each one must follow the stored inputs and the previous transactions of the block.
+func (e EspressoListener) readInputsFromTransactions(
+	ctx context.Context,
+	currentBlockHeight uint64,
+	l1FinalizedHeight uint64,
+	transactions []espressoTypes.Bytes,
+) ([]cModel.AdvanceInput, error) {
+	inputs := []cModel.AdvanceInput{}
+	sequenced := map[common.Address]uint64{}
+	var prevRandao string
+	for i, transaction := range transactions {
+		slog.Debug("Espresso:", "currentBlockHeight", currentBlockHeight)
 
-				app, ok := typedData.Message["app"].(string)
-				if !ok {
-					return fmt.Errorf("message app address type assertion error")
-				}
-				appContract := common.HexToAddress(app)
+		// assume the following encoding
+		// transaction = JSON.stringify({
+		//		 	signature,
+		//		 	typedData: btoa(JSON.stringify(typedData)),
+		//		 })
+		msgSender, typedData, signature, err := commons.ExtractSigAndData(string(transaction))
+		if err != nil {
+			slog.Warn("Ignoring transaction", "blockHeight", currentBlockHeight, "transactionIndex", i, "error", err)
+			continue
+		}
+		// type EspressoMessage struct {
+		// 	nonce   uint32 `json:"nonce"`
+		// 	payload string `json:"payload"`
+		// }
+		nonceField := typedData.Message["nonce"]
 
-				if appContract.Hex() != e.InputterWorker.ApplicationAddress.Hex() {
-					slog.Debug("Espresso: ignoring transaction for other app", "txApp", appContract.Hex(), "expectedApp", e.InputterWorker.ApplicationAddress.Hex())
-					continue
-				}
+		var nonce int64
+		// Request tx is coming from nonodo, the nonce at this point returns as string always
+		if _, ok := nonceField.(string); ok {
+			strNonce := nonceField.(string)
+			parsedNonce, err := strconv.ParseInt(strNonce, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("error converting nonce from string to int64: %v", err)
+			}
+			nonce = parsedNonce
+		} else if _, ok := nonceField.(float64); ok {
+			// When coming from other sources, the nonce at this point is a float
+			nonce = int64(nonceField.(float64))
+		} else {
+			//
+			return nil, fmt.Errorf("error converting nonce: %T", nonceField)
+		}
 
-				payload, ok := typedData.Message["data"].(string)
-				if !ok {
-					return fmt.Errorf("message data type assertion error")
-				}
-				slog.Debug("Espresso input", "msgSender", msgSender, "nonce", nonce, "payload", payload)
+		app, ok := typedData.Message["app"].(string)
+		if !ok {
+			return nil, fmt.Errorf("message app address type assertion error")
+		}
+		appContract := common.HexToAddress(app)
 
-				// update nonce maps
-				// no need to consider node exits abruptly and restarts from where it left
-				// app has to start `nonce` from 1 and increment by 1 for each payload
-				dbNonce, err := e.InputRepository.GetNonce(ctx, appContract, msgSender)
-				if err != nil {
-					slog.Error("calculate internal nonce error",
-						"appContract", appContract,
-						"msgSender", msgSender,
-						"error", err,
-					)
-					return err
-				}
-				if int64(dbNonce) != nonce {
-					slog.Warn("duplicated or incorrect nonce",
-						"nonce", nonce,
-						"expected", dbNonce,
-						"appContract", appContract,
-						"msgSender", msgSender,
-					)
-					continue
-				}
+		if appContract.Hex() != e.InputterWorker.ApplicationAddress.Hex() {
+			slog.Debug("Espresso: ignoring transaction for other app", "txApp", appContract.Hex(), "expectedApp", e.InputterWorker.ApplicationAddress.Hex())
+			continue
+		}
 
-				payload = strings.TrimPrefix(payload, "0x")
+		payload, ok := typedData.Message["data"].(string)
+		if !ok {
+			return nil, fmt.Errorf("message data type assertion error")
+		}
+		slog.Debug("Espresso input", "msgSender", msgSender, "nonce", nonce, "payload", payload)
 
-				chainId := (*big.Int)(typedData.Domain.ChainId).String()
-				slog.Debug("TypedData", "typedData.Domain", typedData.Domain,
-					"chainId", chainId,
-				)
-				blockNumber := e.getL1FinalizedHeight(currentBlockHeight)
-				prevRandao, err := readPrevRandao(ctx, currentBlockHeight, e.InputterWorker)
-				if err != nil {
-					return err
-				}
-				_, err = e.InputRepository.Create(ctx, cModel.AdvanceInput{
-					ID:                     common.Bytes2Hex(crypto.Keccak256(signature)),
-					Index:                  int(index),
-					MsgSender:              msgSender,
-					Payload:                payload,
-					BlockNumber:            blockNumber,
-					BlockTimestamp:         e.getL1FinalizedTimestamp(currentBlockHeight),
-					AppContract:            e.InputterWorker.ApplicationAddress,
-					EspressoBlockNumber:    int(currentBlockHeight),
-					EspressoBlockTimestamp: e.getEspressoTimestamp(currentBlockHeight),
-					InputBoxIndex:          -1,
-					Type:                   "Espresso",
-					ChainId:                chainId,
-					PrevRandao:             prevRandao,
-				})
-				slog.Info("Espresso: input added", "payload", payload)
-				if err != nil {
-					return err
-				}
+		// update nonce maps
+		// no need to consider node exits abruptly and restarts from where it left
+		// app has to start `nonce` from 1 and increment by 1 for each payload
+		dbNonce, err := e.InputRepository.GetNonce(ctx, appContract, msgSender)
+		if err != nil {
+			slog.Error("calculate internal nonce error",
+				"appContract", appContract,
+				"msgSender", msgSender,
+				"error", err,
+			)
+			return nil, err
+		}
+		expected := dbNonce + sequenced[msgSender]
+		if int64(expected) != nonce {
+			slog.Warn("duplicated or incorrect nonce",
+				"nonce", nonce,
+				"expected", expected,
+				"appContract", appContract,
+				"msgSender", msgSender,
+			)
+			continue
+		}
+		sequenced[msgSender]++
+
+		payload = strings.TrimPrefix(payload, "0x")
+
+		chainId := (*big.Int)(typedData.Domain.ChainId).String()
+		slog.Debug("TypedData", "typedData.Domain", typedData.Domain,
+			"chainId", chainId,
+		)
+		if prevRandao == "" {
+			prevRandao, err = readPrevRandao(ctx, l1FinalizedHeight, e.InputterWorker)
+			if err != nil {
+				return nil, err
 			}
 		}
+		inputs = append(inputs, cModel.AdvanceInput{
+			// the same transaction id returned by /submit
+			ID:                     hexutil.Encode(crypto.Keccak256(signature)),
+			MsgSender:              msgSender,
+			Payload:                payload,
+			BlockNumber:            l1FinalizedHeight,
+			BlockTimestamp:         e.getL1FinalizedTimestamp(currentBlockHeight),
+			AppContract:            e.InputterWorker.ApplicationAddress,
+			EspressoBlockNumber:    int(currentBlockHeight),
+			EspressoBlockTimestamp: e.getEspressoTimestamp(currentBlockHeight),
+			InputBoxIndex:          -1,
+			Type:                   "Espresso",
+			ChainId:                chainId,
+			PrevRandao:             prevRandao,
+		})
 	}
+	return inputs, nil
 }
 
 func (e EspressoListener) readEspressoHeader(espressoBlockHeight uint64) string {
@@ -292,26 +344,27 @@ func (e EspressoListener) getL1FinalizedHeight(espressoBlockHeight uint64) uint6
 
 func (e EspressoListener) getEspressoTimestamp(espressoBlockHeight uint64) time.Time {
 	espressoHeader := e.readEspressoHeader(espressoBlockHeight)
-	value := gjson.Get(espressoHeader, "timestamp")
+	value := gjson.Get(espressoHeader, "fields.timestamp")
 	return time.Unix(value.Int(), 0)
 }
 
-func readInputBox(ctx context.Context, l1FinalizedPrevHeight uint64, l1FinalizedCurrentHeight uint64, w *inputter.InputterWorker) error {
+func readInputBox(ctx context.Context, l1FromBlock uint64, l1ToBlock *uint64, w *inputter.InputterWorker) ([]cModel.AdvanceInput, error) {
 	client, err := ethclient.DialContext(ctx, w.Provider)
 	if err != nil {
-		return fmt.Errorf("espresso inputter: dial: %w", err)
+		return nil, fmt.Errorf("espresso inputter: dial: %w", err)
 	}
+	defer client.Close()
 	inputBox, err := contracts.NewInputBox(w.InputBoxAddress, client)
 	if err != nil {
-		return fmt.Errorf("espresso inputter: bind input box: %w", err)
+		return nil, fmt.Errorf("espresso inputter: bind input box: %w", err)
 	}
 
-	err = w.ReadPastInputs(ctx, client, inputBox, l1FinalizedPrevHeight, &l1FinalizedCurrentHeight)
+	inputs, err := w.FindAllInputsByBlockRange(ctx, client, inputBox, l1FromBlock, l1ToBlock)
 	if err != nil {
-		return fmt.Errorf("espresso inputter: read past inputs: %w", err)
+		return nil, fmt.Errorf("espresso inputter: read past inputs: %w", err)
 	}
 
-	return nil
+	return inputs, nil
 }
 
 func readPrevRandao(ctx context.Context, l1FinalizedCurrentHeight uint64, w *inputter.InputterWorker) (string, error) {
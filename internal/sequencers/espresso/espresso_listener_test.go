@@ -7,10 +7,13 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/EspressoSystems/espresso-sequencer-go/types"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -18,19 +21,19 @@ type EspressoListenerSuite struct {
 	suite.Suite
 }
 
-func (s *EspressoListenerSuite) XTestSendTransaction() {
-	// this test is just to understand the api
+func (s *EspressoListenerSuite) TestSendTransaction() {
 	ctx := context.Background()
-	url := "https://query.cappuccino.testnet.espresso.network/"
-	// espressoClient := client.NewClient(url)
+	mock := NewEspressoMock("", time.Hour)
+	e := echo.New()
+	mock.Register(e, MockPath)
+	server := httptest.NewServer(e)
+	defer server.Close()
+	url := server.URL + MockPath + "/"
 	tx := types.Transaction{
 		Namespace: 10008,                        // any number...
 		Payload:   common.Hex2Bytes("deadbeef"), // any payload...
 	}
-	// err := espressoClient.SubmitTransaction(ctx, tx)
-	// s.NoError(err)
-
-	// the func above is a copy from espressoClient.SubmitTransaction
+	// the func below is a copy from espressoClient.SubmitTransaction
 	txHash, err := submitTransactionWithResp(ctx, http.DefaultClient, url, tx)
 	s.NoError(err)
 	s.NotEmpty(txHash)
@@ -0,0 +1,251 @@
+package espresso
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	tagged_base64 "github.com/EspressoSystems/espresso-sequencer-go/tagged-base64"
+	"github.com/EspressoSystems/espresso-sequencer-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/labstack/echo/v4"
+)
+
+// Default time between the blocks of the Espresso mock.
+const DefaultMockBlockTime = time.Second
+
+// Path of the Espresso mock in the nonodo HTTP server.
+const MockPath = "/espresso"
+
+// L1 client used to read the finalized block referenced by the Espresso headers.
+type L1HeaderReader interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*ethtypes.Header, error)
+}
+
+// Local stand-in for the Espresso query and submit services.
+//
+// It produces a block every BlockTime with the transactions submitted since the previous one.
+// Each header references the finalized block of the L1 chain, like the real sequencer does,
+// so the listener reads the InputBox up to that block before the transactions of the block.
+// The routes are served with and without the /v0 prefix, because the client and the listener
+// use different base paths.
+type EspressoMock struct {
+	RpcUrl    string
+	BlockTime time.Duration
+	// L1 client; dialed from RpcUrl when nil.
+	L1 L1HeaderReader
+
+	mutex   sync.Mutex
+	blocks  []mockBlock
+	pending []types.Transaction
+}
+
+type mockBlock struct {
+	header       mockHeader
+	transactions []types.Transaction
+}
+
+// Header in the versioned format of the query service.
+type mockHeader struct {
+	Version mockVersion      `json:"version"`
+	Fields  mockHeaderFields `json:"fields"`
+}
+
+type mockVersion struct {
+	Version struct {
+		Major int `json:"major"`
+		Minor int `json:"minor"`
+	} `json:"Version"`
+}
+
+type mockHeaderFields struct {
+	Height      uint64          `json:"height"`
+	Timestamp   uint64          `json:"timestamp"`
+	L1Head      uint64          `json:"l1_head"`
+	L1Finalized mockL1BlockInfo `json:"l1_finalized"`
+}
+
+type mockL1BlockInfo struct {
+	Number    uint64      `json:"number"`
+	Timestamp string      `json:"timestamp"`
+	Hash      common.Hash `json:"hash"`
+}
+
+func NewEspressoMock(rpcUrl string, blockTime time.Duration) *EspressoMock {
+	return &EspressoMock{
+		RpcUrl:    rpcUrl,
+		BlockTime: blockTime,
+	}
+}
+
+func (m *EspressoMock) String() string {
+	return "espresso_mock"
+}
+
+func (m *EspressoMock) Start(ctx context.Context, ready chan<- struct{}) error {
+	if m.L1 == nil {
+		client, err := ethclient.DialContext(ctx, m.RpcUrl)
+		if err != nil {
+			return fmt.Errorf("espresso mock: dial: %w", err)
+		}
+		defer client.Close()
+		m.L1 = client
+	}
+	// the genesis block, so the listener has a header to start from
+	if err := m.ProduceBlock(ctx); err != nil {
+		return err
+	}
+	slog.Info("espresso mock started", "blockTime", m.BlockTime)
+	ready <- struct{}{}
+	ticker := time.NewTicker(m.BlockTime)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := m.ProduceBlock(ctx); err != nil {
+				slog.Error("espresso mock: failed to produce block", "error", err)
+			}
+		}
+	}
+}
+
+// Produce a block with the pending transactions.
+func (m *EspressoMock) ProduceBlock(ctx context.Context) error {
+	l1Head, err := m.L1.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("espresso mock: read L1 head: %w", err)
+	}
+	finalized, err := m.L1.HeaderByNumber(ctx, big.NewInt(int64(rpc.FinalizedBlockNumber)))
+	if err != nil {
+		return fmt.Errorf("espresso mock: read L1 finalized block: %w", err)
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	height := uint64(len(m.blocks))
+	block := mockBlock{
+		transactions: m.pending,
+		header: mockHeader{
+			Fields: mockHeaderFields{
+				Height:    height,
+				Timestamp: uint64(time.Now().Unix()),
+				L1Head:    l1Head.Number.Uint64(),
+				L1Finalized: mockL1BlockInfo{
+					Number:    finalized.Number.Uint64(),
+					Timestamp: hexutil.EncodeUint64(finalized.Time),
+					Hash:      finalized.Hash(),
+				},
+			},
+		},
+	}
+	block.header.Version.Version.Minor = 1
+	m.blocks = append(m.blocks, block)
+	m.pending = nil
+	if len(block.transactions) > 0 {
+		slog.Info("espresso mock: block produced", "height", height,
+			"transactions", len(block.transactions), "l1Finalized", finalized.Number)
+	}
+	return nil
+}
+
+// Queue the transaction for the next block and return its hash.
+func (m *EspressoMock) Submit(tx types.Transaction) (*types.TaggedBase64, error) {
+	commit := tx.Commit()
+	hash, err := tagged_base64.New("TX", commit[:])
+	if err != nil {
+		return nil, err
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.pending = append(m.pending, tx)
+	slog.Debug("espresso mock: transaction submitted", "hash", hash.String(), "namespace", tx.Namespace)
+	return hash, nil
+}
+
+func (m *EspressoMock) block(c echo.Context) (*mockBlock, error) {
+	height, err := strconv.ParseUint(c.Param("height"), 10, 64)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid height")
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if height >= uint64(len(m.blocks)) {
+		return nil, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("block %d not found", height))
+	}
+	return &m.blocks[height], nil
+}
+
+// Register the query and submit routes of the mock under the given path.
+func (m *EspressoMock) Register(e *echo.Echo, path string) {
+	for _, prefix := range []string{path, path + "/v0"} {
+		g := e.Group(prefix)
+		g.GET("/status/block-height", func(c echo.Context) error {
+			m.mutex.Lock()
+			defer m.mutex.Unlock()
+			return c.JSON(http.StatusOK, len(m.blocks))
+		})
+		g.GET("/availability/header/:height", func(c echo.Context) error {
+			block, err := m.block(c)
+			if err != nil {
+				return err
+			}
+			return c.JSON(http.StatusOK, block.header)
+		})
+		g.GET("/availability/block/:height/namespace/:namespace", func(c echo.Context) error {
+			block, err := m.block(c)
+			if err != nil {
+				return err
+			}
+			namespace, err := strconv.ParseUint(c.Param("namespace"), 10, 64)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "invalid namespace")
+			}
+			transactions := []types.Transaction{}
+			for _, tx := range block.transactions {
+				if tx.Namespace == namespace {
+					transactions = append(transactions, tx)
+				}
+			}
+			// the client requires a proof with the transactions, but doesn't check it
+			var proof any
+			if len(transactions) > 0 {
+				proof = map[string]any{}
+			}
+			return c.JSON(http.StatusOK, map[string]any{
+				"transactions": transactions,
+				"proof":        proof,
+			})
+		})
+		g.GET("/availability/vid/common/:height", func(c echo.Context) error {
+			block, err := m.block(c)
+			if err != nil {
+				return err
+			}
+			return c.JSON(http.StatusOK, types.VidCommonQueryData{
+				Height: block.header.Fields.Height,
+				Common: types.VidCommon("null"),
+			})
+		})
+		g.POST("/submit/submit", func(c echo.Context) error {
+			var tx types.Transaction
+			if err := c.Bind(&tx); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+			hash, err := m.Submit(tx)
+			if err != nil {
+				return err
+			}
+			return c.JSON(http.StatusOK, hash)
+		})
+	}
+}
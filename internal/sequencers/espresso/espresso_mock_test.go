@@ -0,0 +1,190 @@
+package espresso
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/EspressoSystems/espresso-sequencer-go/client"
+	"github.com/calindra/nonodo/internal/sequencers/inputter"
+	"github.com/calindra/nonodo/internal/sequencers/paiodecoder"
+	"github.com/cartesi/rollups-graphql/pkg/commons"
+	"github.com/cartesi/rollups-graphql/pkg/convenience"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/jmoiron/sqlx"
+	"github.com/labstack/echo/v4"
+	_ "github.com/ncruces/go-sqlite3/driver"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	"github.com/stretchr/testify/suite"
+)
+
+type EspressoMockSuite struct {
+	suite.Suite
+	mock   *EspressoMock
+	server *httptest.Server
+	url    string
+	client *client.Client
+}
+
+func TestEspressoMockSuite(t *testing.T) {
+	suite.Run(t, new(EspressoMockSuite))
+}
+
+func (s *EspressoMockSuite) SetupTest() {
+	s.mock = NewEspressoMock("", time.Hour)
+	s.mock.L1 = fakeL1{}
+	e := echo.New()
+	s.mock.Register(e, MockPath)
+	s.server = httptest.NewServer(e)
+	s.url = s.server.URL + MockPath
+	s.client = client.NewClient(s.url)
+	s.Require().NoError(s.mock.ProduceBlock(context.Background()))
+}
+
+func (s *EspressoMockSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *EspressoMockSuite) TestItProducesBlocks() {
+	ctx := context.Background()
+	height, err := s.client.FetchLatestBlockHeight(ctx)
+	s.Require().NoError(err)
+	s.Equal(uint64(1), height)
+
+	s.Require().NoError(s.mock.ProduceBlock(ctx))
+	height, err = s.client.FetchLatestBlockHeight(ctx)
+	s.Require().NoError(err)
+	s.Equal(uint64(2), height)
+
+	transactions, err := s.client.FetchTransactionsInBlock(ctx, 1, 10008) // nolint
+	s.Require().NoError(err)
+	s.Empty(transactions.Transactions)
+}
+
+func (s *EspressoMockSuite) TestItSequencesTheSubmittedTransactions() {
+	ctx := context.Background()
+	espressoClient := EspressoClient{EspressoUrl: s.url}
+	sigAndData := commons.SigAndData{Signature: "0x01", TypedData: "e30="}
+	hash, err := espressoClient.SubmitSigAndData(10008, sigAndData) // nolint
+	s.Require().NoError(err)
+	s.Contains(hash, "TX~")
+	s.Require().NoError(s.mock.ProduceBlock(ctx))
+
+	transactions, err := s.client.FetchTransactionsInBlock(ctx, 1, 10008) // nolint
+	s.Require().NoError(err)
+	s.Require().Len(transactions.Transactions, 1)
+	expected, err := json.Marshal(sigAndData)
+	s.Require().NoError(err)
+	// the listener reads the transaction as the JSON sent by the client
+	s.JSONEq(string(expected), string(transactions.Transactions[0]))
+
+	transactions, err = s.client.FetchTransactionsInBlock(ctx, 1, 1)
+	s.Require().NoError(err)
+	s.Empty(transactions.Transactions)
+}
+
+func (s *EspressoMockSuite) TestTheHeadersReferenceTheFinalizedL1Block() {
+	listener := EspressoListener{espressoUrl: s.url}
+	s.Equal(uint64(36), listener.getL1FinalizedHeight(0))                  // nolint
+	s.Equal(int64(1700000036), listener.getL1FinalizedTimestamp(0).Unix()) // nolint
+	s.WithinDuration(time.Now(), listener.getEspressoTimestamp(0), time.Minute)
+}
+
+func (s *EspressoMockSuite) TestItDoesNotFindFutureBlocks() {
+	_, err := s.client.FetchTransactionsInBlock(context.Background(), 5, 1) // nolint
+	s.ErrorContains(err, "404")
+}
+
+func (s *EspressoMockSuite) TestTheListenerKeepsTheIdOfTheSubmittedTransaction() {
+	ctx := context.Background()
+	app := common.HexToAddress("0xab7528bb862fb57e8a2bcd567a2e929a0be56a5e")
+	key, err := crypto.GenerateKey()
+	s.Require().NoError(err)
+	typedData := paiodecoder.CreateTypedData(app, 0, big.NewInt(10), []byte{1}, big.NewInt(31337)) // nolint
+	typedDataJSON, err := json.Marshal(typedData)
+	s.Require().NoError(err)
+	s.Require().NoError(json.Unmarshal(typedDataJSON, &typedData))
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	s.Require().NoError(err)
+	signature, err := crypto.Sign(hash, key)
+	s.Require().NoError(err)
+	signature[64] += 27 // nolint
+	espressoClient := EspressoClient{EspressoUrl: s.url}
+	_, err = espressoClient.SubmitSigAndData(10008, commons.SigAndData{ // nolint
+		Signature: hexutil.Encode(signature),
+		TypedData: base64.StdEncoding.EncodeToString(typedDataJSON),
+	})
+	s.Require().NoError(err)
+	s.Require().NoError(s.mock.ProduceBlock(ctx))
+	transactions, err := s.client.FetchTransactionsInBlock(ctx, 1, 10008) // nolint
+	s.Require().NoError(err)
+
+	db := sqlx.MustConnect("sqlite3", ":memory:")
+	defer db.Close()
+	repository := convenience.NewContainer(*db, false).GetInputRepository()
+	rpcServer := rpc.NewServer()
+	s.Require().NoError(rpcServer.RegisterName("eth", fakeEth{}))
+	listener := EspressoListener{
+		espressoUrl:     s.url,
+		InputRepository: repository,
+		InputterWorker: &inputter.InputterWorker{
+			ApplicationAddress: app,
+			EthClient:          ethclient.NewClient(rpc.DialInProc(rpcServer)),
+		},
+	}
+	inputs, err := listener.readInputsFromTransactions(ctx, 1, 36, transactions.Transactions) // nolint
+	s.Require().NoError(err)
+	s.Require().Len(inputs, 1)
+	// /submit returns the hash of the signature with the recovery id as v
+	signature[64] -= 27
+	txId := hexutil.Encode(crypto.Keccak256(signature))
+	s.Equal(txId, inputs[0].ID)
+	s.Equal(crypto.PubkeyToAddress(key.PublicKey), inputs[0].MsgSender)
+
+	_, err = repository.Create(ctx, inputs[0])
+	s.Require().NoError(err)
+	stored, err := repository.FindByIDAndAppContract(ctx, txId, nil)
+	s.Require().NoError(err)
+	s.NotNil(stored)
+}
+
+// L1 chain whose finalized block is 64 blocks behind the head.
+type fakeL1 struct{}
+
+// L1 node that serves the headers of fakeL1.
+type fakeEth struct{}
+
+func (fakeEth) GetBlockByNumber(number string, full bool) (*ethtypes.Header, error) {
+	blockNumber, err := hexutil.DecodeBig(number)
+	if err != nil {
+		return nil, err
+	}
+	header, err := fakeL1{}.HeaderByNumber(context.Background(), blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	header.Difficulty = big.NewInt(0)
+	header.Extra = []byte{}
+	return header, nil
+}
+
+func (fakeL1) HeaderByNumber(ctx context.Context, number *big.Int) (*ethtypes.Header, error) {
+	blockNumber := int64(100) // nolint
+	if number != nil && number.Int64() == int64(rpc.FinalizedBlockNumber) {
+		blockNumber = 36 // nolint
+	}
+	return &ethtypes.Header{
+		Number: big.NewInt(blockNumber),
+		Time:   uint64(1700000000 + blockNumber), // nolint
+	}, nil
+}
@@ -9,13 +9,17 @@ import (
 	"log/slog"
 	"math/big"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/calindra/nonodo/internal/contracts"
+	"github.com/calindra/nonodo/internal/metrics"
+	"github.com/calindra/nonodo/internal/tracing"
 	cModel "github.com/cartesi/rollups-graphql/pkg/convenience/model"
 	cRepos "github.com/cartesi/rollups-graphql/pkg/convenience/repository"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 )
 
@@ -41,6 +45,33 @@ type InputterWorker struct {
 	ApplicationAddress common.Address
 	Repository         cRepos.InputRepository
 	EthClient          *ethclient.Client
+	// Records the blocks read, when set.
+	Progress *Progress
+}
+
+// Blocks read by the inputter, for the health checks.
+type Progress struct {
+	mutex sync.Mutex
+	block uint64
+	live  bool
+}
+
+// Return the last block read and whether the inputter is subscribed to the new inputs, so it
+// has read every block up to the head.
+func (p *Progress) Get() (uint64, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.block, p.live
+}
+
+func (p *Progress) set(block uint64, live bool) {
+	if p == nil {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.block = max(p.block, block)
+	p.live = live
 }
 
 func (w InputterWorker) String() string {
@@ -133,17 +164,28 @@ func (w InputterWorker) watchNewInputs(
 	currentBlock := w.InputBoxBlock
 
 	for {
+		w.Progress.set(0, false)
+		head, err := client.BlockNumber(ctx)
+		if err != nil {
+			slog.Error("Inputter", "error", err)
+			slog.Info("Inputter reconnecting", "reconnectDelay", reconnectDelay)
+			metrics.Reconnects.WithLabelValues("inputter").Inc()
+			time.Sleep(reconnectDelay)
+			continue
+		}
 		// First, read the event logs to get the past inputs; then, watch the event logs to get the
 		// new ones. There is a race condition where we might lose inputs sent between the
 		// readPastInputs call and the watchNewInputs call. Given that nonodo is a development node,
 		// we accept this race condition.
-		err := w.ReadPastInputs(ctx, client, inputBox, currentBlock, nil)
+		err = w.ReadPastInputs(ctx, client, inputBox, currentBlock, nil)
 		if err != nil {
 			slog.Error("Inputter", "error", err)
 			slog.Info("Inputter reconnecting", "reconnectDelay", reconnectDelay)
+			metrics.Reconnects.WithLabelValues("inputter").Inc()
 			time.Sleep(reconnectDelay)
 			continue
 		}
+		w.Progress.set(head, false)
 
 		// Create a new subscription
 		logs := make(chan *contracts.InputBoxInputAdded)
@@ -155,10 +197,13 @@ func (w InputterWorker) watchNewInputs(
 		if err != nil {
 			slog.Error("Inputter", "error", err)
 			slog.Info("Inputter reconnecting", "reconnectDelay", reconnectDelay)
+			metrics.Reconnects.WithLabelValues("inputter").Inc()
 			time.Sleep(reconnectDelay)
 			continue
 		}
 
+		w.Progress.set(head, true)
+
 		// Handle the subscription in a separate goroutine
 		errCh := make(chan error, 1)
 		go func() {
@@ -172,6 +217,7 @@ func (w InputterWorker) watchNewInputs(
 					return
 				case event := <-logs:
 					currentBlock = event.Raw.BlockNumber - 1
+					w.Progress.set(event.Raw.BlockNumber, true)
 					if err := w.addInput(ctx, client, event); err != nil {
 						errCh <- err
 						return
@@ -190,6 +236,7 @@ func (w InputterWorker) watchNewInputs(
 		if err != nil {
 			slog.Error("Inputter", "error", err)
 			slog.Info("Inputter reconnecting", "reconnectDelay", reconnectDelay)
+			metrics.Reconnects.WithLabelValues("inputter").Inc()
 			time.Sleep(reconnectDelay)
 		} else {
 			return nil
@@ -228,6 +275,11 @@ func (w InputterWorker) addInput(
 	prevRandao := fmt.Sprintf("0x%s", common.Bytes2Hex(values[5].(*big.Int).Bytes()))
 	payload := common.Bytes2Hex(values[7].([]uint8))
 	inputIndex := int(event.Index.Int64())
+	ctx, span := tracing.Start(ctx, "inputter.addInput",
+		tracing.AppContractKey.String(event.AppContract.Hex()),
+		tracing.InputIdKey.String(strconv.Itoa(inputIndex)),
+	)
+	defer span.End()
 
 	slog.Debug("inputter: read event",
 		"dapp", event.AppContract,
@@ -265,6 +317,8 @@ func (w InputterWorker) addInput(
 	if err != nil {
 		return err
 	}
+	metrics.Inputs.WithLabelValues(metrics.SourceInputBox).Inc()
+	tracing.Inputs.Add(ctx, event.AppContract.Hex(), strconv.Itoa(inputIndex))
 
 	return nil
 }
@@ -349,43 +403,11 @@ func (w InputterWorker) FindAllInputsByBlockAndTimestampLT(
 			return result, fmt.Errorf("inputter: failed to get tx header: %w", err)
 		}
 		timestamp := uint64(header.Time)
-		unixTimestamp := time.Unix(int64(header.Time), 0)
 		if timestamp < endTimestamp {
-			eventInput := it.Event.Input[4:]
-			abi, err := contracts.InputsMetaData.GetAbi()
-			if err != nil {
-				slog.Error("Error parsing abi", "err", err)
-				return result, err
-			}
-
-			values, err := abi.Methods["EvmAdvance"].Inputs.UnpackValues(eventInput)
+			input, err := advanceInputFromEvent(header, it.Event)
 			if err != nil {
-				slog.Error("Error parsing abi", "err", err)
 				return result, err
 			}
-
-			chainId := values[0].(*big.Int).String()
-			appContract := values[1].(common.Address)
-			msgSender := values[2].(common.Address)
-			prevRandao := fmt.Sprintf("0x%s", common.Bytes2Hex(values[5].(*big.Int).Bytes()))
-			payload := common.Bytes2Hex(values[7].([]uint8))
-			inputIndex := int(it.Event.Index.Int64())
-
-			input := cModel.AdvanceInput{
-				ID:                     strconv.Itoa(inputIndex),
-				Index:                  -1,
-				Status:                 cModel.CompletionStatusUnprocessed,
-				MsgSender:              msgSender,
-				Payload:                payload,
-				BlockTimestamp:         unixTimestamp,
-				BlockNumber:            header.Number.Uint64(),
-				EspressoBlockNumber:    -1,
-				EspressoBlockTimestamp: time.Unix(-1, 0),
-				InputBoxIndex:          inputIndex,
-				PrevRandao:             prevRandao,
-				AppContract:            appContract,
-				ChainId:                chainId,
-			}
 			slog.Debug("append InputAdded", "timestamp", timestamp, "endTimestamp", endTimestamp)
 			result = append(result, input)
 		} else {
@@ -399,3 +421,96 @@ func (w InputterWorker) FindAllInputsByBlockAndTimestampLT(
 
 	return result, nil
 }
+
+// Return the inputs added to the application from the start block up to the end block,
+// without storing them.
+func (w *InputterWorker) ReadInputs(ctx context.Context, start uint64, end uint64) ([]cModel.AdvanceInput, error) {
+	client, err := w.GetEthClient()
+	if err != nil {
+		return nil, err
+	}
+	inputBox, err := contracts.NewInputBox(w.InputBoxAddress, client)
+	if err != nil {
+		return nil, fmt.Errorf("inputter: bind input box: %w", err)
+	}
+	return w.FindAllInputsByBlockRange(ctx, client, inputBox, start, &end)
+}
+
+// Return the inputs added to the application from the start block up to the end block,
+// or the latest one when end is nil, without storing them.
+func (w InputterWorker) FindAllInputsByBlockRange(
+	ctx context.Context,
+	client *ethclient.Client,
+	inputBox *contracts.InputBox,
+	startBlockNumber uint64,
+	endBlockNumber *uint64,
+) ([]cModel.AdvanceInput, error) {
+	slog.Debug("FindAllInputsByBlockRange",
+		"startBlockNumber", startBlockNumber,
+		"endBlockNumber", endBlockNumber,
+		"dappAddress", w.ApplicationAddress,
+	)
+	opts := bind.FilterOpts{
+		Context: ctx,
+		Start:   startBlockNumber,
+		End:     endBlockNumber,
+	}
+	filter := []common.Address{w.ApplicationAddress}
+	it, err := inputBox.FilterInputAdded(&opts, filter, nil)
+	result := []cModel.AdvanceInput{}
+	if err != nil {
+		return result, fmt.Errorf("inputter: filter input added: %v", err)
+	}
+	defer it.Close()
+
+	for it.Next() {
+		header, err := client.HeaderByHash(ctx, it.Event.Raw.BlockHash)
+		if err != nil {
+			return result, fmt.Errorf("inputter: failed to get tx header: %w", err)
+		}
+		input, err := advanceInputFromEvent(header, it.Event)
+		if err != nil {
+			return result, err
+		}
+		result = append(result, input)
+	}
+	return result, it.Error()
+}
+
+func advanceInputFromEvent(header *types.Header, event *contracts.InputBoxInputAdded) (cModel.AdvanceInput, error) {
+	eventInput := event.Input[4:]
+	abi, err := contracts.InputsMetaData.GetAbi()
+	if err != nil {
+		slog.Error("Error parsing abi", "err", err)
+		return cModel.AdvanceInput{}, err
+	}
+
+	values, err := abi.Methods["EvmAdvance"].Inputs.UnpackValues(eventInput)
+	if err != nil {
+		slog.Error("Error parsing abi", "err", err)
+		return cModel.AdvanceInput{}, err
+	}
+
+	chainId := values[0].(*big.Int).String()
+	appContract := values[1].(common.Address)
+	msgSender := values[2].(common.Address)
+	prevRandao := fmt.Sprintf("0x%s", common.Bytes2Hex(values[5].(*big.Int).Bytes()))
+	payload := common.Bytes2Hex(values[7].([]uint8))
+	inputIndex := int(event.Index.Int64())
+
+	return cModel.AdvanceInput{
+		ID:                     strconv.Itoa(inputIndex),
+		Index:                  -1,
+		Status:                 cModel.CompletionStatusUnprocessed,
+		MsgSender:              msgSender,
+		Payload:                payload,
+		BlockTimestamp:         time.Unix(int64(header.Time), 0),
+		BlockNumber:            header.Number.Uint64(),
+		EspressoBlockNumber:    -1,
+		EspressoBlockTimestamp: time.Unix(-1, 0),
+		InputBoxIndex:          inputIndex,
+		PrevRandao:             prevRandao,
+		AppContract:            appContract,
+		ChainId:                chainId,
+	}, nil
+}
@@ -0,0 +1,119 @@
+package ordering
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/jmoiron/sqlx"
+	"github.com/labstack/echo/v4"
+)
+
+// Position given to an input when its L2 block was merged with the L1.
+type Decision struct {
+	InputIndex  int            `json:"inputIndex"`
+	InputID     string         `json:"inputId"`
+	AppContract common.Address `json:"appContract"`
+	// InputBox or the name of the L2 sequencer.
+	Source  string `json:"source"`
+	Policy  Policy `json:"policy"`
+	L2Block uint64 `json:"l2Block"`
+	// Block of the InputBox input, or the L1 block referenced by the L2 block.
+	L1Block uint64 `json:"l1Block"`
+}
+
+// Store the ordering decisions.
+type DecisionRepository struct {
+	Db *sqlx.DB
+}
+
+func (r *DecisionRepository) CreateTables() error {
+	schema := `CREATE TABLE IF NOT EXISTS ordering_decisions (
+		app_contract text NOT NULL,
+		input_index  integer NOT NULL,
+		input_id     text NOT NULL,
+		source       text NOT NULL,
+		policy       text NOT NULL,
+		l2_block     integer,
+		l1_block     integer,
+		PRIMARY KEY (app_contract, input_index));`
+	_, err := r.Db.Exec(schema)
+	if err == nil {
+		slog.Debug("Ordering decisions table created")
+	} else {
+		slog.Error("Create table error", "error", err)
+	}
+	return err
+}
+
+func (r *DecisionRepository) Create(ctx context.Context, decisions []Decision) error {
+	tx, err := r.Db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	for _, decision := range decisions {
+		_, err = tx.ExecContext(ctx, `INSERT INTO ordering_decisions (
+			app_contract,
+			input_index,
+			input_id,
+			source,
+			policy,
+			l2_block,
+			l1_block) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			decision.AppContract.Hex(),
+			decision.InputIndex,
+			decision.InputID,
+			decision.Source,
+			string(decision.Policy),
+			decision.L2Block,
+			decision.L1Block,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Return the decisions by input index.
+func (r *DecisionRepository) FindAll(ctx context.Context) ([]Decision, error) {
+	rows, err := r.Db.QueryxContext(ctx, `
+		SELECT app_contract, input_index, input_id, source, policy, l2_block, l1_block
+		FROM ordering_decisions ORDER BY input_index, app_contract`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	decisions := []Decision{}
+	for rows.Next() {
+		var decision Decision
+		var appContract, policy string
+		var l2Block, l1Block int64
+		err := rows.Scan(&appContract, &decision.InputIndex, &decision.InputID,
+			&decision.Source, &policy, &l2Block, &l1Block)
+		if err != nil {
+			return nil, err
+		}
+		decision.AppContract = common.HexToAddress(appContract)
+		decision.Policy = Policy(policy)
+		decision.L2Block = uint64(l2Block)
+		decision.L1Block = uint64(l1Block)
+		decisions = append(decisions, decision)
+	}
+	return decisions, rows.Err()
+}
+
+// Serve the ordering decisions at /nonodo/ordering.
+func Register(e *echo.Echo, repository *DecisionRepository) {
+	e.GET("/nonodo/ordering", func(c echo.Context) error {
+		decisions, err := repository.FindAll(c.Request().Context())
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, decisions)
+	})
+}
@@ -0,0 +1,133 @@
+package ordering
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/calindra/nonodo/internal/metrics"
+	cModel "github.com/cartesi/rollups-graphql/pkg/convenience/model"
+	cRepos "github.com/cartesi/rollups-graphql/pkg/convenience/repository"
+)
+
+// Store the ordered inputs, assigning their indexes after the stored ones.
+type InputWriter interface {
+	AddSequencedInputs(inputs []cModel.AdvanceInput) ([]cModel.AdvanceInput, error)
+}
+
+// Merge the L1 inputs with each L2 block by a policy and store the result.
+type Orderer struct {
+	Policy    Policy
+	ReadDelay time.Duration
+	Writer    InputWriter
+	// Used to skip the inputs already stored when the listener reads them again.
+	Inputs *cRepos.InputRepository
+	// Store the decisions, when set.
+	Decisions *DecisionRepository
+}
+
+func NewOrderer(policy Policy, writer InputWriter, inputs *cRepos.InputRepository) *Orderer {
+	return &Orderer{
+		Policy: policy,
+		Writer: writer,
+		Inputs: inputs,
+	}
+}
+
+// Merge the L1 inputs with the block and store the merged inputs.
+// Return the stored inputs, with their indexes, and the L1 inputs held for a later block.
+func (o *Orderer) Order(
+	ctx context.Context,
+	l1 []cModel.AdvanceInput,
+	block L2Block,
+) ([]cModel.AdvanceInput, []cModel.AdvanceInput, error) {
+	ordered, held := Merge(o.Policy, o.ReadDelay, l1, block)
+	inputs := make([]cModel.AdvanceInput, 0, len(ordered))
+	for _, input := range ordered {
+		if o.Inputs != nil {
+			exist, err := o.Inputs.FindByIDAndAppContract(ctx, input.ID, &input.AppContract)
+			if err != nil {
+				return nil, nil, err
+			}
+			if exist != nil {
+				slog.Debug("ordering: input already stored", "id", input.ID)
+				continue
+			}
+		}
+		inputs = append(inputs, input)
+	}
+	if len(inputs) == 0 {
+		return inputs, held, nil
+	}
+	added, err := o.Writer.AddSequencedInputs(inputs)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, input := range added {
+		metrics.Inputs.WithLabelValues(source(input, block)).Inc()
+	}
+	if o.Decisions != nil {
+		decisions := make([]Decision, len(added))
+		for i, input := range added {
+			decisions[i] = o.decide(input, block)
+		}
+		if err := o.Decisions.Create(ctx, decisions); err != nil {
+			return nil, nil, err
+		}
+	}
+	slog.Debug("ordering: block merged", "source", block.Source, "block", block.Number,
+		"policy", o.Policy, "inputs", len(added), "held", len(held))
+	return added, held, nil
+}
+
+func (o *Orderer) decide(input cModel.AdvanceInput, block L2Block) Decision {
+	decision := Decision{
+		InputIndex:  input.Index,
+		InputID:     input.ID,
+		AppContract: input.AppContract,
+		Source:      source(input, block),
+		Policy:      o.Policy,
+		L2Block:     block.Number,
+		L1Block:     block.L1Finalized,
+	}
+	if input.InputBoxIndex >= 0 {
+		decision.L1Block = input.BlockNumber
+	}
+	return decision
+}
+
+// Source of the input: the InputBox or the sequencer of the block.
+func source(input cModel.AdvanceInput, block L2Block) string {
+	if input.InputBoxIndex >= 0 {
+		return SourceInputBox
+	}
+	return block.Source
+}
+
+// Writer that indexes the inputs after the count of the stored ones.
+// Used when the listener has no model to share the lock with.
+type RepositoryWriter struct {
+	Repository *cRepos.InputRepository
+	mutex      sync.Mutex
+}
+
+func (w *RepositoryWriter) AddSequencedInputs(inputs []cModel.AdvanceInput) ([]cModel.AdvanceInput, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	ctx := context.Background()
+	count, err := w.Repository.Count(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	added := make([]cModel.AdvanceInput, 0, len(inputs))
+	for i, input := range inputs {
+		input.Index = int(count) + i
+		input.Status = cModel.CompletionStatusUnprocessed
+		if _, err := w.Repository.RawCreate(ctx, input); err != nil {
+			return added, err
+		}
+		added = append(added, input)
+	}
+	return added, nil
+}
@@ -0,0 +1,87 @@
+// Package ordering merges the inputs of the InputBox with the transactions of an L2 sequencer.
+//
+// Every L2 block is merged with the L1 inputs read so far. The L1 inputs that come before the
+// block, according to the policy, are placed ahead of its transactions, in InputBox order; the
+// others are held for a later block. The L1 inputs never overtake each other, so the first held
+// input holds all the following ones.
+package ordering
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	cModel "github.com/cartesi/rollups-graphql/pkg/convenience/model"
+)
+
+// Rule to decide which L1 inputs come before an L2 block.
+type Policy string
+
+const (
+	// The L1 inputs up to the finalized L1 block referenced by the L2 block.
+	PolicyL1Finalized Policy = "l1-finalized"
+	// The L1 inputs older than the L2 block timestamp minus the read delay, at the second.
+	PolicyTimestamp Policy = "timestamp"
+	// Every L1 input read so far.
+	PolicyL1First Policy = "l1-first"
+)
+
+// Source of the inputs read from the InputBox.
+const SourceInputBox = "inputbox"
+
+func ParsePolicy(policy string) (Policy, error) {
+	switch Policy(policy) {
+	case PolicyL1Finalized, PolicyTimestamp, PolicyL1First:
+		return Policy(policy), nil
+	default:
+		return "", fmt.Errorf("invalid ordering policy %q; expected %s, %s or %s",
+			policy, PolicyL1Finalized, PolicyTimestamp, PolicyL1First)
+	}
+}
+
+// Block of an L2 sequencer and its references to the L1.
+type L2Block struct {
+	// Name of the sequencer, such as avail or espresso.
+	Source    string
+	Number    uint64
+	Timestamp time.Time
+	// Finalized L1 block referenced by the block; zero when the sequencer has none.
+	L1Finalized uint64
+	// Transactions of the block, in the sequencer order.
+	Inputs []cModel.AdvanceInput
+}
+
+// Merge the L1 inputs with the transactions of the block.
+// Return the inputs in processing order and the L1 inputs held for a later block.
+func Merge(
+	policy Policy,
+	readDelay time.Duration,
+	l1 []cModel.AdvanceInput,
+	block L2Block,
+) ([]cModel.AdvanceInput, []cModel.AdvanceInput) {
+	pending := slices.Clone(l1)
+	slices.SortStableFunc(pending, func(a, b cModel.AdvanceInput) int {
+		return a.InputBoxIndex - b.InputBoxIndex
+	})
+	cutoff := block.Timestamp.Truncate(time.Second).Add(-readDelay)
+	included := 0
+	for _, input := range pending {
+		var before bool
+		switch policy {
+		case PolicyL1Finalized:
+			before = input.BlockNumber <= block.L1Finalized
+		case PolicyTimestamp:
+			before = input.BlockTimestamp.Before(cutoff)
+		case PolicyL1First:
+			before = true
+		}
+		if !before {
+			break
+		}
+		included++
+	}
+	ordered := make([]cModel.AdvanceInput, 0, included+len(block.Inputs))
+	ordered = append(ordered, pending[:included]...)
+	ordered = append(ordered, block.Inputs...)
+	return ordered, pending[included:]
+}
@@ -0,0 +1,191 @@
+package ordering
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cartesi/rollups-graphql/pkg/commons"
+	cModel "github.com/cartesi/rollups-graphql/pkg/convenience/model"
+	cRepos "github.com/cartesi/rollups-graphql/pkg/convenience/repository"
+	"github.com/ethereum/go-ethereum/common"
+	_ "github.com/ncruces/go-sqlite3/driver"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	"github.com/stretchr/testify/suite"
+)
+
+var app = common.HexToAddress("0xab7528bb862fb57e8a2bcd567a2e929a0be56a5e")
+
+type OrderingSuite struct {
+	suite.Suite
+	ctx       context.Context
+	dbFactory *commons.DbFactory
+	inputs    *cRepos.InputRepository
+	decisions *DecisionRepository
+	orderer   *Orderer
+	genesis   time.Time
+}
+
+func TestOrderingSuite(t *testing.T) {
+	suite.Run(t, new(OrderingSuite))
+}
+
+func (s *OrderingSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.genesis = time.Unix(1700000000, 0) // nolint
+	s.dbFactory = commons.NewDbFactory()
+	db := s.dbFactory.CreateDb("ordering.sqlite3")
+	s.inputs = &cRepos.InputRepository{Db: *db}
+	s.Require().NoError(s.inputs.CreateTables())
+	s.decisions = &DecisionRepository{Db: db}
+	s.Require().NoError(s.decisions.CreateTables())
+	s.orderer = NewOrderer(PolicyTimestamp, &RepositoryWriter{Repository: s.inputs}, s.inputs)
+	s.orderer.Decisions = s.decisions
+}
+
+func (s *OrderingSuite) TearDownTest() {
+	s.dbFactory.Cleanup()
+}
+
+// InputBox input added at the given L1 block, 12 seconds apart.
+func (s *OrderingSuite) l1(inputBoxIndex int, blockNumber uint64) cModel.AdvanceInput {
+	return cModel.AdvanceInput{
+		ID:             strconv.Itoa(inputBoxIndex),
+		Index:          -1,
+		AppContract:    app,
+		Payload:        "0x01",
+		InputBoxIndex:  inputBoxIndex,
+		BlockNumber:    blockNumber,
+		BlockTimestamp: s.genesis.Add(time.Duration(blockNumber) * 12 * time.Second), // nolint
+	}
+}
+
+func l2(id string) cModel.AdvanceInput {
+	return cModel.AdvanceInput{
+		ID:            id,
+		AppContract:   app,
+		Payload:       "0x02",
+		InputBoxIndex: -2, // nolint
+		Type:          "Avail",
+	}
+}
+
+func ids(inputs []cModel.AdvanceInput) []string {
+	result := make([]string, len(inputs))
+	for i, input := range inputs {
+		result[i] = input.ID
+	}
+	return result
+}
+
+func (s *OrderingSuite) TestParsePolicy() {
+	for _, policy := range []string{"l1-finalized", "timestamp", "l1-first"} {
+		parsed, err := ParsePolicy(policy)
+		s.NoError(err)
+		s.Equal(Policy(policy), parsed)
+	}
+	_, err := ParsePolicy("fifo")
+	s.ErrorContains(err, "invalid ordering policy")
+}
+
+func (s *OrderingSuite) TestMergeByFinalizedL1Block() {
+	l1 := []cModel.AdvanceInput{s.l1(0, 10), s.l1(1, 20), s.l1(2, 30)}                 // nolint
+	block := L2Block{L1Finalized: 20, Inputs: []cModel.AdvanceInput{l2("a"), l2("b")}} // nolint
+	ordered, held := Merge(PolicyL1Finalized, 0, l1, block)
+	s.Equal([]string{"0", "1", "a", "b"}, ids(ordered))
+	s.Equal([]string{"2"}, ids(held))
+}
+
+func (s *OrderingSuite) TestMergeByTimestamp() {
+	l1 := []cModel.AdvanceInput{s.l1(0, 10), s.l1(1, 20)} // nolint
+	block := L2Block{
+		// the second input is 120 seconds after the first one
+		Timestamp: s.genesis.Add(300*time.Second + 500*time.Millisecond), // nolint
+		Inputs:    []cModel.AdvanceInput{l2("a")},
+	}
+	ordered, held := Merge(PolicyTimestamp, time.Minute, l1, block)
+	s.Equal([]string{"0", "a"}, ids(ordered))
+	s.Equal([]string{"1"}, ids(held))
+
+	// the cutoff is at the second of the block, as the L1 timestamps
+	block.Timestamp = s.genesis.Add(240*time.Second + 500*time.Millisecond) // nolint
+	ordered, held = Merge(PolicyTimestamp, 0, l1, block)
+	s.Equal([]string{"0", "a"}, ids(ordered))
+	s.Equal([]string{"1"}, ids(held))
+}
+
+func (s *OrderingSuite) TestMergeL1First() {
+	l1 := []cModel.AdvanceInput{s.l1(1, 20), s.l1(0, 10)} // nolint
+	ordered, held := Merge(PolicyL1First, time.Hour, l1, L2Block{Inputs: []cModel.AdvanceInput{l2("a")}})
+	s.Equal([]string{"0", "1", "a"}, ids(ordered))
+	s.Empty(held)
+}
+
+func (s *OrderingSuite) TestTheHeldInputHoldsTheFollowingOnes() {
+	// input 1 was read in a later L1 block but comes first in the InputBox
+	l1 := []cModel.AdvanceInput{s.l1(0, 10), s.l1(1, 30), s.l1(2, 20)}         // nolint
+	ordered, held := Merge(PolicyL1Finalized, 0, l1, L2Block{L1Finalized: 25}) // nolint
+	s.Equal([]string{"0"}, ids(ordered))
+	s.Equal([]string{"1", "2"}, ids(held))
+}
+
+func (s *OrderingSuite) TestItOrdersTheStreams() {
+	// L1 inputs every 12 seconds and L2 blocks every 20 seconds, 30 seconds behind
+	s.orderer.ReadDelay = 30 * time.Second // nolint
+	l1 := []cModel.AdvanceInput{}
+	for i := range 6 {
+		l1 = append(l1, s.l1(i, uint64(i+1)))
+	}
+	var held []cModel.AdvanceInput
+	for number := uint64(1); number <= 4; number++ { // nolint
+		block := L2Block{
+			Source:    "avail",
+			Number:    number,
+			Timestamp: s.genesis.Add(time.Duration(number) * 20 * time.Second), // nolint
+			Inputs:    []cModel.AdvanceInput{l2("tx" + strconv.Itoa(int(number)))},
+		}
+		// the listener reads the held inputs again with the next block
+		_, nextHeld, err := s.orderer.Order(s.ctx, append(held, l1...), block)
+		s.Require().NoError(err)
+		held = nextHeld
+		l1 = nil
+	}
+
+	stored, err := s.inputs.FindAll(s.ctx, nil, nil, nil, nil, nil)
+	s.Require().NoError(err)
+	s.Equal([]string{"tx1", "tx2", "0", "1", "tx3", "2", "3", "tx4"}, ids(stored.Rows))
+	for i, input := range stored.Rows {
+		s.Equal(i, input.Index)
+	}
+	s.Equal([]string{"4", "5"}, ids(held))
+
+	decisions, err := s.decisions.FindAll(s.ctx)
+	s.Require().NoError(err)
+	s.Require().Len(decisions, len(stored.Rows))
+	s.Equal(Decision{
+		InputIndex:  2,
+		InputID:     "0",
+		AppContract: app,
+		Source:      SourceInputBox,
+		Policy:      PolicyTimestamp,
+		L2Block:     3, // nolint
+		L1Block:     1,
+	}, decisions[2])
+	s.Equal("avail", decisions[4].Source)
+	s.Equal("tx3", decisions[4].InputID)
+}
+
+func (s *OrderingSuite) TestItSkipsTheStoredInputs() {
+	s.orderer.Policy = PolicyL1First
+	l1 := []cModel.AdvanceInput{s.l1(0, 10)} // nolint
+	added, _, err := s.orderer.Order(s.ctx, l1, L2Block{Number: 1, Inputs: []cModel.AdvanceInput{l2("a")}})
+	s.Require().NoError(err)
+	s.Len(added, 2)
+
+	// the same L1 range is read again after a restart
+	added, _, err = s.orderer.Order(s.ctx, l1, L2Block{Number: 2, Inputs: []cModel.AdvanceInput{l2("b")}})
+	s.Require().NoError(err)
+	s.Equal([]string{"b"}, ids(added))
+	s.Equal(2, added[0].Index)
+}
@@ -25,6 +25,8 @@ type DecoderPaio interface {
 	DecodePaioBatch(ctx context.Context, bytes []byte) (string, error)
 }
 
+// Decoder that runs the decode-batch binary released by Paio.
+// Nonodo uses the NativeDecoder; this one is kept to check the native decoder against Paio.
 type PaioDecoder struct {
 	location string
 }
@@ -0,0 +1,309 @@
+package paiodecoder
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// The Paio batch wire format is the postcard serialization of the batch:
+// unsigned integers are LEB128 varints, byte strings and lists are prefixed by their varint length,
+// addresses are 20-byte strings and the signature values are big-endian byte strings with the
+// full width of their type (32 bytes for r and s, 8 bytes for v).
+const (
+	addressSize = 20
+	wordSize    = 32
+	vSize       = 8
+	// A varint of a 128-bit value has at most 19 bytes.
+	maxVarintSize = 19
+)
+
+var ErrInvalidBatch = errors.New("paio: invalid batch")
+
+// Decode batches natively, without the external decoder binary.
+type NativeDecoder struct{}
+
+var _ DecoderPaio = NativeDecoder{}
+
+// Decode the batch and return it in the same JSON format as the decoder binary.
+// Data coming from Avail is prefixed by its SCALE compact length, which is removed as needed.
+func (NativeDecoder) DecodePaioBatch(ctx context.Context, rawBytes []byte) (string, error) {
+	batch, err := DecodeBatch(rawBytes)
+	if err != nil {
+		data, ok := trimCompactLength(rawBytes)
+		if !ok {
+			return "", err
+		}
+		batch, err = DecodeBatch(data)
+		if err != nil {
+			return "", err
+		}
+	}
+	return batchToJSON(batch)
+}
+
+// Decode a batch from the Paio wire format.
+func DecodeBatch(data []byte) (PaioBatch, error) {
+	r := wireReader{data: data}
+	var batch PaioBatch
+	sequencer := r.bytes("sequencer_payment_address", addressSize)
+	batch.SequencerPaymentAddress = common.BytesToAddress(sequencer).Hex()
+	count := r.varint("txs")
+	if r.err == nil && (!count.IsUint64() || count.Uint64() > uint64(len(data))) {
+		r.fail("txs", "too many transactions")
+	}
+	for i := uint64(0); r.err == nil && i < count.Uint64(); i++ {
+		var tx PaioTransaction
+		tx.App = common.BytesToAddress(r.bytes("app", addressSize)).Hex()
+		tx.Nonce = r.uint64("nonce")
+		tx.MaxGasPrice = r.uint64("max_gas_price")
+		tx.Data = r.bytes("data", -1)
+		tx.Signature.R = hexNumber(r.bytes("signature.r", wordSize))
+		tx.Signature.S = hexNumber(r.bytes("signature.s", wordSize))
+		tx.Signature.V = hexNumber(r.bytes("signature.v", vSize))
+		batch.Txs = append(batch.Txs, tx)
+	}
+	if r.err == nil && r.offset != len(data) {
+		r.fail("batch", fmt.Sprintf("%d trailing bytes", len(data)-r.offset))
+	}
+	if r.err != nil {
+		return PaioBatch{}, r.err
+	}
+	return batch, nil
+}
+
+// Encode the batch in the Paio wire format.
+func EncodeBatch(batch PaioBatch) ([]byte, error) {
+	var w bytes.Buffer
+	sequencer, err := parseAddress(batch.SequencerPaymentAddress)
+	if err != nil {
+		return nil, fmt.Errorf("%w: sequencer_payment_address: %w", ErrInvalidBatch, err)
+	}
+	writeBytes(&w, sequencer.Bytes())
+	writeVarint(&w, new(big.Int).SetInt64(int64(len(batch.Txs))))
+	for i, tx := range batch.Txs {
+		app, err := parseAddress(tx.App)
+		if err != nil {
+			return nil, fmt.Errorf("%w: txs[%d].app: %w", ErrInvalidBatch, i, err)
+		}
+		writeBytes(&w, app.Bytes())
+		writeVarint(&w, new(big.Int).SetUint64(tx.Nonce))
+		writeVarint(&w, new(big.Int).SetUint64(tx.MaxGasPrice))
+		writeBytes(&w, tx.Data)
+		values := []struct {
+			name  string
+			value string
+			size  int
+		}{
+			{"r", tx.Signature.R, wordSize},
+			{"s", tx.Signature.S, wordSize},
+			{"v", tx.Signature.V, vSize},
+		}
+		for _, v := range values {
+			word, err := parseWord(v.value, v.size)
+			if err != nil {
+				return nil, fmt.Errorf("%w: txs[%d].signature.%s: %w", ErrInvalidBatch, i, v.name, err)
+			}
+			writeBytes(&w, word)
+		}
+	}
+	return w.Bytes(), nil
+}
+
+// Helper that keeps the first error while reading the wire format.
+type wireReader struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+func (r *wireReader) fail(field string, reason string) {
+	if r.err == nil {
+		r.err = fmt.Errorf("%w: %s at byte %d: %s", ErrInvalidBatch, field, r.offset, reason)
+	}
+}
+
+func (r *wireReader) varint(field string) *big.Int {
+	value := new(big.Int)
+	if r.err != nil {
+		return value
+	}
+	for i := 0; ; i++ {
+		if i == maxVarintSize {
+			r.fail(field, "varint is too long")
+			return value
+		}
+		if r.offset >= len(r.data) {
+			r.fail(field, "unexpected end of data")
+			return value
+		}
+		b := r.data[r.offset]
+		r.offset++
+		chunk := new(big.Int).SetUint64(uint64(b & 0x7f)) // nolint
+		value.Or(value, chunk.Lsh(chunk, uint(7*i)))      // nolint
+		if b&0x80 == 0 {
+			return value
+		}
+	}
+}
+
+func (r *wireReader) uint64(field string) uint64 {
+	value := r.varint(field)
+	if !value.IsUint64() {
+		r.fail(field, "value does not fit in 64 bits")
+		return 0
+	}
+	return value.Uint64()
+}
+
+// Read a byte string; if size is not negative, the string may not be longer than it.
+func (r *wireReader) bytes(field string, size int) []byte {
+	length := r.varint(field)
+	if r.err != nil {
+		return nil
+	}
+	if !length.IsUint64() || length.Uint64() > uint64(len(r.data)-r.offset) {
+		r.fail(field, "unexpected end of data")
+		return nil
+	}
+	n := int(length.Uint64())
+	if size >= 0 && n > size {
+		r.fail(field, fmt.Sprintf("expected at most %d bytes, got %d", size, n))
+		return nil
+	}
+	value := make([]byte, n)
+	copy(value, r.data[r.offset:r.offset+n])
+	r.offset += n
+	return value
+}
+
+func writeVarint(w *bytes.Buffer, value *big.Int) {
+	v := new(big.Int).Set(value)
+	mask := big.NewInt(0x7f) // nolint
+	for {
+		b := byte(new(big.Int).And(v, mask).Uint64())
+		v.Rsh(v, 7) // nolint
+		if v.Sign() == 0 {
+			w.WriteByte(b)
+			return
+		}
+		w.WriteByte(b | 0x80) // nolint
+	}
+}
+
+func writeBytes(w *bytes.Buffer, value []byte) {
+	writeVarint(w, new(big.Int).SetInt64(int64(len(value))))
+	w.Write(value)
+}
+
+func parseAddress(value string) (common.Address, error) {
+	if !common.IsHexAddress(value) {
+		return common.Address{}, fmt.Errorf("invalid address %q", value)
+	}
+	return common.HexToAddress(value), nil
+}
+
+// Parse a hex number and return it as a big-endian word with the given size.
+func parseWord(value string, size int) ([]byte, error) {
+	n, ok := new(big.Int).SetString(strings.TrimPrefix(value, "0x"), 16)
+	if !ok || n.Sign() < 0 {
+		return nil, fmt.Errorf("invalid number %q", value)
+	}
+	if n.BitLen() > size*8 {
+		return nil, fmt.Errorf("number %q does not fit in %d bytes", value, size)
+	}
+	return n.FillBytes(make([]byte, size)), nil
+}
+
+// Format the big-endian bytes as a hex number without leading zeros, like the decoder binary.
+func hexNumber(value []byte) string {
+	return "0x" + new(big.Int).SetBytes(value).Text(16) // nolint
+}
+
+// Remove the SCALE compact length prefix, if the data starts with one that matches its size.
+func trimCompactLength(data []byte) ([]byte, bool) {
+	if len(data) == 0 {
+		return nil, false
+	}
+	var length uint64
+	var size int
+	switch data[0] & 0b11 { // nolint
+	case 0b00:
+		length, size = uint64(data[0]>>2), 1 // nolint
+	case 0b01:
+		if len(data) < 2 { // nolint
+			return nil, false
+		}
+		length, size = uint64(binary.LittleEndian.Uint16(data)>>2), 2 // nolint
+	case 0b10:
+		if len(data) < 4 { // nolint
+			return nil, false
+		}
+		length, size = uint64(binary.LittleEndian.Uint32(data)>>2), 4 // nolint
+	default:
+		return nil, false
+	}
+	if length != uint64(len(data)-size) {
+		return nil, false
+	}
+	return data[size:], true
+}
+
+// JSON representation of the batch used by the decoder binary.
+// Unlike encoding/json, the binary writes byte strings as arrays of numbers.
+type jsonBatch struct {
+	SequencerPaymentAddress string            `json:"sequencer_payment_address"`
+	Txs                     []jsonTransaction `json:"txs"`
+}
+
+type jsonTransaction struct {
+	App         string        `json:"app"`
+	Nonce       uint64        `json:"nonce"`
+	MaxGasPrice uint64        `json:"max_gas_price"`
+	Data        jsonBytes     `json:"data"`
+	Signature   PaioSignature `json:"signature"`
+}
+
+type jsonBytes []byte
+
+func (b jsonBytes) MarshalJSON() ([]byte, error) {
+	var w bytes.Buffer
+	w.WriteByte('[')
+	for i, v := range b {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		w.WriteString(strconv.Itoa(int(v)))
+	}
+	w.WriteByte(']')
+	return w.Bytes(), nil
+}
+
+func batchToJSON(batch PaioBatch) (string, error) {
+	out := jsonBatch{
+		SequencerPaymentAddress: batch.SequencerPaymentAddress,
+		Txs:                     make([]jsonTransaction, len(batch.Txs)),
+	}
+	for i, tx := range batch.Txs {
+		out.Txs[i] = jsonTransaction{
+			App:         tx.App,
+			Nonce:       tx.Nonce,
+			MaxGasPrice: tx.MaxGasPrice,
+			Data:        tx.Data,
+			Signature:   tx.Signature,
+		}
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
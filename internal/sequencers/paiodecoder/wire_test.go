@@ -0,0 +1,136 @@
+package paiodecoder
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/suite"
+)
+
+// Batch from TestDecodeBytes, as encoded by Paio.
+// nolint
+const sampleBatch = `1400000000000000000000000000000000000000000114ab7528bb862fb57e8a2bcd567a2e929a0be56a5e000a07deadbeeffab10920205f3aa429e8ea753d2e799fa4bf9166264d4114745fb4670eaed856f0dae8e5204e74225ca715f951fed4bec7b0bc635f067183ac3ec44139533b0715e04b4da808000000000000001c`
+
+type WireSuite struct {
+	suite.Suite
+}
+
+func TestWireSuite(t *testing.T) {
+	suite.Run(t, new(WireSuite))
+}
+
+func (s *WireSuite) TestDecodeBatch() {
+	batch, err := DecodeBatch(common.Hex2Bytes(sampleBatch))
+	s.Require().NoError(err)
+	s.Equal("0x0000000000000000000000000000000000000000", batch.SequencerPaymentAddress)
+	s.Require().Len(batch.Txs, 1)
+	tx := batch.Txs[0]
+	s.Equal("0xab7528bb862fB57E8A2BCd567a2e929a0Be56a5e", tx.App)
+	s.Equal(uint64(0), tx.Nonce)
+	s.Equal(uint64(10), tx.MaxGasPrice)
+	s.Equal(common.Hex2Bytes("deadbeeffab109"), tx.Data)
+	s.Equal("0x1c", tx.Signature.V)
+}
+
+func (s *WireSuite) TestEncodeBatchMatchesPaio() {
+	batch, err := DecodeBatch(common.Hex2Bytes(sampleBatch))
+	s.Require().NoError(err)
+	encoded, err := EncodeBatch(batch)
+	s.Require().NoError(err)
+	s.Equal(sampleBatch, common.Bytes2Hex(encoded))
+}
+
+func (s *WireSuite) TestEncodeBatchWithShortSignatureValues() {
+	batch := PaioBatch{
+		SequencerPaymentAddress: "0x63F9725f107358c9115BC9d86c72dD5823E9B1E6",
+		Txs: []PaioTransaction{{
+			App:         "0xab7528bb862fB57E8A2BCd567a2e929a0Be56a5e",
+			Nonce:       300,
+			MaxGasPrice: 1 << 40,
+			Data:        []byte("Hello, World?"),
+			Signature: PaioSignature{
+				R: "0x6c4a7d4453adafa14fd9d79c3f6bab2f482f88f4f212971cd1fdc9c1a2bbdc88",
+				S: "0x17b79806e5af212437f3a2dd7a8c9f4575156b082b130369e29032792ff41e5",
+				V: "0x1c",
+			},
+		}},
+	}
+	encoded, err := EncodeBatch(batch)
+	s.Require().NoError(err)
+	decoded, err := DecodeBatch(encoded)
+	s.Require().NoError(err)
+	s.Equal(batch, decoded)
+}
+
+func (s *WireSuite) TestDecodeBatchErrors() {
+	sample := common.Hex2Bytes(sampleBatch)
+	_, err := DecodeBatch(sample[:len(sample)-1])
+	s.ErrorIs(err, ErrInvalidBatch)
+	s.ErrorContains(err, "signature.v")
+
+	_, err = DecodeBatch(append(sample, 0))
+	s.ErrorContains(err, "1 trailing bytes")
+
+	_, err = DecodeBatch(nil)
+	s.ErrorContains(err, "sequencer_payment_address")
+}
+
+func (s *WireSuite) TestItRemovesTheAvailLengthPrefix() {
+	ctx := context.Background()
+	sample := common.Hex2Bytes(sampleBatch)
+	expected, err := NativeDecoder{}.DecodePaioBatch(ctx, sample)
+	s.Require().NoError(err)
+
+	// SCALE compact length with two bytes
+	prefix := []byte{byte(len(sample)<<2 | 0b01), byte(len(sample) >> 6)}
+	json, err := NativeDecoder{}.DecodePaioBatch(ctx, append(prefix, sample...))
+	s.Require().NoError(err)
+	s.Equal(expected, json)
+
+	_, err = NativeDecoder{}.DecodePaioBatch(ctx, append([]byte{0xff, 0xff}, sample...))
+	s.ErrorIs(err, ErrInvalidBatch)
+}
+
+// Check that decoding is the inverse of encoding and, when the Paio decoder binary is installed,
+// that the native decoder agrees with it.
+func FuzzDecodeBatch(f *testing.F) {
+	f.Add(common.Hex2Bytes(sampleBatch))
+	f.Add([]byte{})
+	f.Add(common.Hex2Bytes("14000000000000000000000000000000000000000000"))
+	var binary *PaioDecoder
+	if location, installed := IsDecodeBatchInstalled(); installed {
+		binary = NewPaioDecoder(location)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		batch, err := DecodeBatch(data)
+		if binary != nil {
+			expected, binaryErr := binary.DecodePaioBatchSkip(context.Background(), 0, data)
+			if (err == nil) != (binaryErr == nil) {
+				t.Fatalf("native error %v, binary error %v", err, binaryErr)
+			}
+			if err == nil {
+				json, err := batchToJSON(batch)
+				if err != nil || json != expected {
+					t.Fatalf("native decoded %s, binary decoded %s", json, expected)
+				}
+			}
+		}
+		if err != nil {
+			return
+		}
+		encoded, err := EncodeBatch(batch)
+		if err != nil {
+			t.Fatalf("failed to encode decoded batch: %v", err)
+		}
+		again, err := DecodeBatch(encoded)
+		if err != nil {
+			t.Fatalf("failed to decode encoded batch: %v", err)
+		}
+		json, _ := batchToJSON(batch)
+		againJSON, _ := batchToJSON(again)
+		if json != againJSON {
+			t.Fatalf("round trip changed the batch: %s != %s", json, againJSON)
+		}
+	})
+}
@@ -16,6 +16,7 @@ type commandLogger struct {
 	name     string
 	buffName string
 	buffer   bytes.Buffer
+	onLine   func(stream string, line string)
 }
 
 func (w *commandLogger) Write(data []byte) (int, error) {
@@ -37,6 +38,9 @@ func (w *commandLogger) Write(data []byte) (int, error) {
 		if len(line) > 0 {
 			slog.Info("command: log", "command", w.name, "buffer", w.buffName,
 				"line", line)
+			if w.onLine != nil {
+				w.onLine(w.buffName, line)
+			}
 		}
 	}
 	return len(data), nil
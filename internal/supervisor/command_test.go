@@ -0,0 +1,30 @@
+// (c) Cartesi and individual authors (see AUTHORS)
+// SPDX-License-Identifier: Apache-2.0 (see LICENSE)
+
+//go:build !windows
+
+package supervisor
+
+import (
+	"context"
+	"sync"
+)
+
+func (s *SupervisorSuite) TestItSendsCommandLinesWithTheirStream() {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	var mutex sync.Mutex
+	lines := map[string]string{}
+	w := CommandWorker{
+		Name:    "app",
+		Command: "sh",
+		Args:    []string{"-c", "echo out; echo err >&2"},
+		OnLine: func(stream string, line string) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			lines[stream] = line
+		},
+	}
+	s.NoError(w.Start(ctx, make(chan struct{}, 1)))
+	s.Equal(map[string]string{"stdout": "out", "stderr": "err"}, lines)
+}
@@ -19,6 +19,8 @@ type CommandWorker struct {
 	Command string
 	Args    []string
 	Env     []string
+	// Called with each line printed by the command; optional.
+	OnLine func(stream string, line string)
 }
 
 func (w CommandWorker) String() string {
@@ -29,8 +31,8 @@ func (w CommandWorker) Start(ctx context.Context, ready chan<- struct{}) error {
 	cmd := exec.CommandContext(ctx, w.Command, w.Args...)
 	cmd.Env = os.Environ()
 	cmd.Env = append(cmd.Env, w.Env...)
-	cmd.Stderr = &commandLogger{buffName: "stdout", name: w.Name}
-	cmd.Stdout = &commandLogger{buffName: "stderr", name: w.Name}
+	cmd.Stdout = &commandLogger{buffName: "stdout", name: w.Name, onLine: w.OnLine}
+	cmd.Stderr = &commandLogger{buffName: "stderr", name: w.Name, onLine: w.OnLine}
 	// Use setpgid to create a process group, so we can send the terminate signal to the
 	// processes and all of its children. This only works on unix systems.
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
@@ -18,6 +18,8 @@ type CommandWorker struct {
 	Command string
 	Args    []string
 	Env     []string
+	// Called with each line printed by the command; optional.
+	OnLine func(stream string, line string)
 }
 
 func (w CommandWorker) String() string {
@@ -28,8 +30,8 @@ func (w CommandWorker) Start(ctx context.Context, ready chan<- struct{}) error {
 	cmd := exec.CommandContext(ctx, w.Command, w.Args...)
 	cmd.Env = os.Environ()
 	cmd.Env = append(cmd.Env, w.Env...)
-	cmd.Stderr = &commandLogger{buffName: "stdout", name: w.Name}
-	cmd.Stdout = &commandLogger{buffName: "stderr", name: w.Name}
+	cmd.Stdout = &commandLogger{buffName: "stdout", name: w.Name, onLine: w.OnLine}
+	cmd.Stderr = &commandLogger{buffName: "stderr", name: w.Name, onLine: w.OnLine}
 	cmd.Cancel = func() error {
 		// Sending Interrupt on Windows is not implemented, so we just kill the process.
 		// See: https://pkg.go.dev/os#Process.Signal
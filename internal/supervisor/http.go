@@ -13,11 +13,16 @@ import (
 
 // The HTTP worker starts and manage an HTTP server.
 type HttpWorker struct {
+	// Name used to refer to the worker; "http" if empty.
+	Name    string
 	Address string
 	Handler http.Handler
 }
 
 func (w HttpWorker) String() string {
+	if w.Name != "" {
+		return w.Name
+	}
 	return "http"
 }
 
@@ -50,3 +55,8 @@ func (w HttpWorker) Start(ctx context.Context, ready chan<- struct{}) error {
 	}
 	return err
 }
+
+// Probe implements Prober by checking whether the server accepts connections.
+func (w HttpWorker) Probe(ctx context.Context) error {
+	return probeTCP(ctx, w.Address)
+}
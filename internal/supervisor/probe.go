@@ -0,0 +1,40 @@
+// (c) Cartesi and individual authors (see AUTHORS)
+// SPDX-License-Identifier: Apache-2.0 (see LICENSE)
+
+package supervisor
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// Default values for the liveness probes.
+const (
+	DefaultProbeInterval  = 5 * time.Second
+	DefaultProbeTimeout   = time.Second
+	DefaultProbeThreshold = 3
+)
+
+// Worker that can be checked periodically after it is ready.
+// When the probe fails several times in a row, the supervisor stops the worker and applies its
+// restart policy.
+type Prober interface {
+	Probe(ctx context.Context) error
+}
+
+func proberOf(worker Worker) (Prober, bool) {
+	return findOption[Prober](worker)
+}
+
+// Check whether there is something listening on the TCP address.
+func probeTCP(ctx context.Context, address string) error {
+	ctx, cancel := context.WithTimeout(ctx, DefaultProbeTimeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,127 @@
+// (c) Cartesi and individual authors (see AUTHORS)
+// SPDX-License-Identifier: Apache-2.0 (see LICENSE)
+
+package supervisor
+
+import (
+	"fmt"
+	"time"
+)
+
+// Default backoff values used when restarting a worker.
+const (
+	DefaultRestartBackoff    = time.Second
+	DefaultMaxRestartBackoff = 30 * time.Second
+)
+
+// Restart mode of a worker after it exits.
+type RestartMode int
+
+const (
+	// Never restart the worker; its exit stops the whole supervisor.
+	RestartNever RestartMode = iota
+	// Restart the worker only when it exits with an error.
+	RestartOnFailure
+	// Always restart the worker, even when it exits with success.
+	RestartAlways
+)
+
+func (m RestartMode) String() string {
+	switch m {
+	case RestartNever:
+		return "never"
+	case RestartOnFailure:
+		return "on-failure"
+	case RestartAlways:
+		return "always"
+	default:
+		return fmt.Sprintf("RestartMode(%d)", int(m))
+	}
+}
+
+// Parse the restart mode from its string representation.
+func ParseRestartMode(value string) (RestartMode, error) {
+	switch value {
+	case "never", "":
+		return RestartNever, nil
+	case "on-failure":
+		return RestartOnFailure, nil
+	case "always":
+		return RestartAlways, nil
+	default:
+		return RestartNever, fmt.Errorf("invalid restart policy %q: use never, on-failure or always", value)
+	}
+}
+
+// Policy used by the supervisor to decide whether a worker should be restarted.
+type RestartPolicy struct {
+	Mode RestartMode
+	// Maximum number of restarts; zero means unlimited.
+	MaxRetries int
+	// Delay before the first restart; it doubles after each restart.
+	Backoff time.Duration
+	// Upper bound of the delay between restarts.
+	MaxBackoff time.Duration
+}
+
+// Check whether the worker should be restarted after exiting with err.
+func (p RestartPolicy) shouldRestart(err error, restarts int) bool {
+	if p.MaxRetries > 0 && restarts >= p.MaxRetries {
+		return false
+	}
+	switch p.Mode {
+	case RestartOnFailure:
+		return err != nil
+	case RestartAlways:
+		return true
+	default:
+		return false
+	}
+}
+
+// Compute the delay before the next restart.
+func (p RestartPolicy) backoff(restarts int) time.Duration {
+	delay := p.Backoff
+	if delay <= 0 {
+		delay = DefaultRestartBackoff
+	}
+	maxDelay := p.MaxBackoff
+	if maxDelay <= 0 {
+		maxDelay = DefaultMaxRestartBackoff
+	}
+	for i := 0; i < restarts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+// Worker that carries its own restart policy.
+type RestartableWorker interface {
+	Worker
+	RestartPolicy() RestartPolicy
+}
+
+type workerWithPolicy struct {
+	Worker
+	policy RestartPolicy
+}
+
+func (w workerWithPolicy) RestartPolicy() RestartPolicy {
+	return w.policy
+}
+
+func (w workerWithPolicy) unwrap() Worker {
+	return w.Worker
+}
+
+// Wrap the worker so the supervisor restarts it according to the policy.
+func WithRestartPolicy(worker Worker, policy RestartPolicy) Worker {
+	return workerWithPolicy{Worker: worker, policy: policy}
+}
+
+func restartPolicyOf(worker Worker) RestartPolicy {
+	if w, ok := findOption[RestartableWorker](worker); ok {
+		return w.RestartPolicy()
+	}
+	return RestartPolicy{Mode: RestartNever}
+}
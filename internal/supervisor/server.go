@@ -46,3 +46,8 @@ func (w ServerWorker) Start(ctx context.Context, ready chan<- struct{}) error {
 	}()
 	return w.CommandWorker.Start(ctx, commandReady)
 }
+
+// Probe implements Prober by polling the server port.
+func (w ServerWorker) Probe(ctx context.Context) error {
+	return probeTCP(ctx, fmt.Sprintf("0.0.0.0:%v", w.Port))
+}
@@ -0,0 +1,188 @@
+// (c) Cartesi and individual authors (see AUTHORS)
+// SPDX-License-Identifier: Apache-2.0 (see LICENSE)
+
+package supervisor
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Worker that starts only after the workers it depends on are ready.
+// Workers that do not declare dependencies wait for the previous worker in the list.
+type DependentWorker interface {
+	Worker
+	DependsOn() []string
+}
+
+// Worker with its own timeout for becoming ready.
+type ReadyTimeoutWorker interface {
+	Worker
+	ReadyTimeout() time.Duration
+}
+
+type workerWithDependencies struct {
+	Worker
+	dependencies []string
+}
+
+func (w workerWithDependencies) DependsOn() []string {
+	return w.dependencies
+}
+
+func (w workerWithDependencies) unwrap() Worker {
+	return w.Worker
+}
+
+// Wrap the worker so the supervisor starts it once the named workers are ready.
+// Without names, the worker starts right away, in parallel with the others.
+func WithDependencies(worker Worker, names ...string) Worker {
+	return workerWithDependencies{Worker: worker, dependencies: names}
+}
+
+type workerWithReadyTimeout struct {
+	Worker
+	timeout time.Duration
+}
+
+func (w workerWithReadyTimeout) ReadyTimeout() time.Duration {
+	return w.timeout
+}
+
+func (w workerWithReadyTimeout) unwrap() Worker {
+	return w.Worker
+}
+
+// Wrap the worker so the supervisor waits up to timeout for it to be ready.
+func WithReadyTimeout(worker Worker, timeout time.Duration) Worker {
+	return workerWithReadyTimeout{Worker: worker, timeout: timeout}
+}
+
+// Worker that wraps another one to attach options for the supervisor.
+type wrappedWorker interface {
+	unwrap() Worker
+}
+
+// Look for an option interface in the worker and in the workers it wraps.
+func findOption[T any](worker Worker) (T, bool) {
+	for {
+		if option, ok := worker.(T); ok {
+			return option, true
+		}
+		wrapped, ok := worker.(wrappedWorker)
+		if !ok {
+			var zero T
+			return zero, false
+		}
+		worker = wrapped.unwrap()
+	}
+}
+
+// Return the indexes of the workers each worker depends on.
+// Fail if a dependency is unknown or if the dependencies form a cycle.
+func resolveDependencies(workers []Worker) ([][]int, error) {
+	indexes := make(map[string][]int)
+	for i, worker := range workers {
+		indexes[worker.String()] = append(indexes[worker.String()], i)
+	}
+	deps := make([][]int, len(workers))
+	for i, worker := range workers {
+		dependent, ok := findOption[DependentWorker](worker)
+		if !ok {
+			if i > 0 {
+				deps[i] = []int{i - 1}
+			}
+			continue
+		}
+		for _, name := range dependent.DependsOn() {
+			found, ok := indexes[name]
+			if !ok {
+				return nil, fmt.Errorf("supervisor: worker %v depends on unknown worker %q", worker, name)
+			}
+			for _, j := range found {
+				if j != i {
+					deps[i] = append(deps[i], j)
+				}
+			}
+		}
+	}
+
+	// check for cycles with a depth-first search
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	marks := make([]int, len(workers))
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch marks[i] {
+		case visiting:
+			return fmt.Errorf("supervisor: dependency cycle involving worker %v", workers[i])
+		case visited:
+			return nil
+		}
+		marks[i] = visiting
+		for _, j := range deps[i] {
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+		marks[i] = visited
+		return nil
+	}
+	for i := range workers {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return deps, nil
+}
+
+// Moments in which the workers started and became ready, relative to the supervisor start.
+type startupTimeline struct {
+	mutex   sync.Mutex
+	begin   time.Time
+	entries []startupEntry
+}
+
+type startupEntry struct {
+	worker string
+	start  time.Duration
+	ready  time.Duration
+}
+
+func newStartupTimeline() *startupTimeline {
+	return &startupTimeline{begin: time.Now()}
+}
+
+func (t *startupTimeline) record(worker Worker, start, ready time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.entries = append(t.entries, startupEntry{
+		worker: worker.String(),
+		start:  start.Sub(t.begin),
+		ready:  ready.Sub(t.begin),
+	})
+}
+
+// Log the timeline in the order the workers became ready.
+func (t *startupTimeline) log() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	sort.SliceStable(t.entries, func(i, j int) bool {
+		return t.entries[i].ready < t.entries[j].ready
+	})
+	for _, entry := range t.entries {
+		slog.Debug("supervisor: startup timeline",
+			"worker", entry.worker,
+			"start", entry.start.Round(time.Millisecond),
+			"ready", entry.ready.Round(time.Millisecond),
+			"took", (entry.ready - entry.start).Round(time.Millisecond),
+		)
+	}
+	slog.Debug("supervisor: all workers are ready", "took", time.Since(t.begin).Round(time.Millisecond))
+}
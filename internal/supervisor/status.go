@@ -0,0 +1,124 @@
+// (c) Cartesi and individual authors (see AUTHORS)
+// SPDX-License-Identifier: Apache-2.0 (see LICENSE)
+
+package supervisor
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// State of a worker managed by the supervisor.
+type WorkerState string
+
+const (
+	WorkerWaiting    WorkerState = "waiting"
+	WorkerStarting   WorkerState = "starting"
+	WorkerRunning    WorkerState = "running"
+	WorkerRestarting WorkerState = "restarting"
+	WorkerStopped    WorkerState = "stopped"
+	WorkerFailed     WorkerState = "failed"
+)
+
+// Snapshot of the state of a worker.
+type WorkerStatus struct {
+	Name      string      `json:"name"`
+	State     WorkerState `json:"state"`
+	Policy    string      `json:"restartPolicy"`
+	Restarts  int         `json:"restarts"`
+	LastError string      `json:"lastError,omitempty"`
+	StartedAt time.Time   `json:"startedAt"`
+}
+
+// Keep track of the state of the workers.
+// The registry may be shared between the supervisor and the HTTP API.
+type StatusRegistry struct {
+	mutex   sync.Mutex
+	workers []*WorkerStatus
+}
+
+func NewStatusRegistry() *StatusRegistry {
+	return &StatusRegistry{}
+}
+
+// Add a worker to the registry and return its status handle.
+func (r *StatusRegistry) add(name string, policy RestartPolicy) *workerStatusHandle {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	status := &WorkerStatus{
+		Name:   name,
+		State:  WorkerWaiting,
+		Policy: policy.Mode.String(),
+	}
+	r.workers = append(r.workers, status)
+	return &workerStatusHandle{registry: r, status: status}
+}
+
+// Return a copy of the status of every worker.
+func (r *StatusRegistry) List() []WorkerStatus {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	list := make([]WorkerStatus, len(r.workers))
+	for i, status := range r.workers {
+		list[i] = *status
+	}
+	return list
+}
+
+// Get the status of the worker with the given name.
+func (r *StatusRegistry) Get(name string) (WorkerStatus, bool) {
+	for _, status := range r.List() {
+		if status.Name == name {
+			return status, true
+		}
+	}
+	return WorkerStatus{}, false
+}
+
+// Serve the worker list as JSON.
+func (r *StatusRegistry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(r.List())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Handle used by the supervisor to update a single worker status.
+type workerStatusHandle struct {
+	registry *StatusRegistry
+	status   *WorkerStatus
+}
+
+func (h *workerStatusHandle) update(f func(status *WorkerStatus)) {
+	h.registry.mutex.Lock()
+	defer h.registry.mutex.Unlock()
+	f(h.status)
+}
+
+func (h *workerStatusHandle) setState(state WorkerState) {
+	h.update(func(status *WorkerStatus) {
+		status.State = state
+		if state == WorkerStarting {
+			status.StartedAt = time.Now()
+		}
+	})
+}
+
+func (h *workerStatusHandle) setError(state WorkerState, err error) {
+	h.update(func(status *WorkerStatus) {
+		status.State = state
+		if err != nil {
+			status.LastError = err.Error()
+		}
+	})
+}
+
+func (h *workerStatusHandle) restarted() {
+	h.update(func(status *WorkerStatus) {
+		status.State = WorkerRestarting
+		status.Restarts++
+	})
+}
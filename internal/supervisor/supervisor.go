@@ -10,18 +10,29 @@ import (
 	"fmt"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Timeout when waiting for workers to finish.
 const DefaultSupervisorTimeout = time.Second * 15
 
-// Start the workers in order, waiting for each one to be ready before starting the next one.
-// When a worker exits, send a cancel signal to all of them and wait for them to finish.
+// Start each worker once the workers it depends on are ready; independent workers start in
+// parallel. See DependentWorker for how the dependencies are declared.
+// When a worker exits, the supervisor applies its restart policy; if the worker should not be
+// restarted, send a cancel signal to all of them and wait for them to finish.
 type SupervisorWorker struct {
 	Name    string
 	Workers []Worker
+	// Timeout for each worker to be ready and for all of them to finish.
+	// A worker may override the ready timeout with WithReadyTimeout.
 	Timeout time.Duration
+	// Interval between liveness probes; DefaultProbeInterval if zero.
+	ProbeInterval time.Duration
+	// Registry updated with the state of each worker; optional.
+	Status *StatusRegistry
+	// Called when the supervisor exits, after the workers finish or time out; optional.
+	Cleanup func()
 }
 
 func (w SupervisorWorker) String() string {
@@ -29,6 +40,9 @@ func (w SupervisorWorker) String() string {
 }
 
 func (w SupervisorWorker) Start(ctx context.Context, ready chan<- struct{}) error {
+	if w.Cleanup != nil {
+		defer w.Cleanup()
+	}
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
@@ -40,34 +54,75 @@ func (w SupervisorWorker) Start(ctx context.Context, ready chan<- struct{}) erro
 		slog.Debug("supervisor: using custom timeout", "timeout", timeout)
 	}
 
+	deps, err := resolveDependencies(w.Workers)
+	if err != nil {
+		return err
+	}
+
+	registry := w.Status
+	if registry == nil {
+		registry = NewStatusRegistry()
+	}
+
 	// Start workers
 	var wg sync.WaitGroup
-Loop:
-	for _, worker := range w.Workers {
-		logger := slog.With("worker", worker)
+	timeline := newStartupTimeline()
+	workersReady := make([]chan struct{}, len(w.Workers))
+	for i := range w.Workers {
+		workersReady[i] = make(chan struct{})
+	}
+	for i, worker := range w.Workers {
+		status := registry.add(worker.String(), restartPolicyOf(worker))
+		readyTimeout := timeout
+		if t, ok := findOption[ReadyTimeoutWorker](worker); ok && t.ReadyTimeout() > 0 {
+			readyTimeout = t.ReadyTimeout()
+		}
 		wg.Add(1)
-		innerReady := make(chan struct{})
 		go func() {
 			defer wg.Done()
-			defer cancel()
-			err := worker.Start(ctx, innerReady)
-			if err != nil && !errors.Is(err, context.Canceled) {
-				logger.Warn("supervisor: worker exitted with error", "error", err)
-			} else {
-				logger.Debug("supervisor: worker exitted with success")
+			logger := slog.With("worker", worker)
+			for _, dep := range deps[i] {
+				select {
+				case <-workersReady[dep]:
+				case <-ctx.Done():
+					status.setState(WorkerStopped)
+					return
+				}
+			}
+
+			start := time.Now()
+			innerReady := make(chan struct{})
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer cancel()
+				w.supervise(ctx, worker, status, innerReady)
+			}()
+			select {
+			case <-innerReady:
+				logger.Debug("supervisor: worker is ready")
+				timeline.record(worker, start, time.Now())
+				close(workersReady[i])
+			case <-time.After(readyTimeout):
+				logger.Warn("supervisor: worker timed out", "timeout", readyTimeout)
+				cancel()
+			case <-ctx.Done():
 			}
 		}()
+	}
+
+	// Wait for all workers to be ready
+Loop:
+	for i := range w.Workers {
 		select {
-		case <-innerReady:
-			logger.Debug("supervisor: worker is ready")
-		case <-time.After(timeout):
-			logger.Warn("supervisor: worker timed out")
-			cancel()
-			break Loop
+		case <-workersReady[i]:
 		case <-ctx.Done():
 			break Loop
 		}
 	}
+	if ctx.Err() == nil {
+		timeline.log()
+	}
 
 	// Wait for context to be done
 	ready <- struct{}{}
@@ -87,3 +142,112 @@ Loop:
 		return fmt.Errorf("supervisor: timed out waiting for workers")
 	}
 }
+
+// Run the worker until it exits and should not be restarted anymore.
+// The ready signal is forwarded only once, even if the worker is restarted before it is ready.
+func (w SupervisorWorker) supervise(
+	ctx context.Context,
+	worker Worker,
+	status *workerStatusHandle,
+	ready chan<- struct{},
+) {
+	logger := slog.With("worker", worker)
+	policy := restartPolicyOf(worker)
+	var forwarded atomic.Bool
+	for restarts := 0; ; restarts++ {
+		status.setState(WorkerStarting)
+		workerCtx, workerCancel := context.WithCancel(ctx)
+		workerReady := make(chan struct{}, 1)
+		var probeErr atomic.Pointer[error]
+		go func() {
+			select {
+			case <-workerReady:
+			case <-workerCtx.Done():
+				return
+			}
+			status.setState(WorkerRunning)
+			if forwarded.CompareAndSwap(false, true) {
+				select {
+				case ready <- struct{}{}:
+				case <-workerCtx.Done():
+					return
+				}
+			}
+			if err := w.probe(workerCtx, worker); err != nil {
+				probeErr.Store(&err)
+				workerCancel()
+			}
+		}()
+		err := worker.Start(workerCtx, workerReady)
+		workerCancel()
+		if perr := probeErr.Load(); perr != nil {
+			err = *perr
+		}
+
+		if ctx.Err() != nil {
+			logger.Debug("supervisor: worker stopped")
+			status.setState(WorkerStopped)
+			return
+		}
+		if err != nil && !errors.Is(err, context.Canceled) {
+			logger.Warn("supervisor: worker exitted with error", "error", err)
+		} else {
+			logger.Debug("supervisor: worker exitted with success")
+		}
+		if !policy.shouldRestart(err, restarts) {
+			if err != nil {
+				status.setError(WorkerFailed, err)
+			} else {
+				status.setState(WorkerStopped)
+			}
+			return
+		}
+
+		delay := policy.backoff(restarts)
+		status.setError(WorkerRestarting, err)
+		status.restarted()
+		logger.Info("supervisor: restarting worker", "restarts", restarts+1, "after", delay)
+		select {
+		case <-ctx.Done():
+			status.setState(WorkerStopped)
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Probe the worker periodically while it is alive.
+// Return an error once the probe fails DefaultProbeThreshold times in a row.
+func (w SupervisorWorker) probe(ctx context.Context, worker Worker) error {
+	prober, ok := proberOf(worker)
+	if !ok {
+		return nil
+	}
+	interval := w.ProbeInterval
+	if interval == 0 {
+		interval = DefaultProbeInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		err := prober.Probe(ctx)
+		if err == nil {
+			failures = 0
+			continue
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		failures++
+		slog.Warn("supervisor: liveness probe failed", "worker", worker, "failures", failures, "error", err)
+		if failures >= DefaultProbeThreshold {
+			return fmt.Errorf("supervisor: liveness probe failed %d times: %w", failures, err)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+// (c) Cartesi and individual authors (see AUTHORS)
+// SPDX-License-Identifier: Apache-2.0 (see LICENSE)
+
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const testTimeout = 5 * time.Second
+
+type SupervisorSuite struct {
+	suite.Suite
+}
+
+func TestSupervisorSuite(t *testing.T) {
+	suite.Run(t, new(SupervisorSuite))
+}
+
+// Worker that fails the first failures times and then runs until canceled.
+type flakyWorker struct {
+	name     string
+	failures int32
+	starts   *atomic.Int32
+}
+
+func (w flakyWorker) String() string {
+	return w.name
+}
+
+func (w flakyWorker) Start(ctx context.Context, ready chan<- struct{}) error {
+	start := w.starts.Add(1)
+	if start <= w.failures {
+		return errors.New("boom")
+	}
+	ready <- struct{}{}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// Worker whose liveness probe always fails.
+type deadWorker struct {
+	starts *atomic.Int32
+}
+
+func (w deadWorker) String() string {
+	return "dead"
+}
+
+func (w deadWorker) Start(ctx context.Context, ready chan<- struct{}) error {
+	w.starts.Add(1)
+	ready <- struct{}{}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (w deadWorker) Probe(ctx context.Context) error {
+	return errors.New("not alive")
+}
+
+// Worker that records when it started and becomes ready after a delay.
+type slowWorker struct {
+	name    string
+	delay   time.Duration
+	started chan<- string
+}
+
+func (w slowWorker) String() string {
+	return w.name
+}
+
+func (w slowWorker) Start(ctx context.Context, ready chan<- struct{}) error {
+	w.started <- w.name
+	select {
+	case <-time.After(w.delay):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	ready <- struct{}{}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (s *SupervisorSuite) TestRestartPolicyShouldRestart() {
+	never := RestartPolicy{Mode: RestartNever}
+	s.False(never.shouldRestart(errors.New("err"), 0))
+
+	onFailure := RestartPolicy{Mode: RestartOnFailure, MaxRetries: 2}
+	s.True(onFailure.shouldRestart(errors.New("err"), 0))
+	s.True(onFailure.shouldRestart(errors.New("err"), 1))
+	s.False(onFailure.shouldRestart(errors.New("err"), 2))
+	s.False(onFailure.shouldRestart(nil, 0))
+
+	always := RestartPolicy{Mode: RestartAlways}
+	s.True(always.shouldRestart(nil, 100))
+}
+
+func (s *SupervisorSuite) TestRestartPolicyBackoff() {
+	policy := RestartPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	s.Equal(time.Second, policy.backoff(0))
+	s.Equal(2*time.Second, policy.backoff(1))
+	s.Equal(4*time.Second, policy.backoff(2))
+	s.Equal(5*time.Second, policy.backoff(3))
+	s.Equal(5*time.Second, policy.backoff(10))
+}
+
+func (s *SupervisorSuite) TestParseRestartMode() {
+	for _, mode := range []RestartMode{RestartNever, RestartOnFailure, RestartAlways} {
+		parsed, err := ParseRestartMode(mode.String())
+		s.NoError(err)
+		s.Equal(mode, parsed)
+	}
+	_, err := ParseRestartMode("sometimes")
+	s.Error(err)
+}
+
+func (s *SupervisorSuite) TestItRestartsFailingWorker() {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	var starts atomic.Int32
+	registry := NewStatusRegistry()
+	w := SupervisorWorker{
+		Name: "test",
+		Workers: []Worker{
+			WithRestartPolicy(flakyWorker{name: "flaky", failures: 2, starts: &starts}, RestartPolicy{
+				Mode:       RestartOnFailure,
+				MaxRetries: 3,
+				Backoff:    time.Millisecond,
+			}),
+		},
+		Status: registry,
+	}
+	result := s.startSupervisor(ctx, w)
+
+	s.Eventually(func() bool {
+		status, ok := registry.Get("flaky")
+		return ok && status.State == WorkerRunning
+	}, testTimeout, 10*time.Millisecond)
+	status, _ := registry.Get("flaky")
+	s.Equal(2, status.Restarts)
+	s.Equal("boom", status.LastError)
+	s.Equal(int32(3), starts.Load())
+
+	cancel()
+	s.NoError(<-result)
+}
+
+func (s *SupervisorSuite) TestItStopsWhenRetriesAreExhausted() {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	var starts atomic.Int32
+	registry := NewStatusRegistry()
+	w := SupervisorWorker{
+		Name: "test",
+		Workers: []Worker{
+			WithRestartPolicy(flakyWorker{name: "flaky", failures: 10, starts: &starts}, RestartPolicy{
+				Mode:       RestartOnFailure,
+				MaxRetries: 2,
+				Backoff:    time.Millisecond,
+			}),
+		},
+		Status: registry,
+	}
+	err := w.Start(ctx, make(chan struct{}, 1))
+	s.NoError(err)
+	s.Equal(int32(3), starts.Load())
+	status, _ := registry.Get("flaky")
+	s.Equal(WorkerFailed, status.State)
+}
+
+func (s *SupervisorSuite) TestItRestartsWorkerWhenProbeFails() {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	var starts atomic.Int32
+	registry := NewStatusRegistry()
+	w := SupervisorWorker{
+		Name: "test",
+		Workers: []Worker{
+			WithRestartPolicy(deadWorker{starts: &starts}, RestartPolicy{
+				Mode:       RestartOnFailure,
+				MaxRetries: 1,
+				Backoff:    time.Millisecond,
+			}),
+		},
+		ProbeInterval: time.Millisecond,
+		Status:        registry,
+	}
+	err := w.Start(ctx, make(chan struct{}, 1))
+	s.NoError(err)
+	s.Equal(int32(2), starts.Load())
+	status, _ := registry.Get("dead")
+	s.Equal(WorkerFailed, status.State)
+	s.Contains(status.LastError, "liveness probe failed")
+}
+
+func (s *SupervisorSuite) TestItStartsIndependentWorkersInParallel() {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	started := make(chan string, 3)
+	w := SupervisorWorker{
+		Name: "test",
+		Workers: []Worker{
+			WithDependencies(slowWorker{name: "a", delay: time.Hour, started: started}),
+			WithDependencies(slowWorker{name: "b", delay: time.Hour, started: started}),
+			WithDependencies(slowWorker{name: "c", delay: time.Hour, started: started}),
+		},
+	}
+	go func() {
+		_ = w.Start(ctx, make(chan struct{}, 1))
+	}()
+	var names []string
+	for range w.Workers {
+		select {
+		case name := <-started:
+			names = append(names, name)
+		case <-ctx.Done():
+			s.FailNow("workers did not start in parallel")
+		}
+	}
+	s.ElementsMatch([]string{"a", "b", "c"}, names)
+}
+
+func (s *SupervisorSuite) TestItStartsWorkersAfterTheirDependencies() {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	started := make(chan string, 4)
+	w := SupervisorWorker{
+		Name: "test",
+		Workers: []Worker{
+			WithDependencies(slowWorker{name: "app", started: started}, "http"),
+			WithDependencies(slowWorker{name: "http", started: started}),
+			WithDependencies(slowWorker{name: "node", delay: 50 * time.Millisecond, started: started}),
+			// without dependencies, it waits for the previous worker
+			slowWorker{name: "claimer", started: started},
+		},
+	}
+	result := s.startSupervisor(ctx, w)
+	close(started)
+	var names []string
+	for name := range started {
+		names = append(names, name)
+	}
+	s.Len(names, 4)
+	s.Less(slices.Index(names, "http"), slices.Index(names, "app"))
+	s.Equal("claimer", names[3])
+	cancel()
+	s.NoError(<-result)
+}
+
+func (s *SupervisorSuite) TestItFailsOnInvalidDependencies() {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	started := make(chan string, 2)
+	unknown := SupervisorWorker{
+		Name: "test",
+		Workers: []Worker{
+			WithDependencies(slowWorker{name: "a", started: started}, "b"),
+		},
+	}
+	err := unknown.Start(ctx, make(chan struct{}, 1))
+	s.ErrorContains(err, `depends on unknown worker "b"`)
+
+	cycle := SupervisorWorker{
+		Name: "test",
+		Workers: []Worker{
+			WithDependencies(slowWorker{name: "a", started: started}, "b"),
+			WithDependencies(slowWorker{name: "b", started: started}, "a"),
+		},
+	}
+	err = cycle.Start(ctx, make(chan struct{}, 1))
+	s.ErrorContains(err, "dependency cycle")
+	s.Empty(started)
+}
+
+func (s *SupervisorSuite) TestItUsesTheWorkerReadyTimeout() {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	started := make(chan string, 1)
+	registry := NewStatusRegistry()
+	w := SupervisorWorker{
+		Name: "test",
+		Workers: []Worker{
+			WithReadyTimeout(slowWorker{name: "slow", delay: time.Hour, started: started}, time.Millisecond),
+		},
+		Status: registry,
+	}
+	err := w.Start(ctx, make(chan struct{}, 1))
+	s.NoError(err)
+	s.NoError(ctx.Err())
+	status, _ := registry.Get("slow")
+	s.Equal(WorkerStopped, status.State)
+}
+
+func (s *SupervisorSuite) TestItFindsOptionsInWrappedWorkers() {
+	policy := RestartPolicy{Mode: RestartAlways}
+	worker := WithRestartPolicy(WithDependencies(deadWorker{}, "node"), policy)
+	s.Equal(policy, restartPolicyOf(worker))
+	dependent, ok := findOption[DependentWorker](worker)
+	s.True(ok)
+	s.Equal([]string{"node"}, dependent.DependsOn())
+	_, ok = proberOf(worker)
+	s.True(ok)
+}
+
+func (s *SupervisorSuite) startSupervisor(ctx context.Context, w SupervisorWorker) <-chan error {
+	ready := make(chan struct{}, 1)
+	result := make(chan error, 1)
+	go func() {
+		result <- w.Start(ctx, ready)
+	}()
+	select {
+	case <-ready:
+	case <-ctx.Done():
+		s.FailNow("supervisor timed out")
+	}
+	return result
+}
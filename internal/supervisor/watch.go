@@ -0,0 +1,236 @@
+// (c) Cartesi and individual authors (see AUTHORS)
+// SPDX-License-Identifier: Apache-2.0 (see LICENSE)
+
+package supervisor
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Default values used when watching files.
+const (
+	DefaultWatchPollInterval = 250 * time.Millisecond
+	DefaultWatchDebounce     = 300 * time.Millisecond
+)
+
+// This worker runs the inner worker and restarts it when any file matching the patterns changes.
+// Patterns use the filepath.Match syntax, plus `**` to match any number of directories.
+type WatchWorker struct {
+	Worker       Worker
+	Patterns     []string
+	Debounce     time.Duration
+	PollInterval time.Duration
+	// Called before the inner worker is started, including restarts.
+	OnStart func()
+}
+
+func (w WatchWorker) String() string {
+	return w.Worker.String()
+}
+
+func (w WatchWorker) Start(ctx context.Context, ready chan<- struct{}) error {
+	changes := make(chan []string)
+	go w.watch(ctx, changes)
+
+	readySent := false
+	for {
+		if w.OnStart != nil {
+			w.OnStart()
+		}
+		innerCtx, innerCancel := context.WithCancel(ctx)
+		innerReady := make(chan struct{}, 1)
+		result := make(chan error, 1)
+		go func() {
+			result <- w.Worker.Start(innerCtx, innerReady)
+		}()
+
+	Wait:
+		for {
+			select {
+			case <-innerReady:
+				if !readySent {
+					readySent = true
+					ready <- struct{}{}
+				}
+			case err := <-result:
+				innerCancel()
+				return err
+			case files := <-changes:
+				slog.Info("watch: files changed, restarting", "worker", w.Worker, "files", files)
+				innerCancel()
+				<-result
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				break Wait
+			}
+		}
+	}
+}
+
+// Poll the files matching the patterns and send the changed ones once they settle down.
+func (w WatchWorker) watch(ctx context.Context, changes chan<- []string) {
+	interval := w.PollInterval
+	if interval == 0 {
+		interval = DefaultWatchPollInterval
+	}
+	debounce := w.Debounce
+	if debounce == 0 {
+		debounce = DefaultWatchDebounce
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	snapshot := scanFiles(w.Patterns)
+	pending := map[string]bool{}
+	var lastChange time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		current := scanFiles(w.Patterns)
+		for _, file := range diffSnapshots(snapshot, current) {
+			pending[file] = true
+			lastChange = time.Now()
+		}
+		snapshot = current
+		if len(pending) == 0 || time.Since(lastChange) < debounce {
+			continue
+		}
+		files := make([]string, 0, len(pending))
+		for file := range pending {
+			files = append(files, file)
+		}
+		sort.Strings(files)
+		select {
+		case changes <- files:
+			pending = map[string]bool{}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// State of a watched file.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Collect the state of every file matching the patterns.
+func scanFiles(patterns []string) map[string]fileStamp {
+	files := map[string]fileStamp{}
+	for _, pattern := range patterns {
+		pattern = filepath.ToSlash(filepath.Clean(pattern))
+		root := globRoot(pattern)
+		// without `**`, there is no need to descend deeper than the pattern
+		maxDepth := -1
+		if !strings.Contains(pattern, "**") {
+			maxDepth = strings.Count(pattern, "/") - strings.Count(filepath.ToSlash(root), "/")
+		}
+		_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if maxDepth >= 0 && path != root && depth(root, path) >= maxDepth {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !matchGlob(pattern, filepath.ToSlash(path)) {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			files[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+			return nil
+		})
+	}
+	return files
+}
+
+// Number of directories between root and path.
+func depth(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return 0
+	}
+	return strings.Count(filepath.ToSlash(rel), "/") + 1
+}
+
+// Return the files that were created, removed or modified.
+func diffSnapshots(before, after map[string]fileStamp) []string {
+	var changed []string
+	for path, stamp := range after {
+		if old, ok := before[path]; !ok || old != stamp {
+			changed = append(changed, path)
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	return changed
+}
+
+// Return the directory before the first segment with glob meta characters.
+func globRoot(pattern string) string {
+	segments := strings.Split(pattern, "/")
+	var root []string
+	for _, segment := range segments[:len(segments)-1] {
+		if strings.ContainsAny(segment, "*?[") {
+			break
+		}
+		root = append(root, segment)
+	}
+	if len(root) == 0 {
+		if strings.HasPrefix(pattern, "/") {
+			return string(os.PathSeparator)
+		}
+		return "."
+	}
+	if len(root) == 1 && root[0] == "" {
+		return string(os.PathSeparator)
+	}
+	return filepath.FromSlash(strings.Join(root, "/"))
+}
+
+// Check whether the slash-separated path matches the pattern.
+func matchGlob(pattern, path string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(path, "/"))
+}
+
+func matchSegments(pattern, path []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(path); i++ {
+				if matchSegments(pattern[1:], path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 {
+			return false
+		}
+		ok, err := filepath.Match(pattern[0], path[0])
+		if err != nil || !ok {
+			return false
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	return len(path) == 0
+}
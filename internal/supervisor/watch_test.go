@@ -0,0 +1,109 @@
+// (c) Cartesi and individual authors (see AUTHORS)
+// SPDX-License-Identifier: Apache-2.0 (see LICENSE)
+
+package supervisor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type WatchSuite struct {
+	suite.Suite
+	tempDir string
+}
+
+func TestWatchSuite(t *testing.T) {
+	suite.Run(t, new(WatchSuite))
+}
+
+func (s *WatchSuite) SetupTest() {
+	s.tempDir = s.T().TempDir()
+}
+
+// Worker that counts how many times it was started.
+type countingWorker struct {
+	starts *atomic.Int32
+}
+
+func (w countingWorker) String() string {
+	return "counting"
+}
+
+func (w countingWorker) Start(ctx context.Context, ready chan<- struct{}) error {
+	w.starts.Add(1)
+	ready <- struct{}{}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (s *WatchSuite) TestMatchGlob() {
+	s.True(matchGlob("*.go", "main.go"))
+	s.False(matchGlob("*.go", "src/main.go"))
+	s.True(matchGlob("src/**/*.go", "src/main.go"))
+	s.True(matchGlob("src/**/*.go", "src/a/b/main.go"))
+	s.False(matchGlob("src/**/*.go", "test/main.go"))
+	s.True(matchGlob("**", "a/b/c"))
+}
+
+func (s *WatchSuite) TestGlobRoot() {
+	s.Equal(".", globRoot("*.go"))
+	s.Equal("src", globRoot("src/**/*.go"))
+	s.Equal(filepath.FromSlash("a/b"), globRoot("a/b/*.py"))
+}
+
+func (s *WatchSuite) TestItScansMatchingFiles() {
+	s.writeFile("main.go", "package main")
+	s.writeFile("sub/lib.go", "package sub")
+	s.writeFile("sub/README.md", "readme")
+
+	files := scanFiles([]string{filepath.Join(s.tempDir, "**", "*.go")})
+	s.Len(files, 2)
+
+	files = scanFiles([]string{filepath.Join(s.tempDir, "*.go")})
+	s.Len(files, 1)
+}
+
+func (s *WatchSuite) TestItRestartsWorkerOnChanges() {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	s.writeFile("app.py", "print(1)")
+
+	var starts, onStart atomic.Int32
+	w := WatchWorker{
+		Worker:       countingWorker{starts: &starts},
+		Patterns:     []string{filepath.Join(s.tempDir, "*.py")},
+		Debounce:     10 * time.Millisecond,
+		PollInterval: 5 * time.Millisecond,
+		OnStart:      func() { onStart.Add(1) },
+	}
+	ready := make(chan struct{}, 1)
+	result := make(chan error, 1)
+	go func() {
+		result <- w.Start(ctx, ready)
+	}()
+	<-ready
+
+	// make sure the modification time changes
+	time.Sleep(20 * time.Millisecond)
+	s.writeFile("app.py", "print(2)")
+	s.Eventually(func() bool {
+		return starts.Load() == 2
+	}, testTimeout, 5*time.Millisecond)
+	s.Equal(int32(2), onStart.Load())
+
+	cancel()
+	s.ErrorIs(<-result, context.Canceled)
+}
+
+func (s *WatchSuite) writeFile(name string, content string) {
+	path := filepath.Join(s.tempDir, name)
+	s.Require().NoError(os.MkdirAll(filepath.Dir(path), 0755))     // nolint
+	s.Require().NoError(os.WriteFile(path, []byte(content), 0644)) // nolint
+}
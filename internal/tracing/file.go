@@ -0,0 +1,78 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Span as a line of the trace file.
+type FileSpan struct {
+	TraceId      string            `json:"traceId"`
+	SpanId       string            `json:"spanId"`
+	ParentSpanId string            `json:"parentSpanId,omitempty"`
+	Name         string            `json:"name"`
+	Start        time.Time         `json:"start"`
+	End          time.Time         `json:"end"`
+	DurationMs   float64           `json:"durationMs"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+	Status       string            `json:"status,omitempty"`
+	Error        string            `json:"error,omitempty"`
+}
+
+// Write each span as a line of JSON.
+type FileExporter struct {
+	mutex   sync.Mutex
+	w       io.WriteCloser
+	encoder *json.Encoder
+}
+
+func NewFileExporter(w io.WriteCloser) *FileExporter {
+	return &FileExporter{w: w, encoder: json.NewEncoder(w)}
+}
+
+func (e *FileExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	for _, span := range spans {
+		if err := e.encoder.Encode(toFileSpan(span)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *FileExporter) Shutdown(ctx context.Context) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.w.Close()
+}
+
+func toFileSpan(span sdktrace.ReadOnlySpan) FileSpan {
+	fileSpan := FileSpan{
+		TraceId:    span.SpanContext().TraceID().String(),
+		SpanId:     span.SpanContext().SpanID().String(),
+		Name:       span.Name(),
+		Start:      span.StartTime(),
+		End:        span.EndTime(),
+		DurationMs: float64(span.EndTime().Sub(span.StartTime()).Microseconds()) / 1000, // nolint
+	}
+	if span.Parent().IsValid() {
+		fileSpan.ParentSpanId = span.Parent().SpanID().String()
+	}
+	if attributes := span.Attributes(); len(attributes) > 0 {
+		fileSpan.Attributes = make(map[string]string, len(attributes))
+		for _, kv := range attributes {
+			fileSpan.Attributes[string(kv.Key)] = kv.Value.Emit()
+		}
+	}
+	if status := span.Status(); status.Code != 0 {
+		fileSpan.Status = status.Code.String()
+		fileSpan.Error = status.Description
+	}
+	return fileSpan
+}
@@ -0,0 +1,143 @@
+// Package tracing follows each input through nonodo with OpenTelemetry spans, from its
+// ingestion to the persistence of its outputs, including the calls of the application.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters of the spans.
+const (
+	ExporterNone = "none"
+	// OTLP over HTTP, configured by the standard OTEL_EXPORTER_OTLP_* environment variables.
+	ExporterOTLP = "otlp"
+	// One JSON line per span in a local file.
+	ExporterFile = "file"
+)
+
+var Exporters = []string{ExporterNone, ExporterOTLP, ExporterFile}
+
+// Time to export the last spans when nonodo stops.
+const ShutdownTimeout = 5 * time.Second
+
+const tracerName = "github.com/calindra/nonodo"
+
+// Attributes of the spans.
+const (
+	AppContractKey = attribute.Key("nonodo.app_contract")
+	InputIdKey     = attribute.Key("nonodo.input.id")
+	InputIndexKey  = attribute.Key("nonodo.input.index")
+	StatusKey      = attribute.Key("nonodo.input.status")
+)
+
+// Set up the global tracer provider with the exporter.
+// Return the function that exports the pending spans and stops the provider.
+func Setup(ctx context.Context, exporter string, file string) (func(), error) {
+	var spanExporter sdktrace.SpanExporter
+	switch exporter {
+	case ExporterNone, "":
+		return func() {}, nil
+	case ExporterOTLP:
+		otlp, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("tracing: create the otlp exporter: %w", err)
+		}
+		spanExporter = otlp
+	case ExporterFile:
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644) // nolint
+		if err != nil {
+			return nil, fmt.Errorf("tracing: open the trace file: %w", err)
+		}
+		spanExporter = NewFileExporter(f)
+	default:
+		return nil, fmt.Errorf("tracing: unknown exporter %q", exporter)
+	}
+	res, err := resource.Merge(resource.Default(),
+		resource.NewSchemaless(attribute.String("service.name", "nonodo")))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			otel.Handle(err)
+		}
+	}, nil
+}
+
+// Start a span with the nonodo tracer.
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// Write the trace context of ctx to the header, as the W3C traceparent.
+func Inject(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// Read the trace context of the header into ctx.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// Span contexts of the ingestion of the inputs not processed yet, so their processing joins
+// the same trace.
+type InputSpans struct {
+	mutex sync.Mutex
+	spans map[string]trace.SpanContext
+}
+
+// Inputs ingested by nonodo.
+var Inputs = &InputSpans{spans: map[string]trace.SpanContext{}}
+
+func inputKey(appContract string, id string) string {
+	return appContract + "/" + id
+}
+
+// Keep the span of ctx as the origin of the input; nothing is kept if tracing is disabled.
+func (s *InputSpans) Add(ctx context.Context, appContract string, id string) {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.spans[inputKey(appContract, id)] = spanContext
+}
+
+// Return ctx with the origin of the input as the parent span, if there is one.
+func (s *InputSpans) Context(ctx context.Context, appContract string, id string) context.Context {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if spanContext, ok := s.spans[inputKey(appContract, id)]; ok {
+		return trace.ContextWithRemoteSpanContext(ctx, spanContext)
+	}
+	return ctx
+}
+
+// Forget the origin of the processed input.
+func (s *InputSpans) Remove(appContract string, id string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.spans, inputKey(appContract, id))
+}
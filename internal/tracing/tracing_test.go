@@ -0,0 +1,127 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type TracingSuite struct {
+	suite.Suite
+	out      *bufferCloser
+	provider *sdktrace.TracerProvider
+}
+
+func TestTracingSuite(t *testing.T) {
+	suite.Run(t, new(TracingSuite))
+}
+
+type bufferCloser struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *bufferCloser) Close() error {
+	b.closed = true
+	return nil
+}
+
+func (s *TracingSuite) SetupTest() {
+	s.out = &bufferCloser{}
+	s.provider = sdktrace.NewTracerProvider(sdktrace.WithSyncer(NewFileExporter(s.out)))
+	otel.SetTracerProvider(s.provider)
+}
+
+func (s *TracingSuite) TearDownTest() {
+	otel.SetTracerProvider(noop.NewTracerProvider())
+}
+
+// Spans written to the file, in the order they ended.
+func (s *TracingSuite) spans() []FileSpan {
+	var spans []FileSpan
+	decoder := json.NewDecoder(&s.out.Buffer)
+	for decoder.More() {
+		var span FileSpan
+		s.Require().NoError(decoder.Decode(&span))
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+func (s *TracingSuite) TestTheFileHasOneLinePerSpan() {
+	ctx, parent := Start(context.Background(), "parent", InputIdKey.String("7"))
+	_, child := Start(ctx, "child")
+	child.RecordError(errors.New("boom"))
+	child.SetStatus(codes.Error, "boom")
+	child.End()
+	parent.End()
+
+	spans := s.spans()
+	s.Require().Len(spans, 2) // nolint
+	s.Equal("child", spans[0].Name)
+	s.Equal("Error", spans[0].Status)
+	s.Equal("boom", spans[0].Error)
+	s.Equal("parent", spans[1].Name)
+	s.Equal(spans[1].TraceId, spans[0].TraceId)
+	s.Equal(spans[1].SpanId, spans[0].ParentSpanId)
+	s.Empty(spans[1].ParentSpanId)
+	s.Equal(map[string]string{"nonodo.input.id": "7"}, spans[1].Attributes)
+
+	s.NoError(s.provider.Shutdown(context.Background()))
+	s.True(s.out.closed)
+}
+
+func (s *TracingSuite) TestTheInputsJoinTheTraceOfTheirIngestion() {
+	ctx, ingestion := Start(context.Background(), "ingestion")
+	Inputs.Add(ctx, "0xapp", "1")
+	ingestion.End()
+
+	_, processing := Start(Inputs.Context(context.Background(), "0xapp", "1"), "processing")
+	processing.End()
+	Inputs.Remove("0xapp", "1")
+	_, other := Start(Inputs.Context(context.Background(), "0xapp", "1"), "other")
+	other.End()
+
+	spans := s.spans()
+	s.Require().Len(spans, 3) // nolint
+	s.Equal(spans[0].TraceId, spans[1].TraceId)
+	s.Equal(spans[0].SpanId, spans[1].ParentSpanId)
+	s.NotEqual(spans[0].TraceId, spans[2].TraceId)
+}
+
+func (s *TracingSuite) TestNothingIsKeptWhenTracingIsDisabled() {
+	otel.SetTracerProvider(noop.NewTracerProvider())
+	ctx, span := Start(context.Background(), "ingestion")
+	Inputs.Add(ctx, "0xapp", "2")
+	span.End()
+	ctx = Inputs.Context(context.Background(), "0xapp", "2")
+	s.False(trace.SpanContextFromContext(ctx).IsValid())
+}
+
+func (s *TracingSuite) TestTheHeaderCarriesTheTrace() {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	ctx, span := Start(context.Background(), "finish")
+	defer span.End()
+	header := http.Header{}
+	Inject(ctx, header)
+	s.NotEmpty(header.Get("traceparent"))
+	extracted := trace.SpanContextFromContext(Extract(context.Background(), header))
+	s.Equal(span.SpanContext().TraceID(), extracted.TraceID())
+	s.Equal(span.SpanContext().SpanID(), extracted.SpanID())
+}
+
+func (s *TracingSuite) TestItRejectsAnUnknownExporter() {
+	_, err := Setup(context.Background(), "jaeger", "")
+	s.ErrorContains(err, `unknown exporter "jaeger"`)
+}
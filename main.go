@@ -7,6 +7,7 @@ package main
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -17,15 +18,22 @@ import (
 	"syscall"
 	"time"
 
+	celestiapipeline "github.com/calindra/nonodo/internal/celestia"
+	"github.com/calindra/nonodo/internal/commons"
+	"github.com/calindra/nonodo/internal/config"
 	"github.com/calindra/nonodo/internal/dataavailability"
 	"github.com/calindra/nonodo/internal/devnet"
 	"github.com/calindra/nonodo/internal/nonodo"
 	"github.com/calindra/nonodo/internal/sequencers/avail"
 	"github.com/calindra/nonodo/internal/sequencers/espresso"
+	"github.com/calindra/nonodo/internal/supervisor"
 	"github.com/carlmjohnson/versioninfo"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/jmoiron/sqlx"
 	"github.com/joho/godotenv"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
 	"github.com/lmittmann/tint"
 	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
@@ -88,7 +96,15 @@ var addressBookCmd = &cobra.Command{
 	Short: "Show address book",
 	Run: func(cmd *cobra.Command, args []string) {
 		slog.Debug("Read json and print address...")
-		devnet.ShowAddresses()
+		if opts.DeploymentFile == "" {
+			devnet.ShowAddresses()
+			return
+		}
+		addressBook, err := opts.LoadAddressBook()
+		if err != nil {
+			exitf("%v", err)
+		}
+		devnet.PrintAddresses(addressBook)
 	},
 }
 
@@ -105,6 +121,15 @@ type CelestiaOpts struct {
 	chainId     int64
 }
 
+type CelestiaPipelineOpts struct {
+	Local              bool
+	LocalBlockTime     time.Duration
+	CommitmentWindow   uint64
+	PollInterval       time.Duration
+	MaxAttempts        int
+	ApplicationAddress string
+}
+
 // Espresso
 type EspressoOpts struct {
 	Payload   string
@@ -138,9 +163,10 @@ var availCmd = &cobra.Command{
 }
 
 var (
-	debug bool
-	color bool
-	opts  = nonodo.NewNonodoOpts()
+	debug      bool
+	color      bool
+	configFile string
+	opts       = nonodo.NewNonodoOpts()
 )
 
 func markFlagRequired(cmd *cobra.Command, flagNames ...string) {
@@ -200,7 +226,7 @@ func addCelestiaSubcommands(celestiaCmd *cobra.Command) {
 				return err
 			}
 
-			height, start, end, err := dataavailability.SubmitBlob(ctx, url, token, celestia.Namespace, []byte(celestia.Payload))
+			height, start, end, err := dataavailability.SubmitBlob(ctx, url, token, celestia.Namespace, content)
 			if err != nil {
 				slog.Error("Submit", "error", err)
 				return err
@@ -241,7 +267,7 @@ func addCelestiaSubcommands(celestiaCmd *cobra.Command) {
 				return err
 			}
 
-			height, start, end, err := dataavailability.SubmitBlob(ctx, url, token, celestia.Namespace, []byte(celestia.Payload))
+			height, start, end, err := dataavailability.SubmitBlob(ctx, url, token, celestia.Namespace, content)
 			if err != nil {
 				slog.Error("Submit", "error", err)
 				return err
@@ -320,12 +346,12 @@ func addCelestiaSubcommands(celestiaCmd *cobra.Command) {
 			slog.Info("Send a payload to Celestia Relay")
 
 			ctx := cmd.Context()
-			err := dataavailability.CallCelestiaRelay(ctx, celestia.Height, celestia.Start, celestia.End, APP_ADDRESS, []byte{}, celestia.RpcUrl, celestia.chainId)
+			tx, err := dataavailability.CallCelestiaRelay(ctx, celestia.Height, celestia.Start, celestia.End, APP_ADDRESS, []byte{}, celestia.RpcUrl, celestia.chainId)
 			if err != nil {
 				return err
 			}
 
-			slog.Info("Payload sent to Celestia Relay")
+			slog.Info("Payload sent to Celestia Relay", "tx", tx)
 
 			return nil
 		},
@@ -339,7 +365,118 @@ func addCelestiaSubcommands(celestiaCmd *cobra.Command) {
 		"If set, celestia command connects to this url instead of setting up Anvil")
 	markFlagRequired(celestiaRelaySend, "height", "start", "end")
 
-	celestiaCmd.AddCommand(celestiaSendCmd, celestiaCheckProofCmd, celestiaRelaySend, celestiaSendFileCmd, celestiaSendFileUrlCmd)
+	// Pipeline
+	pipelineOpts := &CelestiaPipelineOpts{
+		PollInterval:     celestiapipeline.DefaultPollInterval,
+		MaxAttempts:      celestiapipeline.DefaultMaxAttempts,
+		LocalBlockTime:   celestiapipeline.DefaultLocalBlockTime,
+		CommitmentWindow: celestiapipeline.DefaultLocalCommitmentWindow,
+	}
+	celestiaPipelineCmd := &cobra.Command{
+		Use:   "pipeline",
+		Short: "Submit, prove and relay blobs to Celestia Network",
+		Long: "Serve POST /celestia/blobs and take each blob through Celestia and Blobstream up to the relay, " +
+			"with its status at GET /celestia/blobs/:id",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCelestiaPipeline(cmd, celestia, pipelineOpts)
+		},
+	}
+	celestiaPipelineCmd.Flags().StringVar(&celestia.Namespace, "namespace", "", "Namespace of the blobs sent without one")
+	celestiaPipelineCmd.Flags().BoolVar(&pipelineOpts.Local, "local", false,
+		"If set, use an in-process Celestia and Blobstream stand-in instead of the network")
+	celestiaPipelineCmd.Flags().DurationVar(&pipelineOpts.LocalBlockTime, "local-block-time", pipelineOpts.LocalBlockTime,
+		"Block time of the local stand-in")
+	celestiaPipelineCmd.Flags().Uint64Var(&pipelineOpts.CommitmentWindow, "local-commitment-window", pipelineOpts.CommitmentWindow,
+		"Blocks covered by each data commitment of the local stand-in")
+	celestiaPipelineCmd.Flags().DurationVar(&pipelineOpts.PollInterval, "poll-interval", pipelineOpts.PollInterval,
+		"Interval between the checks of the data commitments")
+	celestiaPipelineCmd.Flags().IntVar(&pipelineOpts.MaxAttempts, "max-attempts", pipelineOpts.MaxAttempts,
+		"Failed submissions or relays before a blob is given up")
+	celestiaPipelineCmd.Flags().StringVar(&opts.HttpAddress, "http-address", opts.HttpAddress, "HTTP address of the pipeline API")
+	celestiaPipelineCmd.Flags().IntVar(&opts.HttpPort, "http-port", opts.HttpPort, "HTTP port of the pipeline API")
+	celestiaPipelineCmd.Flags().StringVar(&opts.SqliteFile, "sqlite-file", opts.SqliteFile,
+		"The sqlite file to keep the blobs; a temporary one when empty")
+	celestiaPipelineCmd.Flags().Int64Var(&celestia.chainId, "chain-id", goTestnetChainId, "Chain ID of the relay network")
+	celestiaPipelineCmd.Flags().StringVar(&celestia.RpcUrl, "rpc-url", "http://localhost:8545", "RPC url of the relay network")
+	celestiaPipelineCmd.Flags().StringVar(&pipelineOpts.ApplicationAddress, "address-application", APP_ADDRESS.Hex(),
+		"Application that receives the relayed blobs")
+
+	celestiaCmd.AddCommand(celestiaSendCmd, celestiaCheckProofCmd, celestiaRelaySend, celestiaSendFileCmd, celestiaSendFileUrlCmd,
+		celestiaPipelineCmd)
+}
+
+func runCelestiaPipeline(cmd *cobra.Command, celestia *CelestiaOpts, pipelineOpts *CelestiaPipelineOpts) error {
+	if pipelineOpts.PollInterval <= 0 {
+		exitf("--poll-interval must be positive")
+	}
+	if pipelineOpts.MaxAttempts <= 0 {
+		exitf("--max-attempts must be positive")
+	}
+	if pipelineOpts.Local && (pipelineOpts.LocalBlockTime <= 0 || pipelineOpts.CommitmentWindow == 0) {
+		exitf("--local-block-time and --local-commitment-window must be positive")
+	}
+	checkEthAddress(cmd, "address-application")
+
+	ctx, cancel := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	db, cleanup, err := nonodo.OpenDatabase(opts)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if err := nonodo.MigrateDatabase(ctx, db); err != nil {
+		return err
+	}
+	repository := &celestiapipeline.BlobRepository{Db: db}
+	if err := repository.CreateTables(); err != nil {
+		return err
+	}
+	var pipeline *celestiapipeline.Pipeline
+	if pipelineOpts.Local {
+		blobstream := celestiapipeline.NewLocalBlobstream(pipelineOpts.LocalBlockTime, pipelineOpts.CommitmentWindow)
+		pipeline = celestiapipeline.NewPipeline(repository, blobstream, blobstream, blobstream)
+		slog.Info("Using the local Celestia and Blobstream stand-in")
+	} else {
+		token, url, err := getTokenFromTia()
+		if err != nil {
+			return err
+		}
+		network := celestiapipeline.Network{
+			Url:         url,
+			Token:       token,
+			RpcUrl:      celestia.RpcUrl,
+			ChainId:     celestia.chainId,
+			Application: common.HexToAddress(pipelineOpts.ApplicationAddress),
+		}
+		pipeline = celestiapipeline.NewPipeline(repository, network, network, network)
+	}
+	pipeline.Namespace = celestia.Namespace
+	pipeline.PollInterval = pipelineOpts.PollInterval
+	pipeline.MaxAttempts = pipelineOpts.MaxAttempts
+
+	e := echo.New()
+	e.HideBanner = true
+	e.Use(middleware.Recover())
+	celestiapipeline.Register(e, pipeline)
+	server := supervisor.HttpWorker{
+		Address: fmt.Sprintf("%v:%v", opts.HttpAddress, opts.HttpPort),
+		Handler: e,
+	}
+	w := supervisor.SupervisorWorker{
+		Name:    "celestia",
+		Timeout: opts.TimeoutWorker,
+		Workers: []supervisor.Worker{pipeline, server},
+	}
+	ready := make(chan struct{}, 1)
+	go func() {
+		select {
+		case <-ready:
+			slog.Info("celestia: pipeline ready", "url", fmt.Sprintf("http://%v:%v/celestia/blobs", opts.HttpAddress, opts.HttpPort))
+		case <-ctx.Done():
+		}
+	}()
+	return w.Start(ctx, ready)
 }
 
 func downloadFile(ctx context.Context, url string) ([]byte, error) {
@@ -458,6 +595,9 @@ func getTokenFromTia() (tiatoken string, tiaurl string, missingError error) {
 }
 
 func init() {
+	cmd.Flags().StringVar(&configFile, config.ConfigFlag, "",
+		"TOML or YAML file with the flags and the environment variables; the command line takes precedence")
+
 	// anvil-*
 	cmd.Flags().StringVar(&opts.AnvilAddress, "anvil-address", opts.AnvilAddress,
 		"HTTP address used by Anvil")
@@ -468,6 +608,10 @@ func init() {
 	cmd.Flags().DurationVar(&opts.AnvilBlockTime, "anvil-block-time", opts.AnvilBlockTime, "Block time for Anvil")
 	cmd.Flags().StringVar(&opts.AnvilStateFileName, "anvil-state-file", opts.AnvilStateFileName,
 		"State file used by Anvil")
+	cmd.Flags().StringVar(&opts.AnvilForkUrl, "anvil-fork-url", opts.AnvilForkUrl,
+		"If set, Anvil forks the chain from this RPC url instead of loading the state file")
+	cmd.Flags().Uint64Var(&opts.AnvilForkBlock, "anvil-fork-block", opts.AnvilForkBlock,
+		"Block number used by --anvil-fork-url; the latest block if not set")
 
 	// contracts-*
 	cmd.Flags().StringVar(&opts.ApplicationAddress, "contracts-application-address",
@@ -476,6 +620,10 @@ func init() {
 		opts.InputBoxAddress, "InputBox contract address")
 	cmd.Flags().Uint64Var(&opts.InputBoxBlock, "contracts-input-box-block",
 		opts.InputBoxBlock, "InputBox deployment block number")
+	cmd.Flags().StringVar(&opts.DeploymentFile, "contracts-deployment-file", opts.DeploymentFile,
+		"Deployment JSON with the InputBox, ApplicationFactory and AuthorityFactory addresses")
+	addressBookCmd.Flags().StringVar(&opts.DeploymentFile, "deployment-file", opts.DeploymentFile,
+		"Show the addresses of this deployment JSON instead of the devnet ones")
 
 	// enable-*
 	cmd.Flags().BoolVarP(&debug, "enable-debug", "d", false, "If set, enable debug output")
@@ -484,13 +632,32 @@ func init() {
 		"If set, nonodo starts a built-in echo application")
 
 	cmd.Flags().StringVar(&opts.Sequencer, "sequencer", opts.Sequencer,
-		"Set the sequencer (inputbox[default] or espresso)")
+		"Set the sequencer (inputbox[default], espresso or paio)")
+	cmd.Flags().DurationVar(&opts.PaioBatchInterval, "paio-batch-interval", opts.PaioBatchInterval,
+		"Seal a batch of L2 transactions at this interval when using the paio sequencer")
+	cmd.Flags().IntVar(&opts.PaioBatchSize, "paio-batch-size", opts.PaioBatchSize,
+		"Seal a batch of L2 transactions once it has this many transactions when using the paio sequencer")
+	cmd.Flags().Uint64Var(&opts.PaioMinGasPrice, "paio-min-gas-price", opts.PaioMinGasPrice,
+		"Reject L2 transactions sequenced by nonodo with a lower max_gas_price")
+	cmd.Flags().StringVar(&opts.OrderingPolicy, "ordering-policy", opts.OrderingPolicy,
+		"Policy to merge the InputBox inputs with the L2 blocks (l1-finalized, timestamp or l1-first); "+
+			"defaults to l1-finalized for espresso and paio and timestamp for avail")
+	cmd.Flags().StringVar(&opts.TransactionSchemasFile, "transaction-schemas", opts.TransactionSchemasFile,
+		"JSON file with the EIP-712 schemas of the L2 transactions of each application")
 	cmd.Flags().StringVar(&opts.EspressoUrl, "espresso-url", opts.EspressoUrl,
 		"Set the Espresso base url")
+	cmd.Flags().BoolVar(&opts.EspressoMock, "espresso-mock", opts.EspressoMock,
+		"If set, nonodo serves a local Espresso mock and the espresso sequencer uses it instead of --espresso-url")
+	cmd.Flags().DurationVar(&opts.EspressoMockBlockTime, "espresso-mock-block-time", opts.EspressoMockBlockTime,
+		"Block time of the Espresso mock")
 
 	cmd.Flags().Uint64Var(&opts.Namespace, "namespace", opts.Namespace,
 		"Set the namespace for espresso")
 	cmd.Flags().DurationVar(&opts.TimeoutWorker, "timeout-worker", opts.TimeoutWorker, "Timeout for workers. Example: nonodo --timeout-worker 30s")
+	cmd.Flags().DurationVar(&opts.TimeoutAnvil, "timeout-anvil", opts.TimeoutAnvil,
+		"Timeout for Anvil to be ready, which takes longer with --anvil-fork-url")
+	cmd.Flags().DurationVar(&opts.TimeoutListener, "timeout-listener", opts.TimeoutListener,
+		"Timeout for the Espresso and Avail listeners to be ready")
 	cmd.Flags().DurationVar(&opts.TimeoutInspect, "sm-deadline-inspect-state", opts.TimeoutInspect, "Timeout for inspect requests. Example: nonodo --sm-deadline-inspect-state 30s")
 	cmd.Flags().DurationVar(&opts.TimeoutAdvance, "sm-deadline-advance-state", opts.TimeoutAdvance, "Timeout for advance requests. Example: nonodo --sm-deadline-advance-state 30s")
 
@@ -525,6 +692,29 @@ func init() {
 
 	cmd.Flags().StringVar(&opts.DbImplementation, "db-implementation", opts.DbImplementation,
 		"DB to use. PostgreSQL or SQLite")
+	cobra.CheckErr(cmd.Flags().MarkDeprecated("db-implementation", "use --db-mode"))
+
+	cmd.Flags().StringVar(&opts.DbMode, "db-mode", opts.DbMode,
+		"Storage of the database: memory, file (--sqlite-file or a temporary file) or postgres")
+
+	cmd.Flags().StringSliceVar(&opts.EventSinks, "event-sinks", opts.EventSinks,
+		"Publish the input and output events to postgres (NOTIFY nonodo_events), stdout (JSON lines), "+
+			"webhook urls or none; postgres by default with --db-mode postgres")
+
+	cmd.Flags().StringSliceVar(&opts.WebhookUrls, "webhook-url", opts.WebhookUrls,
+		"Post the events to the url, signed with the NONODO_WEBHOOK_SECRET environment variable if set")
+
+	cmd.Flags().StringSliceVar(&opts.WebhookEvents, "webhook-events", opts.WebhookEvents,
+		"Types of the events sent to the webhooks, such as input_finished or voucher_executed; all if empty")
+
+	cmd.Flags().IntVar(&opts.WebhookMaxAttempts, "webhook-max-attempts", opts.WebhookMaxAttempts,
+		"Attempts to deliver an event to a webhook, with a backoff that doubles from one second")
+
+	cmd.Flags().StringVar(&opts.TraceExporter, "trace-exporter", opts.TraceExporter,
+		"Export the spans of the inputs to none, otlp (OTEL_EXPORTER_OTLP_ENDPOINT) or file")
+
+	cmd.Flags().StringVar(&opts.TraceFile, "trace-file", opts.TraceFile,
+		"File of the spans, one JSON line each, with --trace-exporter file")
 
 	cmd.Flags().StringVar(&opts.NodeVersion, "node-version", opts.NodeVersion,
 		"Node version to emulate")
@@ -537,6 +727,12 @@ func init() {
 	cmd.Flags().StringVar(&opts.SalsaUrl, "salsa-url", opts.SalsaUrl, "Url used to start Salsa")
 	cmd.Flags().BoolVar(&opts.AvailEnabled, "avail-enabled", opts.AvailEnabled, "If set, enables Avail with Paio's sequencer")
 	cmd.Flags().Uint64Var(&opts.AvailFromBlock, "avail-from-block", opts.AvailFromBlock, "The beginning of the queried range for events")
+	cmd.Flags().BoolVar(&opts.AvailEmulator, "avail-emulator", opts.AvailEmulator,
+		"If set, nonodo serves a local Avail emulator and sends the transactions to it instead of Paio's server")
+	cmd.Flags().IntVar(&opts.AvailEmulatorPort, "avail-emulator-port", opts.AvailEmulatorPort,
+		"HTTP and websocket port of the Avail emulator")
+	cmd.Flags().DurationVar(&opts.AvailEmulatorBlockTime, "avail-emulator-block-time", opts.AvailEmulatorBlockTime,
+		"Block time of the Avail emulator")
 
 	cmd.Flags().StringVar(&opts.PaioServerUrl, "paio-server-url", opts.PaioServerUrl, "The Paio's server url")
 
@@ -545,40 +741,51 @@ func init() {
 
 	cmd.Flags().IntVar(&opts.EpochBlocks, "epoch-blocks", opts.EpochBlocks,
 		"Number of blocks in each epoch")
+
+	// app-*
+	cmd.Flags().StringVar(&opts.AppRestartPolicy, "app-restart-policy", opts.AppRestartPolicy,
+		"Restart policy of the application (never, on-failure or always)")
+	cmd.Flags().IntVar(&opts.AppMaxRestarts, "app-max-restarts", opts.AppMaxRestarts,
+		"Maximum number of application restarts; 0 means unlimited")
+	cmd.Flags().IntVar(&opts.AppLogLines, "app-log-lines", opts.AppLogLines,
+		"Number of application log lines kept for the /nonodo/logs API")
+
+	// watch
+	cmd.Flags().StringArrayVar(&opts.WatchPatterns, "watch", opts.WatchPatterns,
+		"Restart the application when files matching this glob change. Example: nonodo --watch 'src/**/*.py' -- python app.py")
+	cmd.Flags().DurationVar(&opts.WatchDebounce, "watch-debounce", opts.WatchDebounce,
+		"Time without file changes before restarting the application")
 }
 
 func run(cmd *cobra.Command, args []string) {
 	ctx := cmd.Context()
 	startTime := time.Now()
 
-	// setup log
-	logOpts := new(tint.Options)
-	if debug {
-		logOpts.Level = slog.LevelDebug
-	}
-	logOpts.AddSource = debug
-	logOpts.NoColor = !color || !isatty.IsTerminal(os.Stdout.Fd())
-	logOpts.TimeFormat = "[15:04:05.000]"
-	handler := tint.NewHandler(os.Stdout, logOpts)
-	logger := slog.New(handler)
-	slog.SetDefault(logger)
-
-	// check args
-	checkEthAddress(cmd, "address-input-box")
-	checkEthAddress(cmd, "address-application")
-	if opts.AnvilPort == 0 {
-		exitf("--anvil-port cannot be 0")
-	}
-	if !cmd.Flags().Changed("sequencer") && cmd.Flags().Changed("rpc-url") && !cmd.Flags().Changed("contracts-input-box-block") {
-		exitf("must set --contracts-input-box-block when setting --rpc-url")
+	// the config file may set the log flags
+	application := applyConfigFile(cmd)
+	if len(args) == 0 {
+		args = application
 	}
-	if opts.EnableEcho && len(args) > 0 {
-		exitf("can't use built-in echo with custom application")
+	logOutput := os.Stdout
+	if opts.LogsToStderr() {
+		logOutput = os.Stderr
 	}
+	setupLog(logOutput, isatty.IsTerminal(logOutput.Fd()))
+
 	if cmd.Flags().Changed("from-l1-block") {
 		opts.FromBlockL1 = &tempFromBlockL1
 	}
 	opts.ApplicationArgs = args
+	useDeploymentInputBox(cmd)
+	useForkInputBoxBlock(cmd)
+
+	// check args
+	if problems := validate(cmd); len(problems) > 0 {
+		for _, problem := range problems {
+			slog.Error("configuration error", "error", problem)
+		}
+		os.Exit(exitConfigError)
+	}
 
 	// handle signals with notify context
 	ctx, cancel := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
@@ -626,8 +833,67 @@ func run(cmd *cobra.Command, args []string) {
 	LoadEnv()
 
 	opts.AutoCount = true // not check the Idempotency
-	err := nonodo.NewSupervisor(opts).Start(ctx, ready)
+	w, err := nonodo.NewSupervisor(opts)
 	cobra.CheckErr(err)
+	cobra.CheckErr(w.Start(ctx, ready))
+}
+
+// Problems of the flags that depend on whether they were set, which the options alone can't tell.
+func flagProblems(cmd *cobra.Command) []string {
+	var problems []string
+	changed := cmd.Flags().Changed
+	if !changed("sequencer") && changed("rpc-url") && !changed("contracts-input-box-block") {
+		problems = append(problems, "must set --contracts-input-box-block when setting --rpc-url")
+	}
+	if changed("anvil-fork-block") && opts.AnvilForkUrl == "" {
+		problems = append(problems, "--anvil-fork-block requires --anvil-fork-url")
+	}
+	if opts.AnvilForkUrl != "" && changed("anvil-state-file") {
+		problems = append(problems, "--anvil-fork-url can't be used with --anvil-state-file")
+	}
+	if opts.EspressoMock && changed("espresso-url") {
+		problems = append(problems, "--espresso-mock can't be used with --espresso-url")
+	}
+	if opts.AvailEmulator && changed("paio-server-url") {
+		problems = append(problems, "--avail-emulator can't be used with --paio-server-url")
+	}
+	return problems
+}
+
+// Check the flags and the options and return every problem found.
+func validate(cmd *cobra.Command) []string {
+	problems := flagProblems(cmd)
+	var validationErr *nonodo.ValidationError
+	if err := opts.Validate(); errors.As(err, &validationErr) {
+		problems = append(problems, validationErr.Problems...)
+	}
+	return problems
+}
+
+// Use the InputBox of the deployment file, unless its address was set.
+func useDeploymentInputBox(cmd *cobra.Command) {
+	if opts.DeploymentFile == "" || cmd.Flags().Changed("contracts-input-box-address") {
+		return
+	}
+	// an invalid file is reported by the validation
+	if addressBook, err := opts.LoadAddressBook(); err == nil {
+		inputBox, _ := addressBook.Address("InputBox")
+		opts.InputBoxAddress = inputBox.Hex()
+	}
+}
+
+// Read the InputBox of a forked chain from its deployment or the fork, instead of the genesis,
+// unless its block was set.
+func useForkInputBoxBlock(cmd *cobra.Command) {
+	if opts.AnvilForkUrl == "" || cmd.Flags().Changed("contracts-input-box-block") {
+		return
+	}
+	if block, ok := opts.ForkInputBoxBlock(); ok {
+		opts.InputBoxBlock = block
+		return
+	}
+	slog.Warn("the InputBox of the forked chain is read from the genesis; " +
+		"set --anvil-fork-block or --contracts-input-box-block to skip its history")
 }
 
 //go:embed .env
@@ -658,18 +924,229 @@ func LoadEnv() {
 	slog.Debug("env: loaded")
 }
 
+// Set the default logger from the --enable-debug and --enable-color flags.
+func setupLog(w io.Writer, terminal bool) {
+	logOpts := new(tint.Options)
+	if debug {
+		commons.LogLevel.Set(slog.LevelDebug)
+	}
+	logOpts.Level = commons.LogLevel
+	logOpts.AddSource = debug
+	logOpts.NoColor = !color || !terminal
+	logOpts.TimeFormat = "[15:04:05.000]"
+	handler := tint.NewHandler(w, logOpts)
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
+}
+
+// Apply the --config file to the flags of the command and return the application in it.
+func applyConfigFile(cmd *cobra.Command) []string {
+	if configFile == "" {
+		return nil
+	}
+	file, err := config.Load(configFile)
+	if err != nil {
+		exitf("invalid --config: %v", err)
+	}
+	if err := file.Apply(cmd.Flags()); err != nil {
+		exitf("invalid --config: %v", err)
+	}
+	slog.Debug("config: loaded", "file", configFile)
+	return file.Application
+}
+
+// Config
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration of nonodo",
+}
+
+func addConfigSubcommands(configCmd *cobra.Command) {
+	var format string
+	configPrintCmd := &cobra.Command{
+		Use:   "print [flags] [-- application [args]...]",
+		Short: "Print the effective configuration, from the flags, the environment and the --config file",
+		Run: func(cmd *cobra.Command, args []string) {
+			if application := applyConfigFile(cmd); len(args) == 0 {
+				args = application
+			}
+			cobra.CheckErr(config.Print(os.Stdout, format, cmd.Flags(), args))
+		},
+	}
+	// the same flags as nonodo, so they can be checked against the file
+	configPrintCmd.Flags().AddFlagSet(cmd.Flags())
+	configPrintCmd.Flags().StringVar(&format, "format", "toml", "Output format (toml or yaml)")
+	configCmd.AddCommand(configPrintCmd)
+}
+
+// Doctor
+var doctorCmd = &cobra.Command{
+	Use:   "doctor [flags] [-- application [args]...]",
+	Short: "Check the configuration of nonodo and the services it depends on",
+	Run: func(cmd *cobra.Command, args []string) {
+		if application := applyConfigFile(cmd); len(args) == 0 {
+			args = application
+		}
+		if cmd.Flags().Changed("from-l1-block") {
+			opts.FromBlockL1 = &tempFromBlockL1
+		}
+		opts.ApplicationArgs = args
+		useDeploymentInputBox(cmd)
+		useForkInputBoxBlock(cmd)
+
+		flags := nonodo.Check{Name: "flags", Status: nonodo.CheckOK}
+		if problems := flagProblems(cmd); len(problems) > 0 {
+			flags.Status = nonodo.CheckFail
+			flags.Details = problems
+		}
+		checks := append([]nonodo.Check{flags}, opts.Doctor(cmd.Context())...)
+		for _, check := range checks {
+			fmt.Printf("[%s] %s\n", check.Status, check.Name)
+			for _, detail := range check.Details {
+				fmt.Printf("    %s\n", detail)
+			}
+		}
+		if nonodo.HasFailures(checks) {
+			os.Exit(exitConfigError)
+		}
+	},
+}
+
+// Database
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the database of nonodo",
+}
+
+func addDbSubcommands(dbCmd *cobra.Command) {
+	var output, input string
+	dbExportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write the inputs, outputs and proofs of the database as JSON lines",
+		Run: func(cmd *cobra.Command, args []string) {
+			db, cleanup := openDatabaseFor(cmd, true)
+			defer cleanup()
+			w := os.Stdout
+			if output != "-" {
+				file, err := os.Create(output)
+				cobra.CheckErr(err)
+				defer file.Close()
+				w = file
+			}
+			counts, err := nonodo.ExportDatabase(cmd.Context(), db, w)
+			cobra.CheckErr(err)
+			slog.Info("db: exported", "rows", counts)
+		},
+	}
+	dbExportCmd.Flags().StringVarP(&output, "output", "o", "-", "File to write, or - for the standard output")
+
+	dbImportCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Read an export into a database without inputs and outputs",
+		Run: func(cmd *cobra.Command, args []string) {
+			db, cleanup := openDatabaseFor(cmd, true)
+			defer cleanup()
+			r := os.Stdin
+			if input != "-" {
+				file, err := os.Open(input)
+				cobra.CheckErr(err)
+				defer file.Close()
+				r = file
+			}
+			counts, err := nonodo.ImportDatabase(cmd.Context(), db, r)
+			cobra.CheckErr(err)
+			slog.Info("db: imported", "rows", counts)
+		},
+	}
+	dbImportCmd.Flags().StringVarP(&input, "input", "i", "-", "File to read, or - for the standard input")
+
+	var dryRun bool
+	dbMigrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply the pending schema migrations to the database",
+		Run: func(cmd *cobra.Command, args []string) {
+			db, cleanup := openDatabaseFor(cmd, false)
+			defer cleanup()
+			migrator, err := nonodo.NewMigrator(db)
+			cobra.CheckErr(err)
+			version, err := migrator.Version(cmd.Context())
+			cobra.CheckErr(err)
+			pending, err := migrator.Pending(cmd.Context())
+			cobra.CheckErr(err)
+			fmt.Printf("schema version %d, latest %d\n", version, migrator.Latest())
+			if len(pending) == 0 {
+				fmt.Println("nothing to migrate")
+				return
+			}
+			if dryRun {
+				for _, m := range pending {
+					fmt.Printf("-- pending %s\n", m)
+					if m.SQL != "" {
+						fmt.Println(strings.TrimSpace(m.SQL))
+					} else {
+						fmt.Println("-- creates the tables of the previous nonodo versions that are missing")
+					}
+				}
+				return
+			}
+			applied, err := migrator.Migrate(cmd.Context())
+			for _, m := range applied {
+				fmt.Printf("applied %s\n", m)
+			}
+			cobra.CheckErr(err)
+		},
+	}
+	dbMigrateCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the pending migrations without applying them")
+
+	for _, c := range []*cobra.Command{dbExportCmd, dbImportCmd, dbMigrateCmd} {
+		// the same database flags as nonodo
+		c.Flags().AddFlagSet(cmd.Flags())
+	}
+	dbCmd.AddCommand(dbExportCmd, dbImportCmd, dbMigrateCmd)
+}
+
+// Open the database selected by the flags of a db subcommand and migrate it, if asked.
+func openDatabaseFor(cmd *cobra.Command, migrate bool) (*sqlx.DB, func()) {
+	applyConfigFile(cmd)
+	switch opts.DatabaseMode() {
+	case nonodo.DbModeMemory:
+		exitf("--db-mode memory can't be used with %s; it starts empty and is gone on exit", cmd.CommandPath())
+	case nonodo.DbModeFile:
+		if opts.SqliteFile == "" {
+			exitf("%s requires --sqlite-file or --db-mode postgres", cmd.CommandPath())
+		}
+	}
+	LoadEnv()
+	db, cleanup, err := nonodo.OpenDatabase(opts)
+	cobra.CheckErr(err)
+	if migrate {
+		if err := nonodo.MigrateDatabase(cmd.Context(), db); err != nil {
+			cleanup()
+			cobra.CheckErr(err)
+		}
+	}
+	return db, cleanup
+}
+
 func main() {
+	// the same flags as nonodo, so the doctor checks the options nonodo would run with
+	doctorCmd.Flags().AddFlagSet(cmd.Flags())
 	addCelestiaSubcommands(celestiaCmd)
 	addEspressoSubcommands(espressoCmd)
 	addAvailSubcommands(availCmd)
-	cmd.AddCommand(addressBookCmd, celestiaCmd, CompletionCmd, espressoCmd, availCmd)
+	addConfigSubcommands(configCmd)
+	addDbSubcommands(dbCmd)
+	cmd.AddCommand(addressBookCmd, celestiaCmd, CompletionCmd, espressoCmd, availCmd, configCmd, doctorCmd, dbCmd)
 	cobra.CheckErr(cmd.Execute())
 }
 
+// Exit code of the configuration errors.
+const exitConfigError = 2
+
 func exitf(format string, args ...any) {
 	err := fmt.Sprintf(format, args...)
 	slog.Error("configuration error", "error", err)
-	os.Exit(1)
+	os.Exit(exitConfigError)
 }
 
 func checkEthAddress(cmd *cobra.Command, varName string) {
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/calindra/nonodo/internal/commons"
+	"github.com/stretchr/testify/suite"
+)
+
+type MainSuite struct {
+	suite.Suite
+	logger *slog.Logger
+	level  slog.Level
+}
+
+func TestMainSuite(t *testing.T) {
+	suite.Run(t, new(MainSuite))
+}
+
+func (s *MainSuite) SetupTest() {
+	s.logger = slog.Default()
+	s.level = commons.LogLevel.Level()
+}
+
+func (s *MainSuite) TearDownTest() {
+	slog.SetDefault(s.logger)
+	commons.LogLevel.Set(s.level)
+	configFile = ""
+	debug = false
+	color = true
+	for _, name := range []string{"enable-debug", "enable-color"} {
+		flag := cmd.Flags().Lookup(name)
+		s.Require().NoError(flag.Value.Set(flag.DefValue))
+		flag.Changed = false
+	}
+}
+
+func (s *MainSuite) TestTheConfigFileSetsTheLog() {
+	configFile = filepath.Join(s.T().TempDir(), "nonodo.toml")
+	content := "[enable]\ndebug = true\ncolor = false\n"
+	s.Require().NoError(os.WriteFile(configFile, []byte(content), 0600)) // nolint
+
+	applyConfigFile(cmd)
+	var out bytes.Buffer
+	setupLog(&out, true)
+	slog.Debug("from the config file")
+
+	s.True(debug)
+	s.False(color)
+	s.Contains(out.String(), "from the config file")
+	s.NotContains(out.String(), "\x1b[")
+}